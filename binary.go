@@ -0,0 +1,251 @@
+package codex
+
+// Compact binary encoding of traits, for caching trained models in key-value
+// stores or shipping them between services. Sounds are written once into a
+// table, and pairs refer to them by index, so the pair tables of big samples
+// take a fraction of their JSON size.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"strconv"
+)
+
+/*********************************** Type ************************************/
+
+// Serialisable form of Traits. The pair tables are encoded as indexes into
+// Sounds, and the other maps as sorted slices, since encoding/gob writes maps
+// in random order. Everything else reuses the JSON mirror, with those fields
+// unset.
+type traitsBinary struct {
+	Traits        traitsJSON
+	Sounds        []string
+	PairSet       [][2]uint32
+	StartSet      [][2]uint32
+	MidSet        [][2]uint32
+	EndSet        [][2]uint32
+	HarmonySet    [][2]uint32
+	Forbidden     [][2]uint32
+	PairCount     []pairCountBinary
+	BoundaryCount []pairCountBinary
+	LengthCount   [][2]int
+	Spelling      [][2]string
+}
+
+// Count of a pair of sounds, referred to by their indexes.
+type pairCountBinary struct {
+	Pair  [2]uint32
+	Count int
+}
+
+/********************************** Methods **********************************/
+
+// Implements encoding.BinaryMarshaler, producing a compact encoding of every
+// field. Unlike the JSON encoding, it's not canonical: encoding/gob numbers
+// types in the order a process first encodes them, so equal traits may produce
+// different bytes in different processes. Use Traits.Hash() to compare traits.
+func (this Traits) MarshalBinary() ([]byte, error) {
+	value := traitsBinary{Sounds: pairSounds(
+		this.PairSet, this.StartSet, this.MidSet, this.EndSet, this.HarmonySet,
+		this.Forbidden, boundarySet(this.PairCount), boundarySet(this.BoundaryCount),
+	)}
+	index := make(map[string]uint32, len(value.Sounds))
+	for i, sound := range value.Sounds {
+		index[sound] = uint32(i)
+	}
+
+	value.PairSet = encodePairIndex(this.PairSet, index)
+	value.StartSet = encodePairIndex(this.StartSet, index)
+	value.MidSet = encodePairIndex(this.MidSet, index)
+	value.EndSet = encodePairIndex(this.EndSet, index)
+	value.HarmonySet = encodePairIndex(this.HarmonySet, index)
+	value.Forbidden = encodePairIndex(this.Forbidden, index)
+	value.PairCount = encodeCountIndex(this.PairCount, index)
+	value.BoundaryCount = encodeCountIndex(this.BoundaryCount, index)
+
+	this.PairSet, this.StartSet, this.MidSet, this.EndSet = nil, nil, nil, nil
+	this.HarmonySet, this.Forbidden = nil, nil
+	this.PairCount, this.BoundaryCount = nil, nil
+	value.LengthCount = encodeLengthCount(this.LengthCount)
+	value.Spelling = encodeSpelling(this.Spelling)
+	this.LengthCount, this.Spelling = nil, nil
+	value.Traits = this.mirror()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Implements encoding.BinaryUnmarshaler. Replaces the traits with the decoded
+// ones. Fails if a pair refers to a sound missing from the table.
+func (this *Traits) UnmarshalBinary(input []byte) error {
+	var value traitsBinary
+	if err := gob.NewDecoder(bytes.NewReader(input)).Decode(&value); err != nil {
+		return err
+	}
+
+	decoded := value.Traits
+	var err error
+	for _, field := range []struct {
+		out   *[][2]string
+		pairs [][2]uint32
+	}{
+		{&decoded.PairSet, value.PairSet},
+		{&decoded.StartSet, value.StartSet},
+		{&decoded.MidSet, value.MidSet},
+		{&decoded.EndSet, value.EndSet},
+		{&decoded.HarmonySet, value.HarmonySet},
+		{&decoded.Forbidden, value.Forbidden},
+	} {
+		if *field.out, err = decodePairIndex(field.pairs, value.Sounds); err != nil {
+			return err
+		}
+	}
+	if decoded.PairCount, err = decodeCountIndex(value.PairCount, value.Sounds); err != nil {
+		return err
+	}
+	if decoded.BoundaryCount, err = decodeCountIndex(value.BoundaryCount, value.Sounds); err != nil {
+		return err
+	}
+	decoded.LengthCount = decodeLengthCount(value.LengthCount)
+	decoded.Spelling = decodeSpelling(value.Spelling)
+	return this.fromMirror(decoded)
+}
+
+/*********************************** Utils ***********************************/
+
+// Returns the sorted set of sounds that occur in the given pair sets.
+func pairSounds(sets ...PairSet) []string {
+	sounds := Set{}
+	for _, set := range sets {
+		for pair := range set {
+			sounds.Add(pair[0])
+			sounds.Add(pair[1])
+		}
+	}
+	return sortedSet(sounds)
+}
+
+// Encodes the given pair set as sorted pairs of indexes of sounds. A nil set
+// produces a nil slice.
+func encodePairIndex(set PairSet, index map[string]uint32) [][2]uint32 {
+	if set == nil {
+		return nil
+	}
+	out := make([][2]uint32, 0, len(set))
+	for _, pair := range sortedPairSet(set) {
+		out = append(out, [2]uint32{index[pair[0]], index[pair[1]]})
+	}
+	return out
+}
+
+// Encodes the given pair counts as counts of sorted pairs of indexes of sounds.
+// A nil map produces a nil slice.
+func encodeCountIndex(counts map[[2]string]int, index map[string]uint32) []pairCountBinary {
+	if counts == nil {
+		return nil
+	}
+	out := make([]pairCountBinary, 0, len(counts))
+	for _, pair := range sortedPairSet(boundarySet(counts)) {
+		out = append(out, pairCountBinary{
+			Pair:  [2]uint32{index[pair[0]], index[pair[1]]},
+			Count: counts[pair],
+		})
+	}
+	return out
+}
+
+// Reverse of encodePairIndex().
+func decodePairIndex(pairs [][2]uint32, sounds []string) ([][2]string, error) {
+	if pairs == nil {
+		return nil, nil
+	}
+	out := make([][2]string, len(pairs))
+	for i, pair := range pairs {
+		decoded, err := decodePair(pair, sounds)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = decoded
+	}
+	return out, nil
+}
+
+// Reverse of encodeCountIndex(), producing counts keyed like the JSON encoding.
+func decodeCountIndex(counts []pairCountBinary, sounds []string) (map[string]int, error) {
+	if counts == nil {
+		return nil, nil
+	}
+	out := make(map[string]int, len(counts))
+	for _, count := range counts {
+		pair, err := decodePair(count.Pair, sounds)
+		if err != nil {
+			return nil, err
+		}
+		out[pair[0]+">"+pair[1]] = count.Count
+	}
+	return out, nil
+}
+
+// Encodes the given length counts as pairs of lengths and counts, sorted by
+// length. A nil map produces a nil slice.
+func encodeLengthCount(counts map[int]int) [][2]int {
+	if counts == nil {
+		return nil
+	}
+	out := make([][2]int, 0, len(counts))
+	for _, length := range sortedLengths(counts) {
+		out = append(out, [2]int{length, counts[length]})
+	}
+	return out
+}
+
+// Reverse of encodeLengthCount().
+func decodeLengthCount(counts [][2]int) map[int]int {
+	if counts == nil {
+		return nil
+	}
+	out := make(map[int]int, len(counts))
+	for _, count := range counts {
+		out[count[0]] = count[1]
+	}
+	return out
+}
+
+// Encodes the given spelling as pairs of sounds and spellings, sorted by sound.
+// A nil map produces a nil slice.
+func encodeSpelling(spelling map[string]string) [][2]string {
+	if spelling == nil {
+		return nil
+	}
+	out := make([][2]string, 0, len(spelling))
+	for _, sound := range sortedKeys(spelling) {
+		out = append(out, [2]string{sound, spelling[sound]})
+	}
+	return out
+}
+
+// Reverse of encodeSpelling().
+func decodeSpelling(spelling [][2]string) map[string]string {
+	if spelling == nil {
+		return nil
+	}
+	out := make(map[string]string, len(spelling))
+	for _, pair := range spelling {
+		out[pair[0]] = pair[1]
+	}
+	return out
+}
+
+// Looks up the sounds of the given pair of indexes.
+func decodePair(pair [2]uint32, sounds []string) ([2]string, error) {
+	for _, i := range pair {
+		if int(i) >= len(sounds) {
+			return [2]string{}, errors.New("sound index " + strconv.Itoa(int(i)) + " is out of range")
+		}
+	}
+	return [2]string{sounds[pair[0]], sounds[pair[1]]}, nil
+}
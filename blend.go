@@ -0,0 +1,44 @@
+package codex
+
+// Hybrids of two words.
+
+/********************************** Statics **********************************/
+
+// Learns traits from the two given words and returns up to `n` random hybrids
+// that begin like `a` and end like `b`: each hybrid starts with the first pair
+// of sounds of `a` and ends with the last pair of sounds of `b`. The original
+// words are never included. Returns an error if either word is invalid.
+func Blend(a, b string, n int) (Set, error) {
+	traits, err := NewTraits([]string{a, b})
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := getSounds(a, traits.knownSounds())
+	if err != nil {
+		return nil, err
+	}
+	end, err := getSounds(b, traits.knownSounds())
+	if err != nil {
+		return nil, err
+	}
+
+	st := &state{
+		traits: traits,
+		prefix: start[:2:2],
+		suffix: end[len(end)-2:],
+		rnd:    newRand(),
+	}
+
+	words := Set{}
+	for len(words) < n {
+		word := st.next()
+		if word == "" {
+			break
+		}
+		if word != a && word != b {
+			words.Add(word)
+		}
+	}
+	return words, nil
+}
@@ -0,0 +1,108 @@
+package codex
+
+// Approximate membership of words, for deduplicating runs of words too big to
+// keep in memory.
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+/*********************************** Type ************************************/
+
+// WordSet is the membership interface used for deduplication by UniqueWith().
+// Both *Set, which is exact, and *BloomSet, which is approximate, implement
+// it.
+type WordSet interface {
+	Add(string)
+	Has(string) bool
+}
+
+// BloomSet is an approximate set of words backed by a Bloom filter. It never
+// forgets an added word, but may report a word that wasn't added as present,
+// with the false positive rate it was created for. It doesn't store the
+// words: at a 1% rate, it takes about 10 bits per word, an order of magnitude
+// less than a Set of short words. Create one with NewBloomSet(). Like a Set,
+// it's not safe for concurrent use.
+type BloomSet struct {
+	bits   []uint64
+	hashes int
+	len    int
+}
+
+/********************************** Methods **********************************/
+
+// Adds the given word.
+func (this *BloomSet) Add(word string) {
+	first, second := bloomHash(word)
+	size := uint64(len(this.bits)) * 64
+	for i := 0; i < this.hashes; i++ {
+		bit := (first + uint64(i)*second) % size
+		this.bits[bit/64] |= 1 << (bit % 64)
+	}
+	this.len++
+}
+
+// True if the given word was added. May also be true for a word that wasn't,
+// at the rate the set was created for, as long as it holds no more words than
+// it was sized for.
+func (this *BloomSet) Has(word string) bool {
+	first, second := bloomHash(word)
+	size := uint64(len(this.bits)) * 64
+	for i := 0; i < this.hashes; i++ {
+		bit := (first + uint64(i)*second) % size
+		if this.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the number of calls to BloomSet.Add(), including the ones that
+// added words already present.
+func (this *BloomSet) Len() int {
+	return this.len
+}
+
+// Returns the memory taken by the filter, in bytes.
+func (this *BloomSet) Size() int {
+	return len(this.bits) * 8
+}
+
+/********************************** Statics **********************************/
+
+// Creates a BloomSet sized to hold n words with the given false positive rate,
+// between 0 and 1 exclusive, such as 0.01. Holding more words than n raises
+// the rate. An n below 1 counts as 1, and a rate out of range as 0.01.
+func NewBloomSet(n int, rate float64) *BloomSet {
+	if n < 1 {
+		n = 1
+	}
+	if !(rate > 0 && rate < 1) {
+		rate = 0.01
+	}
+	bits := math.Ceil(-float64(n) * math.Log(rate) / (math.Ln2 * math.Ln2))
+	hashes := int(math.Round(bits / float64(n) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &BloomSet{
+		bits:   make([]uint64, (int(bits)+63)/64),
+		hashes: hashes,
+	}
+}
+
+/*********************************** Utils ***********************************/
+
+// Returns two hashes of the word for double hashing: the positions of a word
+// are first + i*second. Both are 64-bit FNV-1a sums, and the second one hashes
+// a prefix byte before the word, so it differs from the first. The second
+// hash is odd, thus never zero, so the positions of a word don't all coincide.
+func bloomHash(word string) (uint64, uint64) {
+	first := fnv.New64a()
+	first.Write([]byte(word))
+	second := fnv.New64a()
+	second.Write([]byte{0xff})
+	second.Write([]byte(word))
+	return first.Sum64(), second.Sum64() | 1
+}
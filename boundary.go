@@ -0,0 +1,233 @@
+package codex
+
+// Virtual sounds that mark the start and the end of words in the transition
+// model, so how words begin and end is weighted like any other transition.
+
+import (
+	"errors"
+	"strconv"
+)
+
+/********************************** Globals **********************************/
+
+const (
+	// Virtual sound that precedes the first sound of every word in the
+	// transition model, such as the Transitions of a Matrix.
+	StartToken = "^"
+	// Virtual sound that follows the last sound of every word in the
+	// transition model.
+	EndToken = "$"
+)
+
+/********************************** Methods **********************************/
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Counts the first and the last sound of the given sample word the given
+// number of times.
+func (this *Traits) examineBoundaries(sounds []string, weight int) {
+	if len(sounds) == 0 {
+		return
+	}
+	if this.BoundaryCount == nil {
+		this.BoundaryCount = map[[2]string]int{}
+	}
+	this.BoundaryCount[[2]string{StartToken, sounds[0]}] += weight
+	this.BoundaryCount[[2]string{sounds[len(sounds)-1], EndToken}] += weight
+}
+
+// Subtracts the first and the last sound of the given sample word from the
+// BoundaryCount, deleting counts that drop to zero.
+func (this *Traits) removeBoundaries(sounds []string) {
+	if len(sounds) == 0 {
+		return
+	}
+	for _, pair := range [][2]string{
+		{StartToken, sounds[0]},
+		{sounds[len(sounds)-1], EndToken},
+	} {
+		if this.BoundaryCount[pair] > 0 {
+			this.BoundaryCount[pair]--
+			if this.BoundaryCount[pair] == 0 {
+				delete(this.BoundaryCount, pair)
+			}
+		}
+	}
+}
+
+// Returns the weight of the transition from the StartToken to the given sound:
+// its count in BoundaryCount, or 1 if it has none.
+func (this *Traits) startWeight(sound string) int {
+	if count := this.BoundaryCount[[2]string{StartToken, sound}]; count > 0 {
+		return count
+	}
+	return 1
+}
+
+// Returns the weight of the transition from the given sound to the EndToken:
+// its count in BoundaryCount, or 1 if it has none.
+func (this *Traits) endWeight(sound string) int {
+	if count := this.BoundaryCount[[2]string{sound, EndToken}]; count > 0 {
+		return count
+	}
+	return 1
+}
+
+// Deletes boundary counts of sounds missing from the SoundSet.
+func (this *Traits) delOrphanBoundaries() {
+	for pair := range this.BoundaryCount {
+		if !this.SoundSet.Has(boundarySound(pair)) {
+			delete(this.BoundaryCount, pair)
+		}
+	}
+}
+
+// Checks that every boundary count is a transition between a token and a
+// sound of the SoundSet.
+func (this *Traits) validateBoundaries() error {
+	for pair := range this.BoundaryCount {
+		if !isBoundary(pair) || !this.SoundSet.Has(boundarySound(pair)) {
+			return errors.New("boundary " + strconv.Quote(pair[0]+">"+pair[1]) +
+				" is not a transition between a token and a sound of SoundSet")
+		}
+	}
+	return nil
+}
+
+// Checks that no known sound, vowel or connective is spelled like a token,
+// which would confuse its transitions with those of the token.
+func (this *Traits) validateTokens() error {
+	for _, set := range []Set{this.knownSounds(), this.KnownVowels, this.Connectives} {
+		for _, token := range []string{StartToken, EndToken} {
+			if set.Has(token) {
+				return errors.New("sound " + strconv.Quote(token) + " is reserved for a token")
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the transitions that derived words follow: the pairs of the PairSet,
+// a transition from the StartToken to each first sound of a pair, and one from
+// each second sound of a pair to the EndToken, except for connectives.
+// Traversals start from the StartToken, and only complete words with a
+// transition to the EndToken. Since the tokens only follow seen pairs, the
+// transitions of reversed traits mirror these exactly.
+func (this *Traits) transitions() PairSet {
+	out := make(PairSet, len(this.PairSet))
+	for pair := range this.PairSet {
+		out.Add(pair)
+		if this.validStart(pair[:]) {
+			out.Add([2]string{StartToken, pair[0]})
+		}
+		if this.validFinish(pair[:]) {
+			out.Add([2]string{pair[1], EndToken})
+		}
+	}
+	return out
+}
+
+// Checks if Traits.transitions() has the transition from the StartToken to the
+// first of the given sounds, looking it up in the given table of the
+// transitions, or in the PairSet if the table is nil. True for no sounds.
+func (this *Traits) validFirst(table *soundTable, sounds []string) bool {
+	if len(sounds) == 0 {
+		return true
+	}
+	if table != nil {
+		return table.has(StartToken, sounds[0])
+	}
+	if !this.validStart(sounds) {
+		return false
+	}
+	if len(sounds) > 1 && this.PairSet.Has([2]string{sounds[0], sounds[1]}) {
+		return true
+	}
+	for pair := range this.PairSet {
+		if pair[0] == sounds[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if Traits.transitions() has the transition from the last of the given
+// sounds to the EndToken, looking it up in the given table of the transitions,
+// or in the PairSet if the table is nil. True for no sounds.
+func (this *Traits) validLast(table *soundTable, sounds []string) bool {
+	if len(sounds) == 0 {
+		return true
+	}
+	last := sounds[len(sounds)-1]
+	if table != nil {
+		return table.has(last, EndToken)
+	}
+	if !this.validFinish(sounds) {
+		return false
+	}
+	if len(sounds) > 1 && this.PairSet.Has([2]string{sounds[len(sounds)-2], last}) {
+		return true
+	}
+	for pair := range this.PairSet {
+		if pair[1] == last {
+			return true
+		}
+	}
+	return false
+}
+
+/*********************************** Utils ***********************************/
+
+// Checks if the given pair is a transition from the StartToken or to the
+// EndToken, rather than between two sounds.
+func isBoundary(pair [2]string) bool {
+	return pair[0] == StartToken || pair[1] == EndToken
+}
+
+// Returns the sound of the given boundary transition, which is the one that
+// isn't a token.
+func boundarySound(pair [2]string) string {
+	if pair[0] == StartToken {
+		return pair[1]
+	}
+	return pair[0]
+}
+
+// Returns the set of pairs of the given counts.
+func boundarySet(counts map[[2]string]int) PairSet {
+	set := make(PairSet, len(counts))
+	for pair := range counts {
+		set.Add(pair)
+	}
+	return set
+}
+
+// Returns a copy of the given counts of pairs. A nil map produces a nil map.
+func copyPairCount(counts map[[2]string]int) map[[2]string]int {
+	if counts == nil {
+		return nil
+	}
+	out := make(map[[2]string]int, len(counts))
+	for pair, count := range counts {
+		out[pair] = count
+	}
+	return out
+}
+
+// Returns boundary counts for the words of the given counts spelled backwards:
+// counts of starts become counts of ends, and vice versa. A nil map produces
+// a nil map.
+func reverseBoundaries(counts map[[2]string]int) map[[2]string]int {
+	if counts == nil {
+		return nil
+	}
+	out := make(map[[2]string]int, len(counts))
+	for pair, count := range counts {
+		if pair[0] == StartToken {
+			out[[2]string{pair[1], EndToken}] = count
+		} else {
+			out[[2]string{StartToken, pair[0]}] = count
+		}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package codex
+
+// Casing of generated words.
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*********************************** Type ************************************/
+
+// Casing defines how generators of a traits object case their words. Sample
+// words are always lowercase; the casing only affects the output. To preserve
+// the casing of a cased sample, see DetectCasing().
+type Casing int
+
+const (
+	// Lowercase, like the sample. This is the default.
+	LowerCase Casing = iota
+	// First letter in uppercase, like a name: "Goblin".
+	TitleCase
+	// All letters in uppercase: "GOBLIN".
+	UpperCase
+)
+
+// Names of casings in the text dump format.
+var casingNames = map[Casing]string{
+	LowerCase: "lower",
+	TitleCase: "title",
+	UpperCase: "upper",
+}
+
+/********************************** Methods **********************************/
+
+// Applies the casing to the given lowercase word.
+func (this Casing) apply(word string) string {
+	switch this {
+	case TitleCase:
+		char, size := utf8.DecodeRuneInString(word)
+		if size == 0 {
+			return word
+		}
+		return string(unicode.ToTitle(char)) + word[size:]
+	case UpperCase:
+		return strings.ToUpper(word)
+	default:
+		return word
+	}
+}
+
+// Wraps the given generator function, applying the traits' spelling, rules and
+// casing to its words.
+func (this *Traits) cased(gen func() string) func() string {
+	if this.Casing == LowerCase && len(this.Spelling) == 0 && len(this.Rules) == 0 {
+		return gen
+	}
+	return func() string {
+		return this.styled(gen())
+	}
+}
+
+/********************************** Statics **********************************/
+
+// Returns the casing that most of the given words follow, which preserves the
+// casing of a cased sample: set it as the Casing of traits examined from the
+// lowercased words. A word counts as uppercase if it has no lowercase letters,
+// and as title case if it otherwise begins with an uppercase letter. Ties go to
+// the casing that comes first: LowerCase, then TitleCase.
+func DetectCasing(words []string) Casing {
+	counts := map[Casing]int{}
+	for _, word := range words {
+		counts[wordCasing(word)]++
+	}
+
+	out := LowerCase
+	for _, casing := range []Casing{TitleCase, UpperCase} {
+		if counts[casing] > counts[out] {
+			out = casing
+		}
+	}
+	return out
+}
+
+// Returns the casing of the given word, per DetectCasing().
+func wordCasing(word string) Casing {
+	if strings.ToLower(word) == word {
+		return LowerCase
+	}
+	if strings.ToUpper(word) == word {
+		return UpperCase
+	}
+	char, _ := utf8.DecodeRuneInString(word)
+	if unicode.IsUpper(char) || unicode.IsTitle(char) {
+		return TitleCase
+	}
+	return LowerCase
+}
@@ -0,0 +1,116 @@
+package codex
+
+// Enumeration of derived words that can be interrupted and resumed by another
+// process, for enumerations that take longer than a process lives.
+
+import (
+	"errors"
+	"strconv"
+)
+
+/*********************************** Type ************************************/
+
+// Enumeration returns the words of traits in the order of
+// Traits.SortedGenerator(), and can save its position as a Checkpoint at any
+// time, to be resumed with Traits.Resume(). Use Traits.Enumerate() to start
+// one. Like generators, it's not safe for concurrent use.
+type Enumeration struct {
+	traits *Traits
+	hash   uint64
+	stack  []sortedFrame
+	last   []string
+	count  uint64
+}
+
+// Checkpoint is the position of an Enumeration. It has exported fields, so it
+// can be stored in any format, such as JSON.
+type Checkpoint struct {
+	// Traits.Hash() of the enumerated traits, which guards against resuming
+	// the enumeration with different traits.
+	Hash uint64
+	// Sounds of the last returned word, or none at the start.
+	Sounds []string
+	// Number of words returned so far.
+	Count uint64
+}
+
+/********************************** Methods **********************************/
+
+// Starts an enumeration of the words of the traits, in the order of
+// Traits.SortedGenerator(). The traits must not be modified while it's in use.
+func (this *Traits) Enumerate() *Enumeration {
+	return &Enumeration{traits: this, hash: this.Hash(), stack: this.sortedStack()}
+}
+
+// Resumes an enumeration of the words of the traits from the given checkpoint,
+// which may have been saved by another process. The first word returned is
+// the one that follows the last word returned before the checkpoint. Returns
+// an error if the checkpoint was saved for different traits, or if its word
+// isn't derived from the traits.
+func (this *Traits) Resume(checkpoint Checkpoint) (*Enumeration, error) {
+	hash := this.Hash()
+	if checkpoint.Hash != hash {
+		return nil, errors.New("the checkpoint was saved for different traits")
+	}
+
+	// Rebuilds the stack the traversal had right after returning the word.
+	sounds := append([]string(nil), checkpoint.Sounds...)
+	stack := make([]sortedFrame, 0, len(sounds)+1)
+	for depth, sound := range sounds {
+		path := sounds[:depth:depth]
+		children := this.children(path)
+		index := indexOf(children, sound)
+		if index < 0 {
+			break
+		}
+		stack = append(stack, sortedFrame{path: path, sounds: children, index: index + 1})
+	}
+	if len(stack) < len(sounds) || (len(sounds) > 0 && !this.isWord(sounds)) {
+		return nil, errors.New("the checkpoint has a word not derived from the traits: " +
+			strconv.Quote(join(sounds, "")))
+	}
+	stack = append(stack, sortedFrame{path: sounds, sounds: this.children(sounds)})
+
+	return &Enumeration{
+		traits: this,
+		hash:   hash,
+		stack:  stack,
+		last:   sounds,
+		count:  checkpoint.Count,
+	}, nil
+}
+
+// Returns the next word, or "" when the words are exhausted.
+func (this *Enumeration) Next() string {
+	sounds := this.traits.sortedNext(&this.stack)
+	if sounds == nil {
+		return ""
+	}
+	this.last = sounds
+	this.count++
+	return this.traits.styled(join(sounds, ""))
+}
+
+// Returns the current position of the enumeration. Save it periodically, for
+// example every million words, and pass the latest one to Traits.Resume() to
+// continue after a restart.
+func (this *Enumeration) Checkpoint() Checkpoint {
+	return Checkpoint{
+		Hash:   this.hash,
+		Sounds: append([]string(nil), this.last...),
+		Count:  this.count,
+	}
+}
+
+/*********************************** Utils ***********************************/
+
+// Returns the index of the given string in the given slice, or -1 if it's
+// missing.
+func indexOf(list []string, value string) int {
+	for index, elem := range list {
+		if elem == value {
+			return index
+		}
+	}
+	return -1
+}
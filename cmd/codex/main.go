@@ -0,0 +1,237 @@
+/*
+
+Command-line tool for working with codex models stored as JSON.
+
+Usage:
+
+	codex edit model.json
+
+The "edit" command opens an interactive session for curating a model: listing,
+adding and removing sounds and pairs, previewing generated words after each
+edit, and saving the result. If the file doesn't exist, the session starts with
+an empty model. Type "help" in the session for the list of commands.
+
+*/
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Mitranim/codex"
+)
+
+const usage = `usage: codex edit <model.json>`
+
+const help = `commands:
+  sounds                 list sounds
+  pairs                  list pairs
+  add <a> <b>            add the pair a>b
+  del <a> <b>            delete the pair a>b
+  del-sound <a>          delete a sound and its pairs
+  set <field> <n>        set a numeric field, e.g. "set max-sounds 8"
+  preview [n]            print n generated words (default 12)
+  save                   save the model
+  quit                   exit without saving`
+
+// Number of words printed by previews.
+const previewCount = 12
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, in io.Reader, out io.Writer) error {
+	if len(args) != 2 || args[0] != "edit" {
+		return errors.New(usage)
+	}
+	return edit(args[1], in, out)
+}
+
+// Runs an interactive editing session for the model at the given path.
+func edit(path string, in io.Reader, out io.Writer) error {
+	traits, err := load(path)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		command, params := fields[0], fields[1:]
+
+		switch {
+		case command == "help":
+			fmt.Fprintln(out, help)
+
+		case command == "sounds":
+			fmt.Fprintln(out, strings.Join(sorted(traits.SoundSet), " "))
+
+		case command == "pairs":
+			for _, pair := range sortedPairs(traits.PairSet) {
+				if count, ok := traits.PairCount[pair]; ok {
+					fmt.Fprintln(out, pair[0]+">"+pair[1], count)
+				} else {
+					fmt.Fprintln(out, pair[0]+">"+pair[1])
+				}
+			}
+
+		case command == "add" && len(params) == 2:
+			if err := traits.AddPair([2]string{params[0], params[1]}); err != nil {
+				fmt.Fprintln(out, "error:", err)
+				continue
+			}
+			preview(traits, previewCount, out)
+
+		case command == "del" && len(params) == 2:
+			traits.DelPair([2]string{params[0], params[1]})
+			preview(traits, previewCount, out)
+
+		case command == "del-sound" && len(params) == 1:
+			traits.DelSound(params[0])
+			preview(traits, previewCount, out)
+
+		case command == "set" && len(params) == 2:
+			field := numericField(traits, params[0])
+			n, err := strconv.Atoi(params[1])
+			if field == nil || err != nil {
+				fmt.Fprintln(out, "error: expected a numeric field and a number")
+				continue
+			}
+			*field = n
+			preview(traits, previewCount, out)
+
+		case command == "preview" && len(params) <= 1:
+			n := previewCount
+			if len(params) == 1 {
+				if n, err = strconv.Atoi(params[0]); err != nil {
+					fmt.Fprintln(out, "error: expected a number")
+					continue
+				}
+			}
+			preview(traits, n, out)
+
+		case command == "save":
+			if err := traits.Validate(); err != nil {
+				fmt.Fprintln(out, "warning:", err)
+			}
+			if err := save(path, traits); err != nil {
+				fmt.Fprintln(out, "error:", err)
+				continue
+			}
+			fmt.Fprintln(out, "saved", path)
+
+		case command == "quit" || command == "exit":
+			return nil
+
+		default:
+			fmt.Fprintln(out, "unknown command; type \"help\" for the list of commands")
+		}
+	}
+}
+
+// Reads the model at the given path. A missing file produces empty traits.
+func load(path string) (*codex.Traits, error) {
+	traits := new(codex.Traits)
+	input, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return traits, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(input, traits); err != nil {
+		return nil, err
+	}
+	return traits, nil
+}
+
+// Writes the model to the given path.
+func save(path string, traits *codex.Traits) error {
+	output, err := json.MarshalIndent(traits, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(output, '\n'), 0666)
+}
+
+// Prints up to `n` words generated from the given traits.
+func preview(traits *codex.Traits, n int, out io.Writer) {
+	gen := traits.Generator()
+	words := make([]string, 0, n)
+	for len(words) < n {
+		word := gen()
+		if word == "" {
+			break
+		}
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		fmt.Fprintln(out, "(no words)")
+		return
+	}
+	fmt.Fprintln(out, strings.Join(words, " "))
+}
+
+// Returns a pointer to the numeric field with the given name, as used in the
+// text dump format, or nil.
+func numericField(traits *codex.Traits, name string) *int {
+	switch name {
+	case "min-sounds":
+		return &traits.MinNSounds
+	case "max-sounds":
+		return &traits.MaxNSounds
+	case "min-vowels":
+		return &traits.MinNVowels
+	case "max-vowels":
+		return &traits.MaxNVowels
+	case "max-conseq-vow":
+		return &traits.MaxConseqVow
+	case "max-conseq-cons":
+		return &traits.MaxConseqCons
+	}
+	return nil
+}
+
+func sorted(set codex.Set) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedPairs(set codex.PairSet) [][2]string {
+	keys := make([][2]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
@@ -0,0 +1,29 @@
+package codex
+
+// Sorting of generated words for display.
+
+import (
+	"sort"
+)
+
+/********************************** Statics **********************************/
+
+// Sorts the given words in place using the given comparison function, which
+// returns a negative number if `a` precedes `b`, a positive number if it
+// follows `b`, and zero if they're equal. If `compare` is nil, sorts by byte
+// order, which is only correct for plain English. For lists shown to users in
+// other languages, pass a locale-aware comparison. This package doesn't depend
+// on collation tables, but the CompareString method of collators from
+// golang.org/x/text/collate has the right signature:
+//
+//	collator := collate.New(language.French)
+//	codex.SortWords(words, collator.CompareString)
+func SortWords(words []string, compare func(a, b string) int) {
+	if compare == nil {
+		sort.Strings(words)
+		return
+	}
+	sort.SliceStable(words, func(i, j int) bool {
+		return compare(words[i], words[j]) < 0
+	})
+}
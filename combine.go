@@ -0,0 +1,314 @@
+package codex
+
+// Combination of traits learned from different samples.
+
+/********************************** Methods **********************************/
+
+// Returns new traits that combine the traits with the other traits, as if both
+// samples had been examined together: the sounds, pairs and higher-order
+// sequences are united, counts are added up, and the limits are widened to fit
+// both. Neither input is modified. Settings that don't come from a sample, such
+// as Casing and Weighted, are taken from self; Connectives and forbidden pairs
+// are united, and so is Spelling, preferring self. Higher-order sequences are
+// only kept if both traits have the same Order, and shorter ones only if both
+// back off; positions of pairs only if both are positional, and vowel harmony
+// only if both have it.
+func (this *Traits) Merge(other *Traits) *Traits {
+	out := &Traits{
+		MinNSounds:    mergeMin(this.MinNSounds, other.MinNSounds),
+		MaxNSounds:    maxInt(this.MaxNSounds, other.MaxNSounds),
+		MinNVowels:    mergeMin(this.MinNVowels, other.MinNVowels),
+		MaxNVowels:    maxInt(this.MaxNVowels, other.MaxNVowels),
+		MaxConseqVow:  maxInt(this.MaxConseqVow, other.MaxConseqVow),
+		MaxConseqCons: maxInt(this.MaxConseqCons, other.MaxConseqCons),
+		Weighted:      this.Weighted,
+		MaxUnseen:     this.MaxUnseen,
+		Smoothing:     this.Smoothing,
+		Casing:        this.Casing,
+		Rules:         copyRules(this.Rules),
+		MaxWordLen:    maxInt(this.MaxWordLen, other.MaxWordLen),
+	}
+
+	for _, traits := range []*Traits{this, other} {
+		for sound := range traits.SoundSet {
+			out.SoundSet.Add(sound)
+		}
+		for pair := range traits.PairSet {
+			out.PairSet.Add(pair)
+		}
+	}
+
+	if this.PairCount != nil || other.PairCount != nil {
+		out.PairCount = make(map[[2]string]int, len(out.PairSet))
+		for pair := range out.PairSet {
+			out.PairCount[pair] = this.sampleCount(pair) + other.sampleCount(pair)
+		}
+	}
+
+	for _, traits := range []*Traits{this, other} {
+		for pair, count := range traits.BoundaryCount {
+			if out.BoundaryCount == nil {
+				out.BoundaryCount = map[[2]string]int{}
+			}
+			out.BoundaryCount[pair] += count
+		}
+	}
+
+	for _, traits := range []*Traits{this, other} {
+		for length, count := range traits.LengthCount {
+			if out.LengthCount == nil {
+				out.LengthCount = map[int]int{}
+			}
+			out.LengthCount[length] += count
+		}
+	}
+
+	if this.Order == other.Order {
+		out.Order = this.Order
+		out.Backoff = this.Backoff && other.Backoff
+		for _, traits := range []*Traits{this, other} {
+			for gram := range traits.GramSet {
+				if len(gramSounds(gram)) >= out.minGramSize() {
+					out.GramSet.Add(gram)
+				}
+			}
+		}
+	}
+
+	if this.Positional && other.Positional {
+		out.Positional = true
+		out.StartSet = unitePairs(this.StartSet, other.StartSet)
+		out.MidSet = unitePairs(this.MidSet, other.MidSet)
+		out.EndSet = unitePairs(this.EndSet, other.EndSet)
+	}
+
+	if this.Harmony && other.Harmony {
+		out.Harmony = true
+		out.HarmonySet = unitePairs(this.HarmonySet, other.HarmonySet)
+	}
+
+	if len(this.KnownSounds) > 0 || len(other.KnownSounds) > 0 {
+		out.KnownSounds = unite(this.knownSounds(), other.knownSounds())
+	}
+	if len(this.KnownVowels) > 0 || len(other.KnownVowels) > 0 {
+		out.KnownVowels = unite(this.knownVowels(), other.knownVowels())
+	}
+
+	if this.Connectives != nil || other.Connectives != nil {
+		out.Connectives = unite(this.Connectives, other.Connectives)
+	}
+	if this.Forbidden != nil || other.Forbidden != nil {
+		out.Forbidden = unitePairs(this.Forbidden, other.Forbidden)
+	}
+
+	for _, traits := range []*Traits{other, this} {
+		for sound, spelling := range traits.Spelling {
+			if out.Spelling == nil {
+				out.Spelling = map[string]string{}
+			}
+			out.Spelling[sound] = spelling
+		}
+	}
+
+	return out
+}
+
+// Returns new traits with only the sounds and pairs that occur in both the
+// traits and the other traits, and limits narrowed to fit both, so derived
+// words are plausible in both styles at once. Counts are the smaller of the
+// two. Neither input is modified. Settings that don't come from a sample, such
+// as Casing, known sounds and Spelling, are copied from self. Higher-order
+// sequences are only kept if both traits have the same Order, backing off only
+// if both do; positions of pairs only if both are positional, and vowel
+// harmony only if both have it.
+// The result may derive no words at all if the samples have too little in
+// common.
+func (this *Traits) Intersect(other *Traits) *Traits {
+	out := &Traits{
+		MinNSounds:    maxInt(this.MinNSounds, other.MinNSounds),
+		MaxNSounds:    minInt(this.MaxNSounds, other.MaxNSounds),
+		MinNVowels:    maxInt(this.MinNVowels, other.MinNVowels),
+		MaxNVowels:    minInt(this.MaxNVowels, other.MaxNVowels),
+		MaxConseqVow:  minInt(this.MaxConseqVow, other.MaxConseqVow),
+		MaxConseqCons: minInt(this.MaxConseqCons, other.MaxConseqCons),
+		Weighted:      this.Weighted,
+		MaxUnseen:     this.MaxUnseen,
+		Smoothing:     this.Smoothing,
+		Casing:        this.Casing,
+		MaxWordLen:    this.MaxWordLen,
+	}
+
+	for pair := range this.PairSet {
+		if other.PairSet.Has(pair) {
+			out.PairSet.Add(pair)
+			out.SoundSet.Add(pair[0])
+			out.SoundSet.Add(pair[1])
+		}
+	}
+
+	if this.PairCount != nil || other.PairCount != nil {
+		out.PairCount = make(map[[2]string]int, len(out.PairSet))
+		for pair := range out.PairSet {
+			out.PairCount[pair] = minInt(this.sampleCount(pair), other.sampleCount(pair))
+		}
+	}
+
+	for pair, count := range this.BoundaryCount {
+		if other.BoundaryCount[pair] > 0 && out.SoundSet.Has(boundarySound(pair)) {
+			if out.BoundaryCount == nil {
+				out.BoundaryCount = map[[2]string]int{}
+			}
+			out.BoundaryCount[pair] = minInt(count, other.BoundaryCount[pair])
+		}
+	}
+
+	for length, count := range this.LengthCount {
+		if other.LengthCount[length] > 0 {
+			if out.LengthCount == nil {
+				out.LengthCount = map[int]int{}
+			}
+			out.LengthCount[length] = minInt(count, other.LengthCount[length])
+		}
+	}
+
+	if this.Order == other.Order {
+		out.Order = this.Order
+		out.Backoff = this.Backoff && other.Backoff
+		for gram := range this.GramSet {
+			if other.GramSet.Has(gram) {
+				out.GramSet.Add(gram)
+			}
+		}
+	}
+
+	if this.Positional && other.Positional {
+		out.Positional = true
+		out.StartSet = intersectPairs(this.StartSet, other.StartSet)
+		out.MidSet = intersectPairs(this.MidSet, other.MidSet)
+		out.EndSet = intersectPairs(this.EndSet, other.EndSet)
+	}
+
+	if this.Harmony && other.Harmony {
+		out.Harmony = true
+		out.HarmonySet = intersectPairs(this.HarmonySet, other.HarmonySet)
+		out.delOrphanHarmony()
+	}
+
+	out.copySettings(this)
+	return out
+}
+
+// Returns new traits without the pairs of sounds that occur in the other
+// traits, such as traits learned from examples of unwanted words. This steers
+// generation away from the patterns of the other sample. Sounds that no longer
+// occur in any pair are removed too; the limits stay as they are. The traits
+// are not modified.
+func (this *Traits) Exclude(other *Traits) *Traits {
+	out := this.Clone()
+	for pair := range other.PairSet {
+		out.PairSet.Del(pair)
+	}
+	out.delOrphans()
+	return out
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Copies the known sounds, known vowels, connectives, forbidden pairs,
+// spelling and rules of the given traits, which don't come from a sample.
+func (this *Traits) copySettings(other *Traits) {
+	this.Rules = copyRules(other.Rules)
+	this.Forbidden = copyPairSet(other.Forbidden)
+	if other.Connectives != nil {
+		this.Connectives = copySet(other.Connectives)
+	}
+	if other.KnownSounds != nil {
+		this.KnownSounds = copySet(other.KnownSounds)
+	}
+	if other.KnownVowels != nil {
+		this.KnownVowels = copySet(other.KnownVowels)
+	}
+	for sound, spelling := range other.Spelling {
+		if this.Spelling == nil {
+			this.Spelling = map[string]string{}
+		}
+		this.Spelling[sound] = spelling
+	}
+}
+
+// Returns the number of times the given pair occurs in the sample: the count
+// of a pair in the PairSet, or 0 for a pair missing from it.
+func (this *Traits) sampleCount(pair [2]string) int {
+	if !this.PairSet.Has(pair) {
+		return 0
+	}
+	return this.pairWeight(pair)
+}
+
+/*********************************** Utils ***********************************/
+
+// Returns a new set with the elements of both given sets.
+func unite(one, other Set) Set {
+	out := copySet(one)
+	for key := range other {
+		out.Add(key)
+	}
+	return out
+}
+
+// Returns a new pair set with the elements of both given sets.
+func unitePairs(one, other PairSet) PairSet {
+	out := copyPairSet(one)
+	for pair := range other {
+		out.Add(pair)
+	}
+	return out
+}
+
+// Returns a new pair set with the elements present in both given sets.
+func intersectPairs(one, other PairSet) PairSet {
+	var out PairSet
+	for pair := range one {
+		if other.Has(pair) {
+			out.Add(pair)
+		}
+	}
+	return out
+}
+
+// Returns a copy of the given pair set. A nil set produces a nil set.
+func copyPairSet(set PairSet) PairSet {
+	if set == nil {
+		return nil
+	}
+	out := make(PairSet, len(set))
+	for pair := range set {
+		out.Add(pair)
+	}
+	return out
+}
+
+// Returns the smaller of the given lower bounds, treating 0 as unset, like
+// Traits.Examine() does.
+func mergeMin(one, other int) int {
+	if one == 0 || (other != 0 && other < one) {
+		return other
+	}
+	return one
+}
+
+// Returns the smaller of the given numbers.
+func minInt(one, other int) int {
+	if one < other {
+		return one
+	}
+	return other
+}
+
+// Returns the larger of the given numbers.
+func maxInt(one, other int) int {
+	if one > other {
+		return one
+	}
+	return other
+}
@@ -0,0 +1,93 @@
+package codex
+
+// Compound words made by joining two derived stems.
+
+/*********************************** Type ************************************/
+
+// Head drawn by CompoundGenerator(), with a generator of the tails that haven't
+// been joined with it yet.
+type compoundStem struct {
+	head  string
+	tails func() string
+}
+
+/********************************** Statics **********************************/
+
+// Creates a generator function that returns a new compound word on each call.
+// Each compound joins a stem derived from `head` with a stem derived from
+// `tail`, applying the following junction rules:
+//   1) if the last sound of the head equals the first sound of the tail, it's
+//      only kept once;
+//   2) if both boundary sounds are consonants, `link` is inserted between them;
+//      pass "" to disable this.
+// Compounds are spelled and cased per `head`. Compounds never repeat. Each call
+// joins a random head drawn so far, or a newly drawn one, with the next tail
+// for that head, so every head is eventually joined with every tail. When all
+// of these combinations are used up, further calls return "".
+func CompoundGenerator(head, tail *Traits, link string) func() string {
+	rnd := newRand()
+	heads := (&state{traits: head, rnd: newRand()}).next
+	var stems []compoundStem
+	seen := Set{}
+
+	return func() string {
+		for {
+			n := len(stems)
+			if heads != nil {
+				n++
+			}
+			if n == 0 {
+				return ""
+			}
+
+			index := randIntn(rnd, n)
+			if index == len(stems) {
+				first := heads()
+				if first == "" {
+					heads = nil
+					continue
+				}
+				stems = append(stems, compoundStem{
+					head:  first,
+					tails: (&state{traits: tail, rnd: newRand()}).next,
+				})
+			}
+
+			stem := stems[index]
+			second := stem.tails()
+			if second == "" {
+				stems[index] = stems[len(stems)-1]
+				stems = stems[:len(stems)-1]
+				continue
+			}
+			word := compound(head, tail, stem.head, second, link)
+			if !seen.Has(word) {
+				seen.Add(word)
+				return head.styled(word)
+			}
+		}
+	}
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Joins the given stems per the junction rules of CompoundGenerator().
+func compound(head, tail *Traits, first, second, link string) string {
+	firstSounds, _ := getSounds(first, head.knownSounds())
+	secondSounds, _ := getSounds(second, tail.knownSounds())
+	if len(firstSounds) == 0 || len(secondSounds) == 0 {
+		return first + second
+	}
+
+	last := firstSounds[len(firstSounds)-1]
+	next := secondSounds[0]
+
+	if last == next {
+		return first + join(secondSounds[1:], "")
+	}
+	headVowels, tailVowels := head.knownVowels(), tail.knownVowels()
+	if link != "" && !headVowels.Has(last) && !tailVowels.Has(next) {
+		return first + link + second
+	}
+	return first + second
+}
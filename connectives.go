@@ -0,0 +1,37 @@
+package codex
+
+// Connectives: glyphs such as apostrophes and hyphens that join the parts of a
+// word, like in "o'brien" or "jean-luc".
+
+/********************************** Methods **********************************/
+
+// Checks if the given sequence of sounds doesn't begin with a connective.
+// Always true for traits without connectives.
+func (this *Traits) validStart(sounds []string) bool {
+	return len(this.Connectives) == 0 || len(sounds) == 0 || !this.Connectives.Has(sounds[0])
+}
+
+// Checks if the given sequence of sounds doesn't end with a connective. Always
+// true for traits without connectives.
+func (this *Traits) validFinish(sounds []string) bool {
+	return len(this.Connectives) == 0 || len(sounds) == 0 || !this.Connectives.Has(sounds[len(sounds)-1])
+}
+
+/*********************************** Utils ***********************************/
+
+// Same as maxConsequtiveConsonants(), but connectives aren't consonants, and
+// break up clusters like vowels do.
+func maxConsequtiveConsonantsBetween(sounds []string, vowels, connectives Set) (max int) {
+	var count int
+	for _, sound := range sounds {
+		if vowels.Has(sound) || connectives.Has(sound) {
+			count = 0
+		} else {
+			count++
+			if count > max {
+				max = count
+			}
+		}
+	}
+	return
+}
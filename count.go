@@ -0,0 +1,207 @@
+package codex
+
+// Counting of derived words without enumerating them one by one, and unbiased
+// sampling based on such counts.
+
+import (
+	"math/rand"
+	"sort"
+)
+
+/*********************************** Type ************************************/
+
+// A counter mirrors the virtual tree of a traits object, storing the number of
+// words in each subtree. Nodes are expanded on demand, so the counter only
+// holds the parts of the tree that were descended into, rather than the whole
+// tree. Counting a node's subtree still requires traversing it, but only once.
+type counter struct {
+	traits *Traits
+	root   *countNode
+	// Optional source of randomness. When nil, the global source is used.
+	rnd *rand.Rand
+}
+
+// Node of a counter's tree.
+type countNode struct {
+	// Number of words in the subtree, including the node's own path, that
+	// haven't been taken yet.
+	count uint64
+	// True if the node's own path is a word that hasn't been taken yet.
+	word bool
+	// Sounds of child nodes, sorted. Nil until the node is expanded.
+	sounds []string
+	// Child nodes, in the same order as their sounds.
+	nodes []*countNode
+}
+
+/********************************** Methods **********************************/
+
+// Creates a counter for the given traits.
+func newCounter(traits *Traits) *counter {
+	return &counter{
+		traits: traits,
+		root:   &countNode{count: traits.countFrom(nil)},
+		rnd:    newRand(),
+	}
+}
+
+// Creates the child nodes of the given node, which is at the given path, unless
+// it has been expanded before.
+func (this *counter) expand(node *countNode, path []string) {
+	if node.sounds != nil {
+		return
+	}
+	node.sounds = this.traits.children(path)
+	node.nodes = make([]*countNode, len(node.sounds))
+	for index, sound := range node.sounds {
+		child := append(path[:len(path):len(path)], sound)
+		node.nodes[index] = &countNode{
+			count: this.traits.countFrom(child),
+			word:  this.traits.isWord(child),
+		}
+	}
+}
+
+// Takes a uniformly random word among the words that haven't been taken yet,
+// and marks it as taken. Returns nil when every word has been taken.
+func (this *counter) takeRandom() []string {
+	if this.root.count == 0 {
+		return nil
+	}
+	int63n := rand.Int63n
+	if this.rnd != nil {
+		int63n = this.rnd.Int63n
+	}
+	index := uint64(int63n(int64(this.root.count)))
+
+	var path []string
+	node := this.root
+	for {
+		node.count--
+		if node.word {
+			if index == 0 {
+				node.word = false
+				return path
+			}
+			index--
+		}
+
+		this.expand(node, path)
+		for childIndex, child := range node.nodes {
+			if index < child.count {
+				path = append(path, node.sounds[childIndex])
+				node = child
+				break
+			}
+			index -= child.count
+		}
+	}
+}
+
+// Returns the exact number of words derived from the traits, which is the
+// number of words Traits.Generator() yields before it's exhausted. Counts the
+// words without storing them, but visits each of them, so it takes about as
+// long as exhausting a generator. See Traits.EstimateCount() for a fast
+// alternative.
+func (this *Traits) Count() uint64 {
+	return this.countFrom(nil)
+}
+
+// Estimates the number of words derived from the traits with Knuth's method:
+// random descents into the virtual tree, each multiplying the branching
+// factors on its way, average out to the number of words. This takes a few
+// hundred descents regardless of the number of words, so it's fast even when
+// the words are too many to enumerate, but the estimate varies between calls,
+// by more for lopsided trees.
+func (this *Traits) EstimateCount() float64 {
+	return this.estimateCount(newRand(), estimateProbes)
+}
+
+// Creates a generator function that returns a new word on each call, drawn
+// uniformly at random from the words that haven't been returned yet. Unlike
+// the generator from Traits.Generator(), whose traversal favours some branches
+// of the virtual tree over others, every remaining word is equally likely to
+// come next. The cost is speed: the first call counts every word, which takes
+// about as long as exhausting a regular generator, although without holding
+// the words in memory. When the set is exhausted, further calls return "".
+func (this *Traits) UniformGenerator() func() string {
+	var count *counter
+	return this.cased(func() string {
+		if count == nil {
+			count = newCounter(this)
+		}
+		return join(count.takeRandom(), "")
+	})
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Returns the sounds that continue the given partial word into longer partial
+// words, sorted. If the path is empty, returns the sounds that may begin a
+// word.
+func (this *Traits) children(path []string) []string {
+	var sounds []string
+	for sound := range this.successors(path) {
+		if this.validPart(append(path[:len(path):len(path)], sound)...) {
+			sounds = append(sounds, sound)
+		}
+	}
+	sort.Strings(sounds)
+	return sounds
+}
+
+// Checks if the given valid partial word is also a word yielded by generators.
+func (this *Traits) isWord(path []string) bool {
+	return len(path) >= this.minWordSounds() && this.checkPart(path...)
+}
+
+// Counts the words in the subtree of the virtual tree at the given path,
+// including the path itself.
+func (this *Traits) countFrom(path []string) (count uint64) {
+	if this.isWord(path) {
+		count++
+	}
+	for _, sound := range this.children(path) {
+		count += this.countFrom(append(path[:len(path):len(path)], sound))
+	}
+	return
+}
+
+// Checks if the subtree of the virtual tree at the given path, including the
+// path itself, has any words. Unlike Traits.countFrom(), stops at the first.
+func (this *Traits) hasWordFrom(path []string) bool {
+	if this.isWord(path) {
+		return true
+	}
+	for _, sound := range this.children(path) {
+		if this.hasWordFrom(append(path[:len(path):len(path)], sound)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Number of random descents used by Traits.EstimateCount().
+const estimateProbes = 256
+
+// Estimates the number of derived words with Knuth's method, averaging the
+// given number of random descents into the virtual tree.
+func (this *Traits) estimateCount(rnd *rand.Rand, probes int) float64 {
+	var sum float64
+	for probe := 0; probe < probes; probe++ {
+		var path []string
+		weight := 1.0
+		for {
+			if this.isWord(path) {
+				sum += weight
+			}
+			children := this.children(path)
+			if len(children) == 0 {
+				break
+			}
+			weight *= float64(len(children))
+			path = append(path, children[randIntn(rnd, len(children))])
+		}
+	}
+	return sum / float64(probes)
+}
@@ -0,0 +1,69 @@
+package codex
+
+// Counting of words reachable by several derivation paths.
+
+import (
+	"sort"
+)
+
+/*********************************** Type ************************************/
+
+// CountSet behaves like a multiset of strings: it counts how many times each
+// element was added. Use CountSet.Set() to drop the counts.
+type CountSet map[string]int
+
+/********************************** Methods **********************************/
+
+// Counts the given element once more.
+func (this *CountSet) Add(key string) {
+	if *this == nil {
+		*this = CountSet{}
+	}
+	(*this)[key]++
+}
+
+// Returns how many times the given element was added; 0 if it's missing.
+func (this CountSet) Count(key string) int {
+	return this[key]
+}
+
+// Returns the elements without their counts.
+func (this CountSet) Set() Set {
+	out := make(Set, len(this))
+	for key := range this {
+		out.Add(key)
+	}
+	return out
+}
+
+// Returns the elements ordered by their counts, highest first. Elements with
+// equal counts are sorted.
+func (this CountSet) Ranked() []string {
+	keys := make([]string, 0, len(this))
+	for key := range this {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if this[keys[i]] != this[keys[j]] {
+			return this[keys[i]] > this[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// Returns every word of the traits with the number of its derivation paths:
+// the distinct sequences of sounds that the traits derive and that are spelled
+// as the word. Words reachable in several ways, for example as "t", "h" and as
+// "th", or through Spelling and Rules that merge sounds, are usually the most
+// natural for the sample, so the counts help rank words. Enumerates every
+// path, so it takes about as long as exhausting a generator, and holds every
+// word in memory.
+func (this *Traits) Derivations() CountSet {
+	counts := CountSet{}
+	gen := this.SortedGenerator()
+	for word := gen(); word != ""; word = gen() {
+		counts.Add(word)
+	}
+	return counts
+}
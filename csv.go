@@ -0,0 +1,51 @@
+package codex
+
+// Tabular export of generated words, for sorting and filtering in spreadsheets
+// and other tools.
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+/********************************** Methods **********************************/
+
+// Writes `n` random words from a new generator to the given writer as CSV,
+// with the given field delimiter: ',' for CSV, or '\t' for TSV. The first row
+// is a header, and each following row holds a word, its score per
+// Traits.Score(), and its length in letters. Like in Traits.WriteWordsN(),
+// words aren't kept after they're written. If the word set has fewer words,
+// writes all of them and returns a *CountError. Returns the first error of
+// writing, or an error if the delimiter is invalid, as defined by encoding/csv.
+func (this *Traits) WriteCSV(writer io.Writer, n int, comma rune) error {
+	out := csv.NewWriter(writer)
+	out.Comma = comma
+	if err := out.Write([]string{"word", "score", "length"}); err != nil {
+		return err
+	}
+
+	chances := this.chances()
+	gen := this.Generator()
+	for count := 0; count < n; count++ {
+		word := gen()
+		if word == "" {
+			out.Flush()
+			if err := out.Error(); err != nil {
+				return err
+			}
+			return &CountError{Requested: n, Available: count}
+		}
+		err := out.Write([]string{
+			word,
+			strconv.FormatFloat(this.score(chances, word), 'f', 4, 64),
+			strconv.Itoa(utf8.RuneCountInString(word)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
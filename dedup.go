@@ -0,0 +1,142 @@
+package codex
+
+// Uniqueness of generated words under normalisations other than exact string
+// equality.
+
+import (
+	"strings"
+	"unicode"
+)
+
+/********************************** Statics **********************************/
+
+/*--------------------------------- Public ----------------------------------*/
+
+// Wraps the given generator function, skipping words whose key, as returned by
+// the `key` function, matches the key of a previously returned word. Words from
+// a generator never repeat exactly, but may look alike depending on the use:
+// display names may differ only in casing, while usernames should also be
+// distinct when spoken. Use one of the key functions below, or your own. The
+// keys of returned words are kept in memory; see UniqueWith() to bound it.
+// When the wrapped generator is exhausted, further calls return "".
+func Unique(gen func() string, key func(string) string) func() string {
+	return UniqueWith(gen, key, &Set{})
+}
+
+// Same as Unique(), but records the keys of returned words in the given set,
+// rather than in a new Set. Pass a *BloomSet to deduplicate runs of millions
+// of words in a fraction of the memory, at the cost of occasionally skipping
+// a word whose key was never returned. The set may be shared with other
+// deduplicated generators that must not return the same words.
+func UniqueWith(gen func() string, key func(string) string, seen WordSet) func() string {
+	return func() string {
+		for word := gen(); word != ""; word = gen() {
+			normal := key(word)
+			if !seen.Has(normal) {
+				seen.Add(normal)
+				return word
+			}
+		}
+		return ""
+	}
+}
+
+// Key function for Unique() that returns the word as-is: words are unique
+// unless they're exactly equal.
+func ExactKey(word string) string {
+	return word
+}
+
+// Key function for Unique() that ignores casing.
+func CaseKey(word string) string {
+	return strings.ToLower(word)
+}
+
+// Key function for Unique() that ignores casing and diacritics of Latin
+// letters, such that "Élan" and "elan" are considered equal.
+func DiacriticKey(word string) string {
+	return strings.Map(foldDiacritic, strings.ToLower(word))
+}
+
+// Key function for Unique() that approximates how the word sounds, such that
+// "kaffe", "cafe" and "qaphe" are considered equal. It ignores casing and
+// diacritics, merges letters and digraphs that commonly spell the same sound,
+// and collapses repeated letters. This is a rough heuristic for Latin
+// spellings, not a phonetic algorithm for any particular language.
+func PhoneticKey(word string) string {
+	word = DiacriticKey(word)
+	for _, pair := range phoneticDigraphs {
+		word = strings.Replace(word, pair[0], pair[1], -1)
+	}
+	word = strings.Map(func(char rune) rune {
+		if merged, ok := phoneticLetters[char]; ok {
+			return merged
+		}
+		return char
+	}, word)
+
+	// Collapse repeated letters.
+	var buf []rune
+	for _, char := range word {
+		if len(buf) == 0 || buf[len(buf)-1] != char {
+			buf = append(buf, char)
+		}
+	}
+	return string(buf)
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Returns the given lowercase Latin letter without diacritics, or the rune as-is
+// if it's not a known letter with diacritics.
+func foldDiacritic(char rune) rune {
+	if char < unicode.MaxASCII {
+		return char
+	}
+	for base, variants := range diacritics {
+		if strings.ContainsRune(variants, char) {
+			return base
+		}
+	}
+	return char
+}
+
+// Lowercase Latin letters with diacritics, grouped by base letter.
+var diacritics = map[rune]string{
+	'a': "àáâãäåāăą",
+	'c': "çćĉċč",
+	'd': "ďđ",
+	'e': "èéêëēĕėęě",
+	'g': "ĝğġģ",
+	'h': "ĥħ",
+	'i': "ìíîïĩīĭįı",
+	'j': "ĵ",
+	'k': "ķ",
+	'l': "ĺļľŀł",
+	'n': "ñńņňŉ",
+	'o': "òóôõöøōŏő",
+	'r': "ŕŗř",
+	's': "śŝşšș",
+	't': "ţťŧț",
+	'u': "ùúûüũūŭůűų",
+	'w': "ŵ",
+	'y': "ýÿŷ",
+	'z': "źżž",
+}
+
+// Digraphs replaced by PhoneticKey(), in order.
+var phoneticDigraphs = [][2]string{
+	{"ph", "f"},
+	{"ck", "k"},
+	{"qu", "k"},
+	{"kh", "h"},
+}
+
+// Letters merged by PhoneticKey().
+var phoneticLetters = map[rune]rune{
+	'c': 'k',
+	'q': 'k',
+	'y': 'i',
+	'z': 's',
+	'v': 'f',
+}
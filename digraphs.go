@@ -0,0 +1,74 @@
+package codex
+
+// Discovery of digraphs: pairs of letters that behave like single sounds.
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/********************************** Statics **********************************/
+
+// Finds pairs of letters in the given words that occur together so much more
+// often than apart that they likely spell a single sound, like "th" and "sh"
+// in English, "ij" in Dutch or "sz" in Hungarian. Unlike a fixed list of
+// digraphs, this adapts to the spelling of any sample.
+//
+// A pair qualifies when its Dice coefficient, twice the number of its
+// occurrences divided by the number of occurrences of both letters, is at
+// least the given threshold, and it occurs at least twice. 1 means that the
+// letters never occur apart; 0.5 is a reasonable starting point. Letters are
+// defined like in Letters(), and only pairs of two letters are considered.
+// Promote the result to known sounds before analysis:
+//
+//	sounds := DefaultSounds()
+//	for digraph := range DiscoverDigraphs(words, 0.5) {
+//	  sounds.Add(digraph)
+//	}
+//	traits, err := NewTraitsWithSounds(words, sounds)
+func DiscoverDigraphs(words []string, threshold float64) Set {
+	letters := map[string]int{}
+	pairs := map[[2]string]int{}
+	for _, word := range words {
+		word = strings.ToLower(word)
+		offsets := letterOffsets(word)
+		var prev string
+		for index := 0; index+1 < len(offsets); index++ {
+			letter := word[offsets[index]:offsets[index+1]]
+			if !isLetter(letter) {
+				prev = ""
+				continue
+			}
+			letters[letter]++
+			if prev != "" {
+				pairs[[2]string{prev, letter}]++
+			}
+			prev = letter
+		}
+	}
+
+	digraphs := Set{}
+	for pair, count := range pairs {
+		if count < minDigraphCount || pair[0] == pair[1] {
+			continue
+		}
+		dice := 2 * float64(count) / float64(letters[pair[0]]+letters[pair[1]])
+		if dice >= threshold {
+			digraphs.Add(pair[0] + pair[1])
+		}
+	}
+	return digraphs
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Minimum number of occurrences of a digraph found by DiscoverDigraphs().
+const minDigraphCount = 2
+
+// Checks if the given letter, as defined by letterOffsets(), begins with a
+// letter rather than a digit, punctuation or other symbol.
+func isLetter(letter string) bool {
+	char, _ := utf8.DecodeRuneInString(letter)
+	return unicode.IsLetter(char)
+}
@@ -0,0 +1,417 @@
+package codex
+
+// Human-readable, line-oriented encoding of traits, designed for diffing in
+// code review. Each line holds one fact about the traits:
+//
+//   min-sounds 5
+//   max-sounds 6
+//   sound a
+//   pair a>b 3
+//   boundary ^>a 2
+//   length 5 2
+//   gram a>b>c
+//
+// Fields come in a fixed order, and the lines of each set or map are sorted, so
+// a curated edit to the model shows up as a small diff. Rules are the
+// exception: they apply in order, so they keep it. Empty lines and lines
+// starting with "#" are ignored when parsing.
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/********************************** Methods **********************************/
+
+// Implements encoding.TextMarshaler, producing the line-oriented dump format.
+// Fails if a sound contains whitespace or ">", which the format can't
+// represent.
+func (this Traits) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+
+	for _, line := range []struct {
+		key   string
+		value int
+	}{
+		{"min-sounds", this.MinNSounds},
+		{"max-sounds", this.MaxNSounds},
+		{"min-vowels", this.MinNVowels},
+		{"max-vowels", this.MaxNVowels},
+		{"max-conseq-vow", this.MaxConseqVow},
+		{"max-conseq-cons", this.MaxConseqCons},
+	} {
+		buf.WriteString(line.key + " " + strconv.Itoa(line.value) + "\n")
+	}
+
+	if this.Casing != LowerCase {
+		buf.WriteString("casing " + casingNames[this.Casing] + "\n")
+	}
+	if this.MaxWordLen != 0 {
+		buf.WriteString("max-word-len " + strconv.Itoa(this.MaxWordLen) + "\n")
+	}
+	if this.Order != 0 {
+		buf.WriteString("order " + strconv.Itoa(this.Order) + "\n")
+	}
+	if this.Backoff {
+		buf.WriteString("backoff true\n")
+	}
+	if this.Weighted {
+		buf.WriteString("weighted true\n")
+	}
+	if this.MaxUnseen != 0 {
+		buf.WriteString("max-unseen " + strconv.Itoa(this.MaxUnseen) + "\n")
+	}
+	if this.Smoothing != 0 {
+		buf.WriteString("smoothing " + strconv.FormatFloat(this.Smoothing, 'g', -1, 64) + "\n")
+	}
+	if this.Positional {
+		buf.WriteString("positional true\n")
+	}
+	if this.Harmony {
+		buf.WriteString("harmony true\n")
+	}
+
+	for _, group := range []struct {
+		key string
+		set Set
+	}{
+		{"known-sound", this.KnownSounds},
+		{"known-vowel", this.KnownVowels},
+		{"connective", this.Connectives},
+		{"sound", this.SoundSet},
+	} {
+		for _, sound := range sortedSet(group.set) {
+			if !dumpable(sound) {
+				return nil, errors.New("can't dump sound " + strconv.Quote(sound))
+			}
+			buf.WriteString(group.key + " " + sound + "\n")
+		}
+	}
+
+	for _, pair := range sortedPairSet(this.PairSet) {
+		if !dumpable(pair[0]) || !dumpable(pair[1]) {
+			return nil, errors.New("can't dump pair " + strconv.Quote(pair[0]+">"+pair[1]))
+		}
+		line := "pair " + pair[0] + ">" + pair[1]
+		if count, ok := this.PairCount[pair]; ok {
+			line += " " + strconv.Itoa(count)
+		}
+		buf.WriteString(line + "\n")
+	}
+
+	for _, pair := range sortedPairSet(boundarySet(this.BoundaryCount)) {
+		if !dumpable(boundarySound(pair)) {
+			return nil, errors.New("can't dump boundary " + strconv.Quote(pair[0]+">"+pair[1]))
+		}
+		buf.WriteString("boundary " + pair[0] + ">" + pair[1] + " " + strconv.Itoa(this.BoundaryCount[pair]) + "\n")
+	}
+
+	for _, length := range sortedLengths(this.LengthCount) {
+		buf.WriteString("length " + strconv.Itoa(length) + " " + strconv.Itoa(this.LengthCount[length]) + "\n")
+	}
+
+	for _, group := range []struct {
+		key string
+		set PairSet
+	}{
+		{"start-pair", this.StartSet},
+		{"mid-pair", this.MidSet},
+		{"end-pair", this.EndSet},
+		{"harmony-pair", this.HarmonySet},
+		{"forbidden-pair", this.Forbidden},
+	} {
+		for _, pair := range sortedPairSet(group.set) {
+			if !dumpable(pair[0]) || !dumpable(pair[1]) {
+				return nil, errors.New("can't dump pair " + strconv.Quote(pair[0]+">"+pair[1]))
+			}
+			buf.WriteString(group.key + " " + pair[0] + ">" + pair[1] + "\n")
+		}
+	}
+
+	for _, gram := range sortedSet(this.GramSet) {
+		for _, sound := range gramSounds(gram) {
+			if !dumpable(sound) {
+				return nil, errors.New("can't dump n-gram " + strconv.Quote(gram))
+			}
+		}
+		buf.WriteString("gram " + gram + "\n")
+	}
+
+	for _, sound := range sortedKeys(this.Spelling) {
+		spelling := this.Spelling[sound]
+		if !dumpable(sound) || !dumpable(spelling) {
+			return nil, errors.New("can't dump spelling " + strconv.Quote(sound+">"+spelling))
+		}
+		buf.WriteString("spelling " + sound + ">" + spelling + "\n")
+	}
+
+	// Rules apply in order, so they're not sorted.
+	for _, rule := range this.Rules {
+		pattern, replace := strconv.Quote(rule.Pattern.String()), strconv.Quote(rule.Replace)
+		if strings.ContainsAny(pattern+replace, " \t") {
+			return nil, errors.New("can't dump rule " + pattern)
+		}
+		buf.WriteString("rule " + pattern + " " + replace + "\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Implements encoding.TextUnmarshaler, parsing the line-oriented dump format.
+// Replaces the traits with the parsed ones.
+func (this *Traits) UnmarshalText(input []byte) error {
+	traits := Traits{}
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+
+	for index := 1; scanner.Scan(); index++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Pairs may be followed by their count, and boundaries, lengths and rules
+		// have two values.
+		fields := strings.Fields(line)
+		if fields[0] == "rule" && len(fields) != 3 {
+			return dumpError(index, "expected a pattern and a replacement")
+		}
+		if fields[0] == "length" && len(fields) != 3 {
+			return dumpError(index, "expected a length and a count")
+		}
+		if fields[0] == "boundary" && len(fields) != 3 {
+			return dumpError(index, "expected a boundary and a count")
+		}
+		if len(fields) != 2 && !(len(fields) == 3 && isTripleField(fields[0])) {
+			return dumpError(index, "expected a key and a value")
+		}
+		key, value := fields[0], fields[1]
+
+		switch key {
+		case "min-sounds", "max-sounds", "min-vowels", "max-vowels",
+			"max-conseq-vow", "max-conseq-cons", "max-word-len", "order", "max-unseen":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return dumpError(index, "invalid number "+strconv.Quote(value))
+			}
+			*traits.dumpField(key) = n
+		case "smoothing":
+			smoothing, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return dumpError(index, "invalid number "+strconv.Quote(value))
+			}
+			traits.Smoothing = smoothing
+		case "casing":
+			casing, ok := parseCasing(value)
+			if !ok {
+				return dumpError(index, "unknown casing "+strconv.Quote(value))
+			}
+			traits.Casing = casing
+		case "known-sound":
+			traits.KnownSounds.Add(value)
+		case "known-vowel":
+			traits.KnownVowels.Add(value)
+		case "connective":
+			traits.Connectives.Add(value)
+		case "sound":
+			traits.SoundSet.Add(value)
+		case "pair":
+			pair, ok := parsePair(value)
+			if !ok {
+				return dumpError(index, "invalid pair "+strconv.Quote(value))
+			}
+			traits.PairSet.Add(pair)
+			if len(fields) == 3 {
+				count, err := strconv.Atoi(fields[2])
+				if err != nil {
+					return dumpError(index, "invalid count "+strconv.Quote(fields[2]))
+				}
+				if traits.PairCount == nil {
+					traits.PairCount = map[[2]string]int{}
+				}
+				traits.PairCount[pair] = count
+			}
+		case "boundary":
+			pair, ok := parsePair(value)
+			if !ok || !isBoundary(pair) {
+				return dumpError(index, "invalid boundary "+strconv.Quote(value))
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return dumpError(index, "invalid count "+strconv.Quote(fields[2]))
+			}
+			if traits.BoundaryCount == nil {
+				traits.BoundaryCount = map[[2]string]int{}
+			}
+			traits.BoundaryCount[pair] = count
+		case "length":
+			length, err := strconv.Atoi(value)
+			if err != nil {
+				return dumpError(index, "invalid length "+strconv.Quote(value))
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return dumpError(index, "invalid count "+strconv.Quote(fields[2]))
+			}
+			if traits.LengthCount == nil {
+				traits.LengthCount = map[int]int{}
+			}
+			traits.LengthCount[length] = count
+		case "start-pair", "mid-pair", "end-pair", "harmony-pair", "forbidden-pair":
+			pair, ok := parsePair(value)
+			if !ok {
+				return dumpError(index, "invalid pair "+strconv.Quote(value))
+			}
+			traits.dumpPairSet(key).Add(pair)
+		case "weighted", "positional", "harmony", "backoff":
+			flag, err := strconv.ParseBool(value)
+			if err != nil {
+				return dumpError(index, "invalid boolean "+strconv.Quote(value))
+			}
+			*traits.dumpFlag(key) = flag
+		case "gram":
+			for _, sound := range gramSounds(value) {
+				if sound == "" {
+					return dumpError(index, "invalid n-gram "+strconv.Quote(value))
+				}
+			}
+			traits.GramSet.Add(value)
+		case "spelling":
+			parts := strings.Split(value, ">")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return dumpError(index, "invalid spelling "+strconv.Quote(value))
+			}
+			if traits.Spelling == nil {
+				traits.Spelling = map[string]string{}
+			}
+			traits.Spelling[parts[0]] = parts[1]
+		case "rule":
+			rule, ok := parseRule(fields[1], fields[2])
+			if !ok {
+				return dumpError(index, "invalid rule "+strconv.Quote(fields[1]))
+			}
+			traits.Rules = append(traits.Rules, rule)
+		default:
+			return dumpError(index, "unknown key "+strconv.Quote(key))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	*this = traits
+	return nil
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Returns a pointer to the numeric field with the given dump key.
+func (this *Traits) dumpField(key string) *int {
+	switch key {
+	case "min-sounds":
+		return &this.MinNSounds
+	case "max-sounds":
+		return &this.MaxNSounds
+	case "min-vowels":
+		return &this.MinNVowels
+	case "max-vowels":
+		return &this.MaxNVowels
+	case "max-conseq-vow":
+		return &this.MaxConseqVow
+	case "max-word-len":
+		return &this.MaxWordLen
+	case "order":
+		return &this.Order
+	case "max-unseen":
+		return &this.MaxUnseen
+	default:
+		return &this.MaxConseqCons
+	}
+}
+
+// Returns a pointer to the set of pairs with the given dump key, other than the
+// PairSet.
+func (this *Traits) dumpPairSet(key string) *PairSet {
+	switch key {
+	case "start-pair":
+		return &this.StartSet
+	case "mid-pair":
+		return &this.MidSet
+	case "end-pair":
+		return &this.EndSet
+	case "forbidden-pair":
+		return &this.Forbidden
+	default:
+		return &this.HarmonySet
+	}
+}
+
+// Returns a pointer to the boolean field with the given dump key.
+func (this *Traits) dumpFlag(key string) *bool {
+	switch key {
+	case "weighted":
+		return &this.Weighted
+	case "positional":
+		return &this.Positional
+	case "backoff":
+		return &this.Backoff
+	default:
+		return &this.Harmony
+	}
+}
+
+/*********************************** Utils ***********************************/
+
+// Parses a pair of sounds written as "a>b".
+func parsePair(value string) ([2]string, bool) {
+	sounds := strings.Split(value, ">")
+	if len(sounds) != 2 || sounds[0] == "" || sounds[1] == "" {
+		return [2]string{}, false
+	}
+	return [2]string{sounds[0], sounds[1]}, true
+}
+
+// Parses a rule written as a quoted pattern and a quoted replacement.
+func parseRule(pattern, replace string) (Rule, bool) {
+	pattern, err := strconv.Unquote(pattern)
+	if err != nil {
+		return Rule{}, false
+	}
+	replace, err = strconv.Unquote(replace)
+	if err != nil {
+		return Rule{}, false
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, false
+	}
+	return Rule{Pattern: compiled, Replace: replace}, true
+}
+
+// Finds the casing with the given name.
+func parseCasing(name string) (Casing, bool) {
+	for casing, casingName := range casingNames {
+		if casingName == name {
+			return casing, true
+		}
+	}
+	return LowerCase, false
+}
+
+// Checks if the given key of a dump line takes two values rather than one.
+func isTripleField(key string) bool {
+	return key == "pair" || key == "boundary" || key == "length" || key == "rule"
+}
+
+// Checks if the given sound can be represented in the dump format.
+func dumpable(sound string) bool {
+	return sound != "" && !strings.ContainsAny(sound, "> \t\r\n")
+}
+
+// Creates an error that points at a line in a dump.
+func dumpError(line int, message string) error {
+	return errors.New("line " + strconv.Itoa(line) + ": " + message)
+}
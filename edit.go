@@ -0,0 +1,170 @@
+package codex
+
+// Manual editing of traits. Lets users hand-curate the sounds and pairs learned
+// from a sample while keeping the traits consistent: every pair consists of
+// known sounds, and every sound in the traits belongs to at least one pair.
+
+import (
+	"errors"
+	"strconv"
+)
+
+/********************************** Methods **********************************/
+
+// Adds the given pair of sounds, along with its sounds. Returns an error if
+// either sound is not among the known sounds, or if the pair is forbidden per
+// Traits.ForbidPair(). For positional traits, the pair may occur at the start,
+// in the middle and at the end of words.
+func (this *Traits) AddPair(pair [2]string) error {
+	known := this.knownSounds()
+	for _, sound := range pair {
+		if !known.Has(sound) {
+			return errors.New("unknown sound " + strconv.Quote(sound))
+		}
+	}
+	if this.Forbidden.Has(pair) {
+		return errors.New("pair " + strconv.Quote(pair[0]+">"+pair[1]) + " is forbidden")
+	}
+	this.SoundSet.Add(pair[0])
+	this.SoundSet.Add(pair[1])
+	this.PairSet.Add(pair)
+	if this.Positional {
+		this.StartSet.Add(pair)
+		this.MidSet.Add(pair)
+		this.EndSet.Add(pair)
+	}
+	return nil
+}
+
+// Deletes the given pair of sounds. Sounds that no longer occur in any pair are
+// deleted as well.
+func (this *Traits) DelPair(pair [2]string) {
+	this.PairSet.Del(pair)
+	this.delOrphans()
+}
+
+// Deletes the given sound and every pair it occurs in. Sounds that no longer
+// occur in any pair are deleted as well.
+func (this *Traits) DelSound(sound string) {
+	for pair := range this.PairSet {
+		if pair[0] == sound || pair[1] == sound {
+			this.PairSet.Del(pair)
+		}
+	}
+	this.SoundSet.Del(sound)
+	this.delOrphans()
+}
+
+// Deletes the pairs of sounds that occur fewer than the given number of times
+// in the sample, per PairCount, where pairs missing from it count as occurring
+// once. Large, noisy samples produce many one-off pairs, from typos and loan
+// words, that make derived words look random; pruning them keeps the patterns
+// that dominate the sample. Sounds that no longer occur in any pair are deleted
+// as well. Returns the number of deleted pairs.
+func (this *Traits) Prune(minCount int) int {
+	var count int
+	for pair := range this.PairSet {
+		if this.pairWeight(pair) < minCount {
+			this.PairSet.Del(pair)
+			count++
+		}
+	}
+	this.delOrphans()
+	return count
+}
+
+// Forbids the pair of the given sounds, like "q" followed by "k", so derived
+// words never use it. This removes a transition that produces ugly words
+// without editing the sample: the pair is deleted like with Traits.DelPair(),
+// and added to Forbidden, which keeps it out of derived words even if it's
+// examined again or allowed by MaxUnseen.
+func (this *Traits) ForbidPair(a, b string) {
+	this.ForbidPairs(PairSet.New(nil, [2]string{a, b}))
+}
+
+// Forbids each of the given pairs of sounds, like Traits.ForbidPair().
+func (this *Traits) ForbidPairs(pairs PairSet) {
+	for pair := range pairs {
+		this.Forbidden.Add(pair)
+		this.PairSet.Del(pair)
+	}
+	this.delOrphans()
+}
+
+// Checks the consistency of the traits, which may be broken by editing the
+// fields directly. Returns an error describing the first problem found.
+func (this *Traits) Validate() error {
+	if this.MinNSounds > this.MaxNSounds {
+		return errors.New("MinNSounds exceeds MaxNSounds")
+	}
+	if this.MinNVowels > this.MaxNVowels {
+		return errors.New("MinNVowels exceeds MaxNVowels")
+	}
+
+	if err := this.validateTokens(); err != nil {
+		return err
+	}
+	known := this.knownSounds()
+	for sound := range this.SoundSet {
+		if !known.Has(sound) {
+			return errors.New("unknown sound " + strconv.Quote(sound))
+		}
+	}
+
+	used := Set{}
+	for pair := range this.PairSet {
+		for _, sound := range pair {
+			if !this.SoundSet.Has(sound) {
+				return errors.New("pair " + strconv.Quote(pair[0]+">"+pair[1]) + " has a sound missing from SoundSet: " + strconv.Quote(sound))
+			}
+			used.Add(sound)
+		}
+	}
+	for sound := range this.SoundSet {
+		if !used.Has(sound) {
+			return errors.New("sound " + strconv.Quote(sound) + " doesn't occur in any pair")
+		}
+	}
+	for pair := range this.PairCount {
+		if !this.PairSet.Has(pair) {
+			return errors.New("pair " + strconv.Quote(pair[0]+">"+pair[1]) + " is counted, but missing from PairSet")
+		}
+	}
+
+	if err := this.validateBoundaries(); err != nil {
+		return err
+	}
+	if err := this.validateGrams(); err != nil {
+		return err
+	}
+	if err := this.validatePositions(); err != nil {
+		return err
+	}
+	return this.validateHarmony()
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Deletes sounds that don't occur in any pair, counts of missing sounds and
+// pairs, and higher-order sequences of missing pairs.
+func (this *Traits) delOrphans() {
+	used := Set{}
+	for pair := range this.PairSet {
+		used.Add(pair[0])
+		used.Add(pair[1])
+	}
+	for sound := range this.SoundSet {
+		if !used.Has(sound) {
+			this.SoundSet.Del(sound)
+		}
+	}
+	for pair := range this.PairCount {
+		if !this.PairSet.Has(pair) {
+			delete(this.PairCount, pair)
+		}
+	}
+	this.delOrphanBoundaries()
+	this.delOrphanGrams()
+	this.delOrphanPositions()
+	this.delOrphanHarmony()
+}
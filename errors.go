@@ -0,0 +1,103 @@
+package codex
+
+// Error types.
+
+import (
+	"errors"
+	"io"
+	"strconv"
+)
+
+/******************************** CountError *********************************/
+
+// CountError is returned when fewer words are available than were requested.
+type CountError struct {
+	// Number of words requested.
+	Requested int
+	// Maximum number of words available.
+	Available int
+}
+
+// Implements the error interface.
+func (this *CountError) Error() string {
+	return "requested " + strconv.Itoa(this.Requested) + " words, but only " +
+		strconv.Itoa(this.Available) + " can be made"
+}
+
+/********************************* WordError *********************************/
+
+// WordError is returned when a word can't be examined as a sample word, or
+// can't be split into known sounds. It tells which word was rejected, why, and
+// where, so interactive tools can show users what's wrong with their examples.
+type WordError struct {
+	// The rejected word.
+	Word string
+	// Why the word was rejected, such as "encountered unknown symbol".
+	Reason string
+	// Byte offset of the offending symbol in the word, or -1 if the reason
+	// doesn't concern a position.
+	Offset int
+	// The offending symbol, if any: a letter, as defined by Letters(), that
+	// doesn't begin any known sound at the offset.
+	Symbol string
+}
+
+// Implements the error interface.
+func (this *WordError) Error() string {
+	msg := "invalid word " + strconv.Quote(this.Word) + ": " + this.Reason
+	if this.Symbol != "" {
+		msg += " " + strconv.Quote(this.Symbol) + " at offset " + strconv.Itoa(this.Offset)
+	}
+	return msg
+}
+
+// Creates a WordError that doesn't concern a position in the word.
+func wordError(word, reason string) *WordError {
+	return &WordError{Word: word, Reason: reason, Offset: -1}
+}
+
+/******************************* VersionError ********************************/
+
+// VersionError is returned by LoadModel() when a model file was saved in a
+// format version this package can't read, typically by a newer release.
+type VersionError struct {
+	// Version of the model file.
+	Version int
+	// Latest version this package can read.
+	Supported int
+}
+
+// Implements the error interface.
+func (this *VersionError) Error() string {
+	return "model file has format version " + strconv.Itoa(this.Version) +
+		", but only versions up to " + strconv.Itoa(this.Supported) + " are supported"
+}
+
+// Returned by LoadModel() when the input doesn't start with the header of a
+// model file.
+var ErrNotModel = errors.New("not a codex model file")
+
+/******************************* Stream errors *******************************/
+
+// Returned by streams of words, such as Reader.Next(), when the word set is
+// exhausted. It matches io.EOF per errors.Is(), so code that checks for the end
+// of a stream that way needs no special cases.
+var ErrExhausted error = eofError("word set exhausted")
+
+// Returned by streams of words, such as Reader.Next(), when the stream has
+// produced as many words as its budget allows. It matches io.EOF per
+// errors.Is().
+var ErrBudgetExceeded error = eofError("word budget exceeded")
+
+// Cause of the end of a stream, matching io.EOF.
+type eofError string
+
+// Implements the error interface.
+func (this eofError) Error() string {
+	return string(this)
+}
+
+// Makes errors.Is() match the error with io.EOF.
+func (this eofError) Is(target error) bool {
+	return target == io.EOF
+}
@@ -0,0 +1,122 @@
+package codex
+
+// Conversion of traits into data for other kinds of name generators. Such
+// formats are less expressive than traits, so conversions are approximate and
+// come with a report of what was lost.
+
+/*********************************** Type ************************************/
+
+// SyllableTable is a name table, as used by name generators that join a random
+// prefix, an optional middle, and a random suffix.
+type SyllableTable struct {
+	Prefixes Set
+	Middles  Set
+	Suffixes Set
+}
+
+// Fidelity describes how closely a conversion reproduces the original traits.
+type Fidelity struct {
+	// Number of words the conversion was based on.
+	NWords int
+	// Number of words that couldn't be represented at all.
+	NSkipped int
+	// Pairs of sounds from the traits that the conversion can't reproduce.
+	MissingPairs PairSet
+	// Pairs of sounds that the conversion produces, but the traits don't have.
+	ExtraPairs PairSet
+}
+
+/********************************** Methods **********************************/
+
+// Approximates the traits as a syllable table, based on up to `limit` words
+// from a new generator. Each word is split into syllables around its vowels.
+// Its first syllable becomes a prefix, its last syllable becomes a suffix, and
+// anything in between becomes a middle. Words of one syllable can't be
+// represented and are skipped. The returned fidelity report compares the
+// pairs of sounds the table can produce with the pairs of the traits.
+func (this *Traits) SyllableTable(limit int) (SyllableTable, Fidelity) {
+	table := SyllableTable{Prefixes: Set{}, Middles: Set{}, Suffixes: Set{}}
+	fidelity := Fidelity{}
+
+	gen := (&state{traits: this, rnd: newRand()}).next
+	for word := gen(); word != "" && fidelity.NWords < limit; word = gen() {
+		fidelity.NWords++
+		sounds, _ := getSounds(word, this.knownSounds())
+		syllables := this.syllables(sounds)
+		if len(syllables) < 2 {
+			fidelity.NSkipped++
+			continue
+		}
+		table.Prefixes.Add(syllables[0])
+		table.Suffixes.Add(syllables[len(syllables)-1])
+		if len(syllables) > 2 {
+			table.Middles.Add(join(syllables[1:len(syllables)-1], ""))
+		}
+	}
+
+	pairs := PairSet{}
+	addPairs := func(word string) {
+		sounds, err := getSounds(word, this.knownSounds())
+		if err != nil {
+			return
+		}
+		for pair := range getPairs(sounds) {
+			pairs.Add(pair)
+		}
+	}
+	for prefix := range table.Prefixes {
+		for suffix := range table.Suffixes {
+			addPairs(prefix + suffix)
+			for middle := range table.Middles {
+				addPairs(prefix + middle + suffix)
+			}
+		}
+	}
+
+	fidelity.MissingPairs = PairSet{}
+	for pair := range this.PairSet {
+		if !pairs.Has(pair) {
+			fidelity.MissingPairs.Add(pair)
+		}
+	}
+	fidelity.ExtraPairs = PairSet{}
+	for pair := range pairs {
+		if !this.PairSet.Has(pair) {
+			fidelity.ExtraPairs.Add(pair)
+		}
+	}
+
+	return table, fidelity
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Splits the given sounds into syllables. Each syllable has a group of vowels
+// at its core. Of the consonants between two groups of vowels, the last one
+// begins the next syllable, and the others end the previous one. Leading and
+// trailing consonants belong to the first and last syllables.
+func (this *Traits) syllables(sounds []string) (syllables []string) {
+	vowels := this.knownVowels()
+
+	// Indices of the sounds that begin vowel groups.
+	var cores []int
+	for index, sound := range sounds {
+		if vowels.Has(sound) && (index == 0 || !vowels.Has(sounds[index-1])) {
+			cores = append(cores, index)
+		}
+	}
+	if len(cores) == 0 {
+		return []string{join(sounds, "")}
+	}
+
+	start := 0
+	for _, core := range cores[1:] {
+		end := core
+		if end > 0 && !vowels.Has(sounds[end-1]) {
+			end--
+		}
+		syllables = append(syllables, join(sounds[start:end], ""))
+		start = end
+	}
+	return append(syllables, join(sounds[start:], ""))
+}
@@ -0,0 +1,173 @@
+package codex
+
+// Compatibility with the traits of foliant, the JavaScript port of this
+// package, so traits can be moved between the two implementations.
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+/*********************************** Type ************************************/
+
+// Shape of foliant's traits as plain JSON: the limits and the sets of sounds
+// and pairs, with camelCase keys. Foliant has none of the other fields of
+// Traits.
+type traitsFoliant struct {
+	MinNSounds    int             `json:"minNSounds"`
+	MaxNSounds    int             `json:"maxNSounds"`
+	MinNVowels    int             `json:"minNVowels"`
+	MaxNVowels    int             `json:"maxNVowels"`
+	MaxConseqVow  int             `json:"maxConseqVow"`
+	MaxConseqCons int             `json:"maxConseqCons"`
+	SoundSet      json.RawMessage `json:"soundSet"`
+	PairSet       json.RawMessage `json:"pairSet"`
+}
+
+/********************************** Methods **********************************/
+
+// Encodes the traits in the JSON shape of foliant's traits, to be loaded by
+// the JavaScript port. Foliant only knows the limits and the sets of sounds
+// and pairs, so the other fields, such as counts, settings and known sounds,
+// are dropped. Sets are encoded as sorted arrays, and pairs as arrays of two
+// sounds.
+func (this Traits) MarshalFoliant() ([]byte, error) {
+	soundSet, err := json.Marshal(nonNilSlice(sortedSet(this.SoundSet)))
+	if err != nil {
+		return nil, err
+	}
+	pairSet, err := json.Marshal(nonNilPairs(sortedPairSet(this.PairSet)))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(traitsFoliant{
+		MinNSounds:    this.MinNSounds,
+		MaxNSounds:    this.MaxNSounds,
+		MinNVowels:    this.MinNVowels,
+		MaxNVowels:    this.MaxNVowels,
+		MaxConseqVow:  this.MaxConseqVow,
+		MaxConseqCons: this.MaxConseqCons,
+		SoundSet:      soundSet,
+		PairSet:       pairSet,
+	})
+}
+
+/********************************** Statics **********************************/
+
+// Creates traits from foliant's traits encoded as JSON, such as a trained
+// model saved by the JavaScript port. Sets may be arrays or objects keyed by
+// their elements, like {"a": true}. Pairs may be an array of arrays of two
+// sounds, or an object keyed by the first sound whose values are the sets of
+// second sounds. Sounds unknown to this package, which foliant may have been
+// configured with, are added to the KnownSounds. Returns an error if the
+// input has a different shape or describes inconsistent traits.
+func NewTraitsFromFoliant(input []byte) (*Traits, error) {
+	var value traitsFoliant
+	if err := json.Unmarshal(input, &value); err != nil {
+		return nil, err
+	}
+
+	traits := &Traits{
+		MinNSounds:    value.MinNSounds,
+		MaxNSounds:    value.MaxNSounds,
+		MinNVowels:    value.MinNVowels,
+		MaxNVowels:    value.MaxNVowels,
+		MaxConseqVow:  value.MaxConseqVow,
+		MaxConseqCons: value.MaxConseqCons,
+	}
+	sounds, err := decodeFoliantSet(value.SoundSet)
+	if err != nil {
+		return nil, err
+	}
+	traits.SoundSet = Set.New(nil, sounds...)
+	traits.PairSet, err = decodeFoliantPairs(value.PairSet)
+	if err != nil {
+		return nil, err
+	}
+
+	known := DefaultSounds()
+	for sound := range traits.SoundSet {
+		if !known.Has(sound) {
+			traits.KnownSounds = unite(known, traits.SoundSet)
+			break
+		}
+	}
+
+	if err := traits.Validate(); err != nil {
+		return nil, err
+	}
+	return traits, nil
+}
+
+/*********************************** Utils ***********************************/
+
+// Decodes a set of foliant, which is either an array of elements or an object
+// keyed by them. Elements whose value in an object is false or null are
+// excluded.
+func decodeFoliantSet(input json.RawMessage) ([]string, error) {
+	var list []string
+	if err := json.Unmarshal(input, &list); err == nil {
+		return list, nil
+	}
+	var dict map[string]interface{}
+	if err := json.Unmarshal(input, &dict); err != nil {
+		return nil, errors.New("expected a set as an array or an object, got " + strconv.Quote(string(input)))
+	}
+	for key, value := range dict {
+		if value != nil && value != false {
+			list = append(list, key)
+		}
+	}
+	return list, nil
+}
+
+// Decodes a pair set of foliant, which is either an array of pairs or an
+// object keyed by the first sounds of pairs whose values are sets of the
+// second sounds.
+func decodeFoliantPairs(input json.RawMessage) (PairSet, error) {
+	var list [][]string
+	if err := json.Unmarshal(input, &list); err == nil {
+		set := make(PairSet, len(list))
+		for _, pair := range list {
+			if len(pair) != 2 {
+				return nil, errors.New("expected a pair of two sounds, got " + strconv.Itoa(len(pair)))
+			}
+			set.Add([2]string{pair[0], pair[1]})
+		}
+		return set, nil
+	}
+
+	var dict map[string]json.RawMessage
+	if err := json.Unmarshal(input, &dict); err != nil {
+		return nil, errors.New("expected pairs as an array or an object, got " + strconv.Quote(string(input)))
+	}
+	set := PairSet{}
+	for first, value := range dict {
+		seconds, err := decodeFoliantSet(value)
+		if err != nil {
+			return nil, err
+		}
+		for _, second := range seconds {
+			set.Add([2]string{first, second})
+		}
+	}
+	return set, nil
+}
+
+// Returns the given slice, or an empty slice if it's nil, so it's encoded as
+// an empty JSON array rather than null.
+func nonNilSlice(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// Same as nonNilSlice(), for pairs.
+func nonNilPairs(list [][2]string) [][2]string {
+	if list == nil {
+		return [][2]string{}
+	}
+	return list
+}
@@ -0,0 +1,90 @@
+package codex
+
+// Vowel harmony: which vowels occur together in sample words. Languages like
+// Finnish, Turkish and Hungarian don't mix front and back vowels in a word,
+// while neutral vowels go with either; recording which vowels share a word
+// captures both without knowing the classes in advance.
+
+import (
+	"errors"
+	"strconv"
+)
+
+/********************************** Methods **********************************/
+
+// Records the pairs of different vowels that occur together in the given
+// sounds. Does nothing unless the traits have Harmony enabled.
+func (this *Traits) examineHarmony(sounds []string) {
+	if !this.Harmony {
+		return
+	}
+	vowels := this.knownVowels()
+	for index, one := range sounds {
+		if !vowels.Has(one) {
+			continue
+		}
+		for _, other := range sounds[index+1:] {
+			if other != one && vowels.Has(other) {
+				this.HarmonySet.Add(harmonyPair(one, other))
+			}
+		}
+	}
+}
+
+// Checks if every two different vowels of the given sounds occur together in a
+// sample word. Always true unless the traits have Harmony enabled.
+func (this *Traits) validHarmony(sounds []string) bool {
+	if !this.Harmony {
+		return true
+	}
+	vowels := this.knownVowels()
+	for index, one := range sounds {
+		if !vowels.Has(one) {
+			continue
+		}
+		for _, other := range sounds[index+1:] {
+			if other != one && vowels.Has(other) && !this.HarmonySet.Has(harmonyPair(one, other)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Deletes harmony pairs with vowels missing from the SoundSet.
+func (this *Traits) delOrphanHarmony() {
+	for pair := range this.HarmonySet {
+		if !this.SoundSet.Has(pair[0]) || !this.SoundSet.Has(pair[1]) {
+			this.HarmonySet.Del(pair)
+		}
+	}
+}
+
+// Checks that every harmony pair consists of two different vowels from the
+// SoundSet, in order.
+func (this *Traits) validateHarmony() error {
+	vowels := this.knownVowels()
+	for pair := range this.HarmonySet {
+		key := strconv.Quote(pair[0] + ">" + pair[1])
+		if pair != harmonyPair(pair[0], pair[1]) || pair[0] == pair[1] {
+			return errors.New("harmony pair " + key + " must consist of two different vowels in order")
+		}
+		for _, sound := range pair {
+			if !this.SoundSet.Has(sound) || !vowels.Has(sound) {
+				return errors.New("harmony pair " + key + " has a sound that isn't a vowel from SoundSet: " + strconv.Quote(sound))
+			}
+		}
+	}
+	return nil
+}
+
+/*********************************** Utils ***********************************/
+
+// Returns the given vowels as a pair in sorted order, which makes the pair the
+// same regardless of which vowel comes first in a word.
+func harmonyPair(one, other string) [2]string {
+	if other < one {
+		return [2]string{other, one}
+	}
+	return [2]string{one, other}
+}
@@ -0,0 +1,24 @@
+package codex
+
+// Fingerprints of traits.
+
+import (
+	"hash/fnv"
+)
+
+/********************************** Methods **********************************/
+
+// Returns a fingerprint of the traits: the 64-bit FNV-1a hash of their
+// canonical JSON encoding. Equal traits always have equal hashes, regardless
+// of the order of the sample words or of map iteration, so services can key
+// caches of trained traits and generated words by it, and tell when a sample
+// has actually changed. Different traits may collide, though rarely. Traits
+// that can't be encoded, such as those with a NaN Smoothing, all have the
+// hash of an empty input.
+func (this *Traits) Hash() uint64 {
+	hash := fnv.New64a()
+	if input, err := this.MarshalJSON(); err == nil {
+		hash.Write(input)
+	}
+	return hash.Sum64()
+}
@@ -0,0 +1,35 @@
+package codex
+
+// Conversion of data from other kinds of name generators into traits.
+
+/********************************** Statics **********************************/
+
+// Creates traits from a list of syllables, as used by simple syllable-based
+// name generators that join random syllables. Examines every word made of two
+// syllables from the list, which teaches the traits every junction between
+// syllables.
+func NewTraitsFromSyllables(syllables []string) (*Traits, error) {
+	words := make([]string, 0, len(syllables)*len(syllables))
+	for _, first := range syllables {
+		for _, second := range syllables {
+			words = append(words, first+second)
+		}
+	}
+	return NewTraits(words)
+}
+
+// Creates traits from a name table, as used by name generators that join a
+// random prefix, an optional middle, and a random suffix. Examines every word
+// the table can make. `middles` may be empty.
+func NewTraitsFromTable(prefixes, middles, suffixes []string) (*Traits, error) {
+	words := make([]string, 0, len(prefixes)*(len(middles)+1)*len(suffixes))
+	for _, prefix := range prefixes {
+		for _, suffix := range suffixes {
+			words = append(words, prefix+suffix)
+			for _, middle := range middles {
+				words = append(words, prefix+middle+suffix)
+			}
+		}
+	}
+	return NewTraits(words)
+}
@@ -0,0 +1,105 @@
+package codex
+
+// Interned sounds, for traversals that look up the successors of sounds many
+// times over.
+
+/*********************************** Type ************************************/
+
+// Table of the sounds of a pair set, interned as integer IDs, with the pairs
+// indexed by the ID of their first sound, and marked in a bitset adjacency
+// matrix. Looking up the successors of a sound takes a slice index, and
+// checking a pair takes a bit test, rather than a scan of every pair or a
+// lookup in a map of string pairs, which dominated the time of traversals. The
+// pairs may include transitions of the StartToken and the EndToken, which are
+// interned like sounds. The table is a snapshot: it doesn't reflect later
+// changes to the pair set.
+type soundTable struct {
+	// IDs of sounds, which index `sounds` and `next`.
+	ids map[string]uint32
+	// Sounds by ID, in sorted order.
+	sounds []string
+	// IDs of the second sounds of pairs, by the ID of their first sound.
+	next [][]uint32
+	// Bit `first*len(sounds)+second` is set for each pair, by the IDs of its
+	// sounds. Nil for tables with more than maxAdjacentSounds sounds, which
+	// check pairs in `next` instead.
+	adjacent []uint64
+}
+
+/********************************** Globals **********************************/
+
+// Number of sounds above which a soundTable has no adjacency bitset, which
+// grows with the square of the number of sounds. At this number, it takes
+// 2 MiB, while pair sets of real samples have at most a few hundred sounds.
+const maxAdjacentSounds = 4096
+
+/********************************** Methods **********************************/
+
+// Creates shallow child nodes for a tree from the pairs of the table on the
+// given path. Same as sprout() for the pair set of the table.
+func (this *soundTable) sprout(path ...string) map[string]*tree {
+	nodes := map[string]*tree{}
+	last := StartToken
+	if len(path) > 0 {
+		last = path[len(path)-1]
+	}
+	id, ok := this.ids[last]
+	if !ok {
+		return nodes
+	}
+	for _, next := range this.next[id] {
+		if sound := this.sounds[next]; sound != EndToken {
+			nodes[sound] = nil
+		}
+	}
+	return nodes
+}
+
+// True if the table has the pair of the given sounds.
+func (this *soundTable) has(sound, other string) bool {
+	first, ok := this.ids[sound]
+	if !ok {
+		return false
+	}
+	second, ok := this.ids[other]
+	if !ok {
+		return false
+	}
+	if this.adjacent == nil {
+		for _, next := range this.next[first] {
+			if next == second {
+				return true
+			}
+		}
+		return false
+	}
+	bit := int(first)*len(this.sounds) + int(second)
+	return this.adjacent[bit/64]&(1<<uint(bit%64)) != 0
+}
+
+/********************************** Statics **********************************/
+
+// Interns the sounds of the given pairs and indexes the pairs by their first
+// sound.
+func newSoundTable(pairs PairSet) *soundTable {
+	table := &soundTable{sounds: pairSounds(pairs)}
+	size := len(table.sounds)
+	table.ids = make(map[string]uint32, size)
+	for id, sound := range table.sounds {
+		table.ids[sound] = uint32(id)
+	}
+
+	table.next = make([][]uint32, size)
+	if size <= maxAdjacentSounds {
+		table.adjacent = make([]uint64, (size*size+63)/64)
+	}
+	for pair := range pairs {
+		first, second := table.ids[pair[0]], table.ids[pair[1]]
+		table.next[first] = append(table.next[first], second)
+		if table.adjacent != nil {
+			bit := int(first)*size + int(second)
+			table.adjacent[bit/64] |= 1 << uint(bit%64)
+		}
+	}
+	return table
+}
@@ -0,0 +1,117 @@
+package codex
+
+// Weighted mixtures of traits learned from different samples.
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+/********************************** Statics **********************************/
+
+// Returns new weighted traits that mix the styles of the two given traits in
+// the given proportion: `alpha` is the share of `a`, and the rest is the share
+// of `b`. For example, 0.7 makes derived words 70% like `a` and 30% like `b`,
+// where Traits.Merge() would weigh both samples by their size. Each pair of
+// sounds is weighted by its relative frequency in each sample, mixed in the
+// given proportion, and so are the first and last sounds and the lengths of
+// words; pairs whose mixed weight is zero are dropped, so 1 and 0 reproduce the
+// pairs of either traits. The rest is combined like in Traits.Merge(). Neither
+// input is modified. Returns an error if `alpha` is not between 0 and 1.
+func Interpolate(a, b *Traits, alpha float64) (*Traits, error) {
+	if !(alpha >= 0 && alpha <= 1) {
+		return nil, errors.New("expected a share between 0 and 1, got " +
+			strconv.FormatFloat(alpha, 'g', -1, 64))
+	}
+
+	out := a.Merge(b)
+	out.Weighted = true
+
+	totalA, totalB := a.totalPairWeight(), b.totalPairWeight()
+	out.PairCount = make(map[[2]string]int, len(out.PairSet))
+	for pair := range out.PairSet {
+		count := mixCount(
+			alpha, float64(a.sampleCount(pair))/totalA,
+			1-alpha, float64(b.sampleCount(pair))/totalB,
+		)
+		if count == 0 {
+			out.PairSet.Del(pair)
+			continue
+		}
+		out.PairCount[pair] = count
+	}
+	out.delOrphans()
+
+	// Starts and ends of words compete with pairs of sounds in the transition
+	// model, so they're scaled like pairs.
+	boundaries := out.BoundaryCount
+	out.BoundaryCount = nil
+	for pair := range boundaries {
+		count := mixCount(
+			alpha, float64(a.BoundaryCount[pair])/totalA,
+			1-alpha, float64(b.BoundaryCount[pair])/totalB,
+		)
+		if count > 0 {
+			if out.BoundaryCount == nil {
+				out.BoundaryCount = map[[2]string]int{}
+			}
+			out.BoundaryCount[pair] = count
+		}
+	}
+
+	totalA, totalB = totalLengthCount(a.LengthCount), totalLengthCount(b.LengthCount)
+	out.LengthCount = nil
+	for _, traits := range []*Traits{a, b} {
+		for length := range traits.LengthCount {
+			count := mixCount(
+				alpha, float64(a.LengthCount[length])/totalA,
+				1-alpha, float64(b.LengthCount[length])/totalB,
+			)
+			if count > 0 {
+				if out.LengthCount == nil {
+					out.LengthCount = map[int]int{}
+				}
+				out.LengthCount[length] = count
+			}
+		}
+	}
+
+	return out, nil
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Resolution of the counts of interpolated traits: a pair that makes up the
+// entire mixture is counted this many times.
+const interpolateScale = 10000
+
+// Returns the sum of the weights of the pairs of the traits, per
+// Traits.pairWeight(), or 1 if there are none, to be used as a divisor.
+func (this *Traits) totalPairWeight() float64 {
+	var total int
+	for pair := range this.PairSet {
+		total += this.pairWeight(pair)
+	}
+	return math.Max(float64(total), 1)
+}
+
+// Returns the sum of the given length counts, or 1 if there are none, to be
+// used as a divisor.
+func totalLengthCount(counts map[int]int) float64 {
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	return math.Max(float64(total), 1)
+}
+
+// Mixes two relative frequencies with the given shares and scales the result to
+// a count. Any nonzero mixture counts at least once.
+func mixCount(shareA, freqA, shareB, freqB float64) int {
+	mix := shareA*freqA + shareB*freqB
+	if mix <= 0 {
+		return 0
+	}
+	return int(math.Max(math.Round(mix*interpolateScale), 1))
+}
@@ -0,0 +1,286 @@
+package codex
+
+// JSON encoding of traits. The encoding is canonical: fields are written with
+// sorted keys and sets are written as sorted arrays, so equal traits always
+// encode to identical bytes, regardless of the order of the sample words or of
+// map iteration. This makes model files suitable for fingerprinting, diffing
+// and version control.
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*********************************** Type ************************************/
+
+// Serialisable mirror of Traits. Fields are declared in alphabetical order,
+// which is the order encoding/json writes them in.
+type traitsJSON struct {
+	Backoff       bool              `json:"Backoff,omitempty"`
+	BoundaryCount map[string]int    `json:"BoundaryCount,omitempty"`
+	Casing        Casing            `json:"Casing,omitempty"`
+	Connectives   []string          `json:"Connectives,omitempty"`
+	EndSet        [][2]string       `json:"EndSet,omitempty"`
+	Forbidden     [][2]string       `json:"Forbidden,omitempty"`
+	GramSet       []string          `json:"GramSet,omitempty"`
+	Harmony       bool              `json:"Harmony,omitempty"`
+	HarmonySet    [][2]string       `json:"HarmonySet,omitempty"`
+	KnownSounds   []string          `json:"KnownSounds,omitempty"`
+	KnownVowels   []string          `json:"KnownVowels,omitempty"`
+	LengthCount   map[int]int       `json:"LengthCount,omitempty"`
+	MaxConseqCons int               `json:"MaxConseqCons"`
+	MaxConseqVow  int               `json:"MaxConseqVow"`
+	MaxNSounds    int               `json:"MaxNSounds"`
+	MaxNVowels    int               `json:"MaxNVowels"`
+	MaxUnseen     int               `json:"MaxUnseen,omitempty"`
+	MaxWordLen    int               `json:"MaxWordLen,omitempty"`
+	MidSet        [][2]string       `json:"MidSet,omitempty"`
+	MinNSounds    int               `json:"MinNSounds"`
+	MinNVowels    int               `json:"MinNVowels"`
+	Order         int               `json:"Order,omitempty"`
+	PairCount     map[string]int    `json:"PairCount,omitempty"`
+	PairSet       [][2]string       `json:"PairSet"`
+	Positional    bool              `json:"Positional,omitempty"`
+	Rules         []ruleJSON        `json:"Rules,omitempty"`
+	Smoothing     float64           `json:"Smoothing,omitempty"`
+	SoundSet      []string          `json:"SoundSet"`
+	Spelling      map[string]string `json:"Spelling,omitempty"`
+	StartSet      [][2]string       `json:"StartSet,omitempty"`
+	Weighted      bool              `json:"Weighted,omitempty"`
+}
+
+// Serialisable mirror of Rule.
+type ruleJSON struct {
+	Pattern string `json:"Pattern"`
+	Replace string `json:"Replace"`
+}
+
+/********************************** Methods **********************************/
+
+// Implements json.Marshaler, producing the canonical encoding.
+func (this Traits) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.mirror())
+}
+
+// Implements json.Unmarshaler. Replaces the traits with the decoded ones.
+func (this *Traits) UnmarshalJSON(input []byte) error {
+	var value traitsJSON
+	if err := json.Unmarshal(input, &value); err != nil {
+		return err
+	}
+	return this.fromMirror(value)
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Returns the serialisable mirror of the traits, shared by the encodings.
+func (this Traits) mirror() traitsJSON {
+	return traitsJSON{
+		Backoff:       this.Backoff,
+		BoundaryCount: encodePairCount(this.BoundaryCount),
+		Casing:        this.Casing,
+		Connectives:   sortedSet(this.Connectives),
+		EndSet:        sortedPairSet(this.EndSet),
+		Forbidden:     sortedPairSet(this.Forbidden),
+		GramSet:       sortedSet(this.GramSet),
+		Harmony:       this.Harmony,
+		HarmonySet:    sortedPairSet(this.HarmonySet),
+		KnownSounds:   sortedSet(this.KnownSounds),
+		KnownVowels:   sortedSet(this.KnownVowels),
+		LengthCount:   this.LengthCount,
+		MaxConseqCons: this.MaxConseqCons,
+		MaxConseqVow:  this.MaxConseqVow,
+		MaxNSounds:    this.MaxNSounds,
+		MaxNVowels:    this.MaxNVowels,
+		MaxUnseen:     this.MaxUnseen,
+		MaxWordLen:    this.MaxWordLen,
+		MidSet:        sortedPairSet(this.MidSet),
+		MinNSounds:    this.MinNSounds,
+		MinNVowels:    this.MinNVowels,
+		Order:         this.Order,
+		PairCount:     encodePairCount(this.PairCount),
+		PairSet:       sortedPairSet(this.PairSet),
+		Positional:    this.Positional,
+		Rules:         encodeRules(this.Rules),
+		Smoothing:     this.Smoothing,
+		SoundSet:      sortedSet(this.SoundSet),
+		Spelling:      this.Spelling,
+		StartSet:      sortedPairSet(this.StartSet),
+		Weighted:      this.Weighted,
+	}
+}
+
+// Reverse of Traits.mirror(). Replaces the traits with the mirrored ones.
+func (this *Traits) fromMirror(value traitsJSON) error {
+	*this = Traits{
+		MinNSounds:    value.MinNSounds,
+		MaxNSounds:    value.MaxNSounds,
+		MinNVowels:    value.MinNVowels,
+		MaxNVowels:    value.MaxNVowels,
+		MaxConseqVow:  value.MaxConseqVow,
+		MaxConseqCons: value.MaxConseqCons,
+		SoundSet:      setFromSlice(value.SoundSet),
+		PairSet:       pairSetFromSlice(value.PairSet),
+		KnownSounds:   setFromSlice(value.KnownSounds),
+		KnownVowels:   setFromSlice(value.KnownVowels),
+		Connectives:   setFromSlice(value.Connectives),
+		Casing:        value.Casing,
+		MaxWordLen:    value.MaxWordLen,
+		Order:         value.Order,
+		GramSet:       setFromSlice(value.GramSet),
+		Backoff:       value.Backoff,
+		Positional:    value.Positional,
+		StartSet:      pairSetFromSlice(value.StartSet),
+		MidSet:        pairSetFromSlice(value.MidSet),
+		EndSet:        pairSetFromSlice(value.EndSet),
+		Harmony:       value.Harmony,
+		HarmonySet:    pairSetFromSlice(value.HarmonySet),
+		Spelling:      value.Spelling,
+		Weighted:      value.Weighted,
+		MaxUnseen:     value.MaxUnseen,
+		Smoothing:     value.Smoothing,
+		Forbidden:     pairSetFromSlice(value.Forbidden),
+		LengthCount:   value.LengthCount,
+	}
+	pairCount, err := decodePairCount(value.PairCount)
+	if err != nil {
+		return err
+	}
+	this.PairCount = pairCount
+	boundaryCount, err := decodePairCount(value.BoundaryCount)
+	if err != nil {
+		return err
+	}
+	this.BoundaryCount = boundaryCount
+	rules, err := decodeRules(value.Rules)
+	if err != nil {
+		return err
+	}
+	this.Rules = rules
+	return nil
+}
+
+/*********************************** Utils ***********************************/
+
+// Returns the elements of the given set as a sorted slice. A nil set produces a
+// nil slice.
+func sortedSet(set Set) []string {
+	if set == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Returns the elements of the given pair set as a sorted slice. A nil set
+// produces a nil slice.
+func sortedPairSet(set PairSet) [][2]string {
+	if set == nil {
+		return nil
+	}
+	keys := make([][2]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessPair(keys[i], keys[j]) })
+	return keys
+}
+
+// Inverse of sortedSet().
+func setFromSlice(keys []string) Set {
+	if keys == nil {
+		return nil
+	}
+	return Set.New(nil, keys...)
+}
+
+// Inverse of sortedPairSet().
+func pairSetFromSlice(keys [][2]string) PairSet {
+	if keys == nil {
+		return nil
+	}
+	return PairSet.New(nil, keys...)
+}
+
+// Orders pairs lexicographically.
+func lessPair(one, other [2]string) bool {
+	if one[0] != other[0] {
+		return one[0] < other[0]
+	}
+	return one[1] < other[1]
+}
+
+// Encodes pair counts as a map with keys like "a>b", which encoding/json writes
+// in sorted order. A nil map produces a nil map.
+func encodePairCount(counts map[[2]string]int) map[string]int {
+	if counts == nil {
+		return nil
+	}
+	out := make(map[string]int, len(counts))
+	for pair, count := range counts {
+		out[pair[0]+">"+pair[1]] = count
+	}
+	return out
+}
+
+// Reverse of encodePairCount().
+func decodePairCount(counts map[string]int) (map[[2]string]int, error) {
+	if counts == nil {
+		return nil, nil
+	}
+	out := make(map[[2]string]int, len(counts))
+	for key, count := range counts {
+		sounds := strings.Split(key, ">")
+		if len(sounds) != 2 || sounds[0] == "" || sounds[1] == "" {
+			return nil, errors.New("invalid pair " + strconv.Quote(key))
+		}
+		out[[2]string{sounds[0], sounds[1]}] = count
+	}
+	return out, nil
+}
+
+// Returns the keys of the given map, sorted.
+func sortedKeys(dict map[string]string) []string {
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Encodes rules as their patterns and replacements. Nil rules produce nil.
+func encodeRules(rules []Rule) []ruleJSON {
+	if rules == nil {
+		return nil
+	}
+	out := make([]ruleJSON, len(rules))
+	for index, rule := range rules {
+		out[index] = ruleJSON{Pattern: rule.Pattern.String(), Replace: rule.Replace}
+	}
+	return out
+}
+
+// Reverse of encodeRules(). Fails if a pattern doesn't compile.
+func decodeRules(rules []ruleJSON) ([]Rule, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	out := make([]Rule, len(rules))
+	for index, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		out[index] = Rule{Pattern: pattern, Replace: rule.Replace}
+	}
+	return out, nil
+}
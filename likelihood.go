@@ -0,0 +1,283 @@
+package codex
+
+// Likelihood of words under the transition model of traits, and search for the
+// most likely words.
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+/*********************************** Type ************************************/
+
+// Logarithms of the probabilities of the transitions in a traits' matrix. The
+// first sound of a word is chosen among the successors of the StartToken, each
+// next sound among the successors of the previous sound, in proportion to the
+// weight of the transition, and the word ends where the EndToken is chosen.
+// With smoothing, each sound may also be followed by any sound it isn't
+// followed by in the matrix, or end a word where the matrix doesn't end one.
+type chances struct {
+	start  map[string]float64
+	next   map[[2]string]float64
+	unseen map[string]float64
+	// Log probabilities of the numbers of sounds of words, per LengthCount.
+	// Empty without LengthCount, in which case lengths don't affect scores.
+	lengths map[int]float64
+	// Log probability of a number of sounds missing from `lengths`.
+	rareLength float64
+	// Known sounds of the traits, for splitting scored words.
+	known Set
+}
+
+// Partial word considered by the beam search in Traits.TopK().
+type candidate struct {
+	sounds []string
+	word   string
+	score  float64
+}
+
+/********************************** Methods **********************************/
+
+// Returns up to `k` words derived from the traits with the highest likelihood
+// under the transition model, most likely first. Finds them with a beam search,
+// which is deterministic: unlike generators, this always returns the same
+// words for the same traits. Shorter words tend to be more likely, since each
+// transition can only lower the likelihood.
+func (this *Traits) TopK(k int) []string {
+	if k <= 0 {
+		return nil
+	}
+	chances := this.chances()
+	width := k * topKBeamFactor
+
+	var beam, results []candidate
+	for _, sound := range this.children(nil) {
+		part := candidate{
+			sounds: []string{sound},
+			word:   sound,
+			score:  chances.start[sound],
+		}
+		if this.isWord(part.sounds) {
+			results = append(results, part.ended(chances))
+		}
+		beam = append(beam, part)
+	}
+
+	for len(beam) > 0 {
+		// Transitions, including the one to the end, only lower the likelihood,
+		// so once the beam can't beat the results, we're done.
+		if len(results) >= k {
+			sortCandidates(results)
+			results = results[:k]
+			if results[k-1].score >= beam[0].score {
+				break
+			}
+		}
+
+		var next []candidate
+		for _, part := range beam {
+			last := part.sounds[len(part.sounds)-1]
+			for _, sound := range this.children(part.sounds) {
+				child := candidate{
+					sounds: append(part.sounds[:len(part.sounds):len(part.sounds)], sound),
+					word:   part.word + sound,
+					score:  part.score + chances.transition(last, sound),
+				}
+				if this.isWord(child.sounds) {
+					results = append(results, child.ended(chances))
+				}
+				next = append(next, child)
+			}
+		}
+
+		sortCandidates(next)
+		if len(next) > width {
+			next = next[:width]
+		}
+		beam = next
+	}
+
+	// Different sequences of sounds may spell the same word.
+	sortCandidates(results)
+	words := make([]string, 0, k)
+	seen := Set{}
+	for _, result := range results {
+		if len(words) == k {
+			break
+		}
+		if !seen.Has(result.word) {
+			seen.Add(result.word)
+			words = append(words, this.styled(result.word))
+		}
+	}
+	return words
+}
+
+// Returns the log likelihood of the given word under the transition model of
+// the traits, which is the same model Traits.TopK() ranks by. Higher is more
+// likely; comparing scores lets applications rank candidate words by how well
+// they fit the sample, including words the traits didn't derive. Returns
+// negative infinity if the word has unknown sounds or pairs of sounds that
+// never occur in the sample, or can't begin or end with its first or last
+// sound. Casing is ignored. Longer words tend to score lower, since each
+// transition can only lower the likelihood. With LengthCount, the score also
+// includes the chance of the word's number of sounds among the sample words,
+// where numbers of sounds missing from it count as occurring once.
+func (this *Traits) Score(word string) float64 {
+	return this.score(this.chances(), word)
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Same as Traits.Score(), with the given chances of the traits, so callers that
+// score many words compute them once.
+func (this *Traits) score(chances chances, word string) float64 {
+	sounds, err := getSounds(strings.ToLower(word), chances.known)
+	if err != nil {
+		return math.Inf(-1)
+	}
+	return chances.logLikelihood(sounds)
+}
+
+// Number of partial words kept by the beam search in Traits.TopK(), per
+// requested word.
+const topKBeamFactor = 8
+
+// Computes the log probabilities of the transitions of the traits.
+func (this *Traits) chances() chances {
+	matrix := this.Matrix()
+	chances := chances{
+		start:  map[string]float64{},
+		next:   map[[2]string]float64{},
+		unseen: map[string]float64{},
+		known:  this.knownSounds(),
+	}
+
+	var starts float64
+	for _, weight := range matrix.Transitions[StartToken] {
+		starts += float64(weight)
+	}
+	for sound, weight := range matrix.Transitions[StartToken] {
+		chances.start[sound] = math.Log(float64(weight) / starts)
+	}
+
+	// Laplace smoothing adds the pseudo-count to each of the possible
+	// successors, which are all the sounds and the end of the word.
+	smoothing := math.Max(this.Smoothing, 0)
+	nSounds := float64(len(this.SoundSet))
+	for sound, successors := range matrix.Transitions {
+		if sound == StartToken {
+			continue
+		}
+		total := smoothing * (nSounds + 1)
+		for _, weight := range successors {
+			total += float64(weight)
+		}
+		for successor, weight := range successors {
+			chances.next[[2]string{sound, successor}] = math.Log((float64(weight) + smoothing) / total)
+		}
+		if smoothing > 0 {
+			chances.unseen[sound] = math.Log(smoothing / total)
+		}
+	}
+	if smoothing > 0 {
+		for sound := range this.SoundSet {
+			if _, ok := chances.unseen[sound]; !ok {
+				chances.unseen[sound] = -math.Log(nSounds + 1)
+			}
+		}
+	}
+
+	// Lengths that derived words may have, but that are missing from the
+	// counts, count as occurring once.
+	if len(this.LengthCount) > 0 {
+		var total float64
+		for length := this.minWordSounds(); length <= this.MaxNSounds; length++ {
+			if this.LengthCount[length] < 1 {
+				total++
+			}
+		}
+		for _, count := range this.LengthCount {
+			if count > 0 {
+				total += float64(count)
+			}
+		}
+		chances.lengths = make(map[int]float64, len(this.LengthCount))
+		for length, count := range this.LengthCount {
+			if count > 0 {
+				chances.lengths[length] = math.Log(float64(count) / total)
+			}
+		}
+		chances.rareLength = -math.Log(total)
+	}
+
+	return chances
+}
+
+// Returns the log likelihood of the given sequence of sounds, or negative
+// infinity if the sequence is impossible under the transition model.
+func (this chances) logLikelihood(sounds []string) float64 {
+	if len(sounds) == 0 {
+		return math.Inf(-1)
+	}
+	score, ok := this.start[sounds[0]]
+	if !ok {
+		return math.Inf(-1)
+	}
+	for index := 1; index < len(sounds); index++ {
+		score += this.transition(sounds[index-1], sounds[index])
+	}
+	return score + this.transition(sounds[len(sounds)-1], EndToken) + this.length(len(sounds))
+}
+
+// Returns the log probability that a word has the given number of sounds, or 0
+// if the traits have no LengthCount.
+func (this chances) length(length int) float64 {
+	if len(this.lengths) == 0 {
+		return 0
+	}
+	if chance, ok := this.lengths[length]; ok {
+		return chance
+	}
+	return this.rareLength
+}
+
+// Returns the log probability that the given sound is followed by the other
+// sound, or negative infinity if the transition is impossible.
+func (this chances) transition(sound, other string) float64 {
+	if chance, ok := this.next[[2]string{sound, other}]; ok {
+		return chance
+	}
+	// Sounds that may follow others unseen are exactly the keys of `unseen`, and
+	// any of them may end a word.
+	if _, ok := this.unseen[other]; ok || other == EndToken {
+		if chance, ok := this.unseen[sound]; ok {
+			return chance
+		}
+	}
+	return math.Inf(-1)
+}
+
+// Returns the candidate as a complete word, with the likelihood of ending after
+// its last sound and of its length.
+func (this candidate) ended(chances chances) candidate {
+	// Added one by one, in the order of chances.logLikelihood(), so both give
+	// identical scores.
+	this.score += chances.transition(this.sounds[len(this.sounds)-1], EndToken)
+	this.score += chances.length(len(this.sounds))
+	return this
+}
+
+/*********************************** Utils ***********************************/
+
+// Sorts candidates from the most to the least likely. Ties are broken
+// alphabetically, which keeps the order deterministic.
+func sortCandidates(candidates []candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].word < candidates[j].word
+	})
+}
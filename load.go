@@ -0,0 +1,109 @@
+package codex
+
+// Loading of sample words from text files.
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/********************************** Statics **********************************/
+
+// Reads a list of sample words from the given reader, to be passed to
+// NewTraits() and friends. Words may be separated by newlines, commas or any
+// whitespace, so the same function reads one word per line, a comma-separated
+// list, or prose-like text. Text from "#" to the end of the line is a comment
+// and is ignored. Repeated words are dropped, keeping the order of their first
+// occurrence. Returns the words read before the first error of reading, along
+// with the error.
+//
+// To learn from a list too big to hold in memory, stream it with
+// NewTraitsFromReader() instead.
+func LoadWords(reader io.Reader) ([]string, error) {
+	var words []string
+	seen := Set{}
+	// Unlike bufio.Scanner, this reads lines of any length, such as long
+	// comma-separated lists.
+	buf := bufio.NewReader(reader)
+	for {
+		line, err := buf.ReadString('\n')
+		if index := strings.IndexByte(line, '#'); index >= 0 {
+			line = line[:index]
+		}
+		for _, word := range strings.FieldsFunc(line, isWordSeparator) {
+			if !seen.Has(word) {
+				seen.Add(word)
+				words = append(words, word)
+			}
+		}
+		if err == io.EOF {
+			return words, nil
+		}
+		if err != nil {
+			return words, err
+		}
+	}
+}
+
+// Reads a list of sample words from the file at the given path in the given
+// file system, such as an embed.FS or os.DirFS(), like LoadWords().
+func LoadWordsFS(fsys fs.FS, path string) ([]string, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadWords(file)
+}
+
+// Reads a frequency list from the given reader, to be passed to
+// Traits.ExamineWeighted(). Each line holds a word and its count, separated by
+// a tab or spaces, as published by most frequency datasets:
+//
+//	the	56271872
+//	of	33950064
+//
+// Blank lines and text from "#" to the end of the line are ignored. Counts of
+// repeated words are summed. Returns an error for lines without a positive
+// integer count, stating the number of the line.
+func LoadFrequencies(reader io.Reader) (map[string]int, error) {
+	counts := map[string]int{}
+	scanner := bufio.NewScanner(reader)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		if index := strings.IndexByte(line, '#'); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		index := strings.LastIndexAny(line, " \t")
+		if index < 0 {
+			return nil, errors.New("line " + strconv.Itoa(number) + ": expected a word and a count, got " + strconv.Quote(line))
+		}
+		word, field := strings.TrimSpace(line[:index]), line[index+1:]
+		count, err := strconv.Atoi(field)
+		if err != nil || count < 1 {
+			return nil, errors.New("line " + strconv.Itoa(number) + ": expected a positive count, got " + strconv.Quote(field))
+		}
+		counts[word] += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+/*********************************** Utils ***********************************/
+
+// Checks if the given rune separates words in lists read by LoadWords().
+func isWordSeparator(char rune) bool {
+	return char == ',' || unicode.IsSpace(char)
+}
@@ -0,0 +1,114 @@
+package codex
+
+// Plain-data view of the transitions learned by traits.
+
+import (
+	"errors"
+	"strconv"
+)
+
+/*********************************** Type ************************************/
+
+// Matrix is the transition model of a traits object as plain data, for use
+// outside of this package, for example in NumPy or R.
+type Matrix struct {
+	// Maps each sound to its successors, and each successor to the weight of
+	// the transition. The StartToken leads to the sounds that may begin a word,
+	// and the sounds that may end a word lead to the EndToken.
+	Transitions map[string]map[string]int
+	// Sounds that may begin a word.
+	Starts Set
+	// Sounds that may end a word.
+	Ends Set
+}
+
+/********************************** Methods **********************************/
+
+// Returns the transition model of the traits as plain data. Every pair of
+// sounds in the traits becomes a transition weighted by its count in
+// PairCount, or 1 if it has none. Every start sound becomes a transition from
+// the StartToken, and every end sound a transition to the EndToken, weighted
+// by its count in BoundaryCount, or 1 if it has none.
+func (this *Traits) Matrix() Matrix {
+	matrix := Matrix{
+		Transitions: map[string]map[string]int{},
+		Starts:      Set{},
+		Ends:        Set{},
+	}
+	for pair := range this.PairSet {
+		matrix.add(pair, this.pairWeight(pair))
+	}
+	for pair := range this.Starts() {
+		matrix.Starts.Add(pair[0])
+	}
+	for pair := range this.Ends() {
+		matrix.Ends.Add(pair[1])
+	}
+	for sound := range matrix.Starts {
+		matrix.add([2]string{StartToken, sound}, this.startWeight(sound))
+	}
+	for sound := range matrix.Ends {
+		matrix.add([2]string{sound, EndToken}, this.endWeight(sound))
+	}
+	return matrix
+}
+
+// Merges the transitions of the given matrix, which may have been computed
+// elsewhere, into the traits. Each transition with a positive weight becomes a
+// pair of sounds, and its weight is added to the pair's count; transitions
+// from the StartToken and to the EndToken are added to the BoundaryCount
+// instead. The Starts and Ends of the matrix are ignored, since traits derive
+// them from their pairs. Returns an error if the matrix has a sound that's not
+// among the known sounds, leaving the traits unchanged.
+//
+// A matrix has no numeric limits, so the caller must set them, for example
+// Traits.MinNSounds and Traits.MaxNSounds, before the traits can produce words.
+func (this *Traits) ImportMatrix(matrix Matrix) error {
+	known := this.knownSounds()
+	for sound, successors := range matrix.Transitions {
+		if sound != StartToken && !known.Has(sound) {
+			return errors.New("unknown sound " + strconv.Quote(sound))
+		}
+		for successor := range successors {
+			if (successor != EndToken || sound == StartToken) && !known.Has(successor) {
+				return errors.New("unknown sound " + strconv.Quote(successor))
+			}
+		}
+	}
+
+	for sound, successors := range matrix.Transitions {
+		for successor, weight := range successors {
+			pair := [2]string{sound, successor}
+			switch {
+			case weight <= 0:
+			case isBoundary(pair):
+				if this.BoundaryCount == nil {
+					this.BoundaryCount = map[[2]string]int{}
+				}
+				this.BoundaryCount[pair] += weight
+			default:
+				this.SoundSet.Add(sound)
+				this.SoundSet.Add(successor)
+				this.PairSet.Add(pair)
+				if this.PairCount == nil {
+					this.PairCount = map[[2]string]int{}
+				}
+				this.PairCount[pair] += weight
+			}
+		}
+	}
+
+	// Sounds may begin or end words only if they occur in pairs.
+	this.delOrphanBoundaries()
+	return nil
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Sets the weight of the given transition.
+func (this *Matrix) add(pair [2]string, weight int) {
+	if this.Transitions[pair[0]] == nil {
+		this.Transitions[pair[0]] = map[string]int{}
+	}
+	this.Transitions[pair[0]][pair[1]] = weight
+}
@@ -0,0 +1,29 @@
+package codex
+
+// Generators that trade speed for memory, for constrained targets.
+
+/*********************************** Type ************************************/
+
+// MemoryProfile defines how a generator created by Traits.GeneratorWith()
+// trades memory for speed.
+type MemoryProfile int
+
+const (
+	// The generator interns the transitions of the traits in a table, with an
+	// adjacency bitset for up to a few thousand sounds, which makes lookups
+	// fast. This is the default, used by Traits.Generator().
+	FastProfile MemoryProfile = iota
+	// The generator looks up transitions in the traits themselves, and takes no
+	// memory beyond the visited parts of the virtual tree, at the cost of speed.
+	// Suits constrained targets like mobile devices and WASM.
+	LowMemoryProfile
+)
+
+/********************************** Methods **********************************/
+
+// Same as Traits.Generator(), using the given memory profile. Both profiles
+// yield the same words.
+func (this *Traits) GeneratorWith(profile MemoryProfile) func() string {
+	st := &state{traits: this, rnd: newRand(), compact: profile == LowMemoryProfile}
+	return this.cased(st.next)
+}
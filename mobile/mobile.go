@@ -0,0 +1,93 @@
+/*
+
+Wrapper around codex suitable for gomobile bindings on iOS and Android. The API
+only uses types supported by `gomobile bind`: strings, ints, errors, and
+pointers to exported structs. Lists of words are passed around as
+newline-separated strings.
+
+Build with:
+
+	gomobile bind -target=android github.com/Mitranim/codex/mobile
+	gomobile bind -target=ios github.com/Mitranim/codex/mobile
+
+*/
+package mobile
+
+import (
+	"strings"
+
+	"github.com/Mitranim/codex"
+)
+
+/*********************************** Type ************************************/
+
+// Generator makes random non-repeating words derived from a sample.
+type Generator struct {
+	traits *codex.Traits
+	gen    func() string
+}
+
+/********************************** Methods **********************************/
+
+// Returns the next word, or "" when no more words can be made.
+func (this *Generator) Next() string {
+	return this.gen()
+}
+
+// Returns up to `n` next words, separated by newlines, or "" if `n` isn't
+// positive.
+func (this *Generator) NextN(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	// Grown as needed, since `n` may far exceed the number of words left.
+	var words []string
+	for len(words) < n {
+		word := this.gen()
+		if word == "" {
+			break
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, "\n")
+}
+
+// Starts over, forgetting the words returned so far. Subsequent words may
+// repeat the previous ones.
+func (this *Generator) Reset() {
+	this.gen = this.traits.Generator()
+}
+
+/********************************** Statics **********************************/
+
+// Analyses the given newline-separated sample words and creates a generator of
+// words derived from them.
+func NewGenerator(sample string) (*Generator, error) {
+	traits, err := codex.NewTraits(splitLines(sample))
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{traits: traits, gen: traits.Generator()}, nil
+}
+
+// Analyses the given newline-separated sample words and returns up to `n`
+// derived words, separated by newlines.
+func Generate(sample string, n int) (string, error) {
+	gen, err := NewGenerator(sample)
+	if err != nil {
+		return "", err
+	}
+	return gen.NextN(n), nil
+}
+
+// Splits the given text into non-empty trimmed lines.
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,87 @@
+package codex
+
+// Traits that may be replaced at runtime, for example when a model file is
+// reloaded, while generators derived from them are in use.
+
+import (
+	"sync/atomic"
+)
+
+/*********************************** Type ************************************/
+
+// Model holds traits that may be atomically replaced while generators derived
+// from them are in use. Traits passed to a model must not be modified
+// afterwards; to change them, swap in a modified copy. A model is safe for
+// concurrent use. Use NewModel() to create one.
+type Model struct {
+	value atomic.Value
+}
+
+// SwapPolicy defines what a generator created by Model.Generator() does when
+// the model's traits are replaced.
+type SwapPolicy int
+
+const (
+	// The generator keeps deriving words from the traits it started with until
+	// they're exhausted, ignoring replacements. Generators created afterwards
+	// use the new traits.
+	FinishOld SwapPolicy = iota
+	// The generator switches to the new traits at the next word. Words stay
+	// unique across the switch, at the cost of remembering every returned word.
+	SwitchNext
+)
+
+/********************************** Methods **********************************/
+
+// Returns the current traits.
+func (this *Model) Traits() *Traits {
+	traits, _ := this.value.Load().(*Traits)
+	return traits
+}
+
+// Replaces the traits. Generators in use react per their swap policy. Nil is
+// treated as empty traits, which derive no words.
+func (this *Model) Swap(traits *Traits) {
+	if traits == nil {
+		traits = new(Traits)
+	}
+	this.value.Store(traits)
+}
+
+// Creates a generator function that derives words from the model's traits,
+// reacting to replacements of the traits per the given policy. Like other
+// generators, it's not safe for concurrent use. When the traits are exhausted,
+// further calls return "", until the traits are replaced if the policy is
+// SwitchNext.
+func (this *Model) Generator(policy SwapPolicy) func() string {
+	traits := this.Traits()
+	gen := traits.Generator()
+	if policy != SwitchNext {
+		return gen
+	}
+
+	seen := Set{}
+	return func() string {
+		if current := this.Traits(); current != traits {
+			traits = current
+			gen = traits.Generator()
+		}
+		for word := gen(); word != ""; word = gen() {
+			if !seen.Has(word) {
+				seen.Add(word)
+				return word
+			}
+		}
+		return ""
+	}
+}
+
+/********************************** Statics **********************************/
+
+// Creates a model holding the given traits. Nil is treated as empty traits,
+// like in Model.Swap().
+func NewModel(traits *Traits) *Model {
+	model := new(Model)
+	model.Swap(traits)
+	return model
+}
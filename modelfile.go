@@ -0,0 +1,73 @@
+package codex
+
+// Versioned file format for trained traits. A model file starts with a magic
+// string and a format version, followed by the binary encoding of the traits,
+// so files written by other releases are either read correctly or rejected
+// with a clear error.
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+/********************************** Globals **********************************/
+
+// Magic string that starts every model file.
+const modelMagic = "CDXM"
+
+// Version of the model file format written by Traits.SaveModel(). Increase it
+// whenever a change of the binary encoding would make older releases misread
+// the payload.
+const modelVersion = 1
+
+/********************************** Methods **********************************/
+
+// Writes the traits to the given writer as a versioned model file, to be read
+// with LoadModel(). Unlike the bare binary encoding, a model file can be
+// identified as such, and a release that can't read it says so.
+func (this *Traits) SaveModel(writer io.Writer) error {
+	payload, err := this.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	header := make([]byte, len(modelMagic)+2)
+	copy(header, modelMagic)
+	binary.BigEndian.PutUint16(header[len(modelMagic):], modelVersion)
+	if _, err := writer.Write(header); err != nil {
+		return err
+	}
+	_, err = writer.Write(payload)
+	return err
+}
+
+/********************************** Statics **********************************/
+
+// Reads traits from a model file written by Traits.SaveModel(). Returns
+// ErrNotModel if the input isn't a model file, and a *VersionError if it was
+// written in a newer format version.
+func LoadModel(reader io.Reader) (*Traits, error) {
+	header := make([]byte, len(modelMagic)+2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotModel
+		}
+		return nil, err
+	}
+	if string(header[:len(modelMagic)]) != modelMagic {
+		return nil, ErrNotModel
+	}
+	version := int(binary.BigEndian.Uint16(header[len(modelMagic):]))
+	if version < 1 || version > modelVersion {
+		return nil, &VersionError{Version: version, Supported: modelVersion}
+	}
+
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	traits := new(Traits)
+	if err := traits.UnmarshalBinary(payload); err != nil {
+		return nil, err
+	}
+	return traits, nil
+}
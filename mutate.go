@@ -0,0 +1,84 @@
+package codex
+
+// Variants of existing words made by small edits of their sounds.
+
+/********************************** Methods **********************************/
+
+// Returns up to `n` random distinct variants of the given word, each made by
+// one of the following edits:
+//   1) swapping two adjacent sounds;
+//   2) inserting one of the traits' sounds;
+//   3) dropping a sound.
+// Only variants that qualify as words derived from the traits are returned, so
+// the result may have fewer than `n` words, and is empty if `n` is 0 or
+// negative. Returns an error if the word can't be split into known sounds.
+func (this *Traits) Mutate(word string, n int) (Set, error) {
+	if n <= 0 {
+		return Set{}, nil
+	}
+
+	sounds, err := getSounds(word, this.knownSounds())
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := Set{}
+	consider := func(variant []string) {
+		if this.derivable(variant) {
+			candidates.Add(join(variant, ""))
+		}
+	}
+
+	for index := range sounds {
+		// Swap with the next sound.
+		if index+1 < len(sounds) {
+			variant := append([]string(nil), sounds...)
+			variant[index], variant[index+1] = variant[index+1], variant[index]
+			consider(variant)
+		}
+
+		// Drop this sound.
+		consider(append(append([]string(nil), sounds[:index]...), sounds[index+1:]...))
+	}
+
+	// Insert a sound at each position, including the end.
+	for index := 0; index <= len(sounds); index++ {
+		for sound := range this.SoundSet {
+			variant := make([]string, 0, len(sounds)+1)
+			variant = append(variant, sounds[:index]...)
+			variant = append(variant, sound)
+			variant = append(variant, sounds[index:]...)
+			consider(variant)
+		}
+	}
+
+	candidates.Del(word)
+
+	keys := sortedSet(candidates)
+	shuffle(newRand(), keys)
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return Set.New(nil, keys...), nil
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Checks whether the given sequence of sounds is a complete word derived from
+// the traits. Unlike Traits.validComplete(), this doesn't assume that the
+// sequence was produced by a tree traversal, and verifies each pair.
+func (this *Traits) derivable(sounds []string) bool {
+	if len(sounds) == 0 {
+		return false
+	}
+	for pair := range getPairs(sounds) {
+		if this.Forbidden.Has(pair) {
+			return false
+		}
+		if !this.PairSet.Has(pair) && (this.MaxUnseen <= 0 ||
+			!this.SoundSet.Has(pair[0]) || !this.SoundSet.Has(pair[1])) {
+			return false
+		}
+	}
+	return this.validComplete(sounds...)
+}
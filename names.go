@@ -0,0 +1,88 @@
+package codex
+
+// Multi-part names, such as a first name followed by a surname, made of words
+// derived from one or two traits objects.
+
+/********************************** Statics **********************************/
+
+// Creates a generator function that returns a new two-part name on each call.
+// The first part is derived from `first` and the second from `last`; pass the
+// same traits twice to derive both parts from one sample. If `related` is true,
+// both parts of each name share at least one sound. Each part is cased per the
+// casing of its traits. Names never repeat. When no more names can be made,
+// further calls return two empty strings.
+func NameGenerator(first, last *Traits, related bool) func() (string, string) {
+	// Casing is applied to complete names, since the sounds of cased words
+	// can't be looked up.
+	firsts := (&state{traits: first, rnd: newRand()}).next
+	lasts := (&state{traits: last, rnd: newRand()}).next
+
+	// Surnames that didn't fit previous first names. We keep them around for
+	// later first names rather than losing them from the word set.
+	var pending []string
+
+	return func() (string, string) {
+		for name := firsts(); name != ""; name = firsts() {
+			if !related {
+				surname := lasts()
+				if surname == "" {
+					break
+				}
+				return first.styled(name), last.styled(surname)
+			}
+
+			sounds := first.soundSet(name)
+
+			for index, surname := range pending {
+				if sharesSound(sounds, last.soundSet(surname)) {
+					pending = append(pending[:index], pending[index+1:]...)
+					return first.styled(name), last.styled(surname)
+				}
+			}
+
+			for surname := lasts(); surname != ""; surname = lasts() {
+				if sharesSound(sounds, last.soundSet(surname)) {
+					return first.styled(name), last.styled(surname)
+				}
+				pending = append(pending, surname)
+			}
+		}
+		return "", ""
+	}
+}
+
+// Returns up to `n` two-part names, as defined by NameGenerator(). Fewer names
+// are returned if the word sets are exhausted, and none if `n` isn't positive.
+func NameN(first, last *Traits, n int, related bool) [][2]string {
+	if n <= 0 {
+		return nil
+	}
+	gen := NameGenerator(first, last, related)
+	names := make([][2]string, 0, n)
+	for len(names) < n {
+		name, surname := gen()
+		if name == "" {
+			break
+		}
+		names = append(names, [2]string{name, surname})
+	}
+	return names
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Returns the set of sounds that make up the given derived word.
+func (this *Traits) soundSet(word string) Set {
+	sounds, _ := getSounds(word, this.knownSounds())
+	return Set.New(nil, sounds...)
+}
+
+// Checks if the given sets have at least one sound in common.
+func sharesSound(one, other Set) bool {
+	for sound := range one {
+		if other.Has(sound) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package codex
+
+// Sets of words with a stable iteration order.
+
+import (
+	"sort"
+)
+
+/*********************************** Type ************************************/
+
+// SetOrder defines the order in which an OrderedSet lists its words.
+type SetOrder int
+
+const (
+	// Words are listed in the order they were first added. This is the
+	// default.
+	InsertionOrder SetOrder = iota
+	// Words are listed sorted, regardless of the order they were added.
+	SortedOrder
+)
+
+// OrderedSet is a set of words, like Set, that lists its words in a stable
+// order chosen at construction, so output is reproducible without sorting.
+// The zero value is an empty set in insertion order. Create one with
+// NewOrderedSet() to choose the order. Operations that return new sets keep
+// the order of the receiver.
+type OrderedSet struct {
+	order SetOrder
+	keys  []string
+	index Set
+}
+
+/********************************** Methods **********************************/
+
+// Returns the order of the set.
+func (this *OrderedSet) Order() SetOrder {
+	return this.order
+}
+
+// Adds the given word, unless it's already present. In insertion order, a
+// word added again keeps its original position.
+func (this *OrderedSet) Add(key string) {
+	if this.index.Has(key) {
+		return
+	}
+	this.index.Add(key)
+
+	if this.order == SortedOrder {
+		i := sort.SearchStrings(this.keys, key)
+		this.keys = append(this.keys, "")
+		copy(this.keys[i+1:], this.keys[i:])
+		this.keys[i] = key
+		return
+	}
+	this.keys = append(this.keys, key)
+}
+
+// Deletes the given word. Takes time proportional to the size of the set.
+func (this *OrderedSet) Del(key string) {
+	if !this.index.Has(key) {
+		return
+	}
+	this.index.Del(key)
+	i := indexOf(this.keys, key)
+	this.keys = append(this.keys[:i], this.keys[i+1:]...)
+}
+
+// Checks for the presence of the given word.
+func (this *OrderedSet) Has(key string) bool {
+	return this.index.Has(key)
+}
+
+// Returns the number of words in the set.
+func (this *OrderedSet) Len() int {
+	return len(this.keys)
+}
+
+// Returns the words of the set in its order. The slice is a copy, and may be
+// modified.
+func (this *OrderedSet) Slice() []string {
+	return append([]string(nil), this.keys...)
+}
+
+// Returns the words as an unordered Set.
+func (this *OrderedSet) Set() Set {
+	return Set.New(nil, this.keys...)
+}
+
+// Returns a new set with the words of both sets. In insertion order, the words
+// of this set come first, followed by the new words of the other set.
+func (this *OrderedSet) Union(other *OrderedSet) *OrderedSet {
+	out := this.empty()
+	for _, key := range this.keys {
+		out.Add(key)
+	}
+	for _, key := range other.keys {
+		out.Add(key)
+	}
+	return out
+}
+
+// Returns a new set with the words present in both sets, in the order of this
+// set.
+func (this *OrderedSet) Intersect(other *OrderedSet) *OrderedSet {
+	return this.Filter(other.Has)
+}
+
+// Returns a new set with the words of this set missing from the other set, in
+// the order of this set.
+func (this *OrderedSet) Diff(other *OrderedSet) *OrderedSet {
+	return this.Filter(func(key string) bool { return !other.Has(key) })
+}
+
+// Returns a new set with the words for which the given function returns true,
+// in the order of this set.
+func (this *OrderedSet) Filter(pred func(string) bool) *OrderedSet {
+	out := this.empty()
+	for _, key := range this.keys {
+		if pred(key) {
+			out.Add(key)
+		}
+	}
+	return out
+}
+
+// Returns an empty set with the same order.
+func (this *OrderedSet) empty() *OrderedSet {
+	return &OrderedSet{order: this.order}
+}
+
+/********************************** Statics **********************************/
+
+// Creates a set with the given order and words, for example
+// NewOrderedSet(SortedOrder, words.Slice()...).
+func NewOrderedSet(order SetOrder, keys ...string) *OrderedSet {
+	set := &OrderedSet{order: order}
+	for _, key := range keys {
+		set.Add(key)
+	}
+	return set
+}
@@ -0,0 +1,83 @@
+package codex
+
+// Enumeration of the whole word set on all CPU cores.
+
+import (
+	"runtime"
+)
+
+/********************************** Methods **********************************/
+
+// Returns every word of the traits. Unlike exhausting a generator, the
+// traversal is split by starting pair into branches, which are enumerated by
+// a pool of goroutines, one per GOMAXPROCS, and merged into one set, so big
+// enumerations take a fraction of the time on machines with many cores. The
+// traits must not be modified during the call. Holds every word in memory;
+// see Traits.WordsTo() or Traits.Enumerate() to stream them instead.
+// Traits.WordsN() stays sequential: its words are a random selection, which
+// one generator keeps uniform, while split branches would each be exhausted
+// in turn.
+func (this *Traits) Words() Set {
+	return this.words(runtime.GOMAXPROCS(0))
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Same as Traits.Words(), with the given number of workers.
+func (this *Traits) words(workers int) Set {
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Words of a single sound are collected here; longer words are in the
+	// branches of the starting pairs.
+	words := Set{}
+	var branches [][]string
+	for _, first := range this.children(nil) {
+		path := []string{first}
+		if this.isWord(path) {
+			words.Add(this.styled(first))
+		}
+		for _, second := range this.children(path) {
+			branches = append(branches, []string{first, second})
+		}
+	}
+
+	jobs := make(chan []string)
+	results := make(chan Set, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			out := Set{}
+			for path := range jobs {
+				this.collectFrom(path, out)
+			}
+			results <- out
+		}()
+	}
+	for _, path := range branches {
+		jobs <- path
+	}
+	close(jobs)
+
+	for i := 0; i < workers; i++ {
+		out := <-results
+		if len(out) > len(words) {
+			words, out = out, words
+		}
+		for word := range out {
+			words.Add(word)
+		}
+	}
+	return words
+}
+
+// Adds the words in the subtree of the virtual tree at the given path,
+// including the path itself, to the given set.
+func (this *Traits) collectFrom(path []string, out Set) {
+	if this.isWord(path) {
+		out.Add(this.styled(join(path, "")))
+	}
+	for _, sound := range this.children(path) {
+		this.collectFrom(append(path[:len(path):len(path)], sound), out)
+	}
+}
@@ -0,0 +1,74 @@
+package codex
+
+// Phoneme input, such as IPA transcriptions, and spelling of derived words.
+
+import (
+	"errors"
+	"strings"
+)
+
+/********************************** Methods **********************************/
+
+// Analyses the given words, written as phonemes separated by spaces or dots,
+// and merges their attributes into self. This decouples the traits from the
+// quirks of a spelling: for example, the IPA transcriptions "ʃ.ɪ.p" and "tʃ ɪ p"
+// have three sounds each. Every phoneme becomes a known sound, replacing the
+// default ones. Set KnownVowels to the vowel phonemes beforehand, and Spelling
+// to spell derived words in an orthography.
+//
+// The separators only define the phonemes. Like any other sample word, each
+// word is then split by the longest matching known sound, so a phoneme that
+// spells like a sequence of others, like "tʃ" for "t" and "ʃ", takes
+// precedence over them.
+func (this *Traits) ExaminePhonemes(words []string) error {
+	if this == nil {
+		return errors.New("can't examine with nil pointer")
+	}
+
+	joined := make([]string, 0, len(words))
+	for _, word := range words {
+		phonemes := strings.FieldsFunc(word, isPhonemeSeparator)
+		if this.KnownSounds == nil {
+			this.KnownSounds = Set{}
+		}
+		for _, phoneme := range phonemes {
+			this.KnownSounds.Add(phoneme)
+		}
+		joined = append(joined, join(phonemes, ""))
+	}
+
+	return this.Examine(joined)
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Spells the given derived word per the traits' Spelling. Words that don't
+// split into known sounds are returned as is.
+func (this *Traits) spell(word string) string {
+	if len(this.Spelling) == 0 {
+		return word
+	}
+	sounds, err := getSounds(word, this.knownSounds())
+	if err != nil {
+		return word
+	}
+	for index, sound := range sounds {
+		if spelling, ok := this.Spelling[sound]; ok {
+			sounds[index] = spelling
+		}
+	}
+	return join(sounds, "")
+}
+
+// Applies the traits' spelling, rules and casing to the given derived word.
+func (this *Traits) styled(word string) string {
+	return this.Casing.apply(this.rewrite(this.spell(word)))
+}
+
+/*********************************** Utils ***********************************/
+
+// Checks if the given character separates phonemes in the input of
+// Traits.ExaminePhonemes().
+func isPhonemeSeparator(char rune) bool {
+	return char == '.' || char == ' ' || char == '\t'
+}
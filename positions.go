@@ -0,0 +1,98 @@
+package codex
+
+// Positional pairs: which pairs of sounds occur at the start, in the middle and
+// at the end of sample words.
+
+import (
+	"errors"
+	"strconv"
+)
+
+/********************************** Methods **********************************/
+
+// Records the positions of the pairs of the given sounds. Does nothing unless
+// the traits are positional.
+func (this *Traits) examinePositions(sounds []string) {
+	if !this.Positional || len(sounds) < 2 {
+		return
+	}
+	last := len(sounds) - 2
+	this.StartSet.Add([2]string{sounds[0], sounds[1]})
+	this.EndSet.Add([2]string{sounds[last], sounds[last+1]})
+	for i := 1; i < last; i++ {
+		this.MidSet.Add([2]string{sounds[i], sounds[i+1]})
+	}
+}
+
+// Checks if the pairs of the given partial word occur in their positions: the
+// first pair must be in the StartSet, and every pair between the first and the
+// last must be in the MidSet. The last pair may continue the word or end it,
+// so it must be in either the MidSet or the EndSet. A single sound must begin
+// a pair in the StartSet. Always true unless the traits are positional.
+func (this *Traits) validPositions(sounds []string) bool {
+	if !this.Positional || len(sounds) == 0 {
+		return true
+	}
+	if len(sounds) == 1 {
+		for pair := range this.StartSet {
+			if pair[0] == sounds[0] {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !this.StartSet.Has([2]string{sounds[0], sounds[1]}) {
+		return false
+	}
+	last := len(sounds) - 2
+	for i := 1; i < last; i++ {
+		if !this.MidSet.Has([2]string{sounds[i], sounds[i+1]}) {
+			return false
+		}
+	}
+	if last > 0 {
+		pair := [2]string{sounds[last], sounds[last+1]}
+		if !this.MidSet.Has(pair) && !this.EndSet.Has(pair) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks if the given complete word ends with a pair from the EndSet. Always
+// true unless the traits are positional.
+func (this *Traits) validEnding(sounds []string) bool {
+	if !this.Positional || len(sounds) < 2 {
+		return true
+	}
+	return this.EndSet.Has([2]string{sounds[len(sounds)-2], sounds[len(sounds)-1]})
+}
+
+// Checks if the given pair may end a derived word.
+func (this *Traits) validEnd(pair [2]string) bool {
+	return this.validSequence(nil, pair[:]) && this.validEnding(pair[:]) && this.validLast(nil, pair[:])
+}
+
+// Deletes positions of pairs missing from the PairSet.
+func (this *Traits) delOrphanPositions() {
+	for _, set := range []PairSet{this.StartSet, this.MidSet, this.EndSet} {
+		for pair := range set {
+			if !this.PairSet.Has(pair) {
+				set.Del(pair)
+			}
+		}
+	}
+}
+
+// Checks that every positioned pair is in the PairSet.
+func (this *Traits) validatePositions() error {
+	for _, set := range []PairSet{this.StartSet, this.MidSet, this.EndSet} {
+		for pair := range set {
+			if !this.PairSet.Has(pair) {
+				return errors.New("positioned pair " + strconv.Quote(pair[0]+">"+pair[1]) + " is missing from PairSet")
+			}
+		}
+	}
+	return nil
+}
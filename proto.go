@@ -0,0 +1,496 @@
+package codex
+
+// Protocol buffer encoding of traits, per the schema in codex.proto, for
+// exchanging models with systems written in other languages. The wire format
+// is implemented here, so the package doesn't depend on a protobuf runtime.
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+)
+
+/*********************************** Type ************************************/
+
+// Wire types of protocol buffers used by the schema.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// Buffer that protocol buffer fields are appended to.
+type protoBuffer []byte
+
+// Reader of the fields of an encoded protocol buffer message.
+type protoReader []byte
+
+/********************************** Methods **********************************/
+
+// Encodes the traits as a Traits message of codex.proto. Like the JSON
+// encoding, the output is canonical: equal traits produce identical bytes.
+func (this Traits) MarshalProto() ([]byte, error) {
+	var buf protoBuffer
+	buf.int(1, this.MinNSounds)
+	buf.int(2, this.MaxNSounds)
+	buf.int(3, this.MinNVowels)
+	buf.int(4, this.MaxNVowels)
+	buf.int(5, this.MaxConseqVow)
+	buf.int(6, this.MaxConseqCons)
+	buf.strings(7, sortedSet(this.SoundSet))
+	buf.pairs(8, this.PairSet)
+	buf.strings(9, sortedSet(this.KnownSounds))
+	buf.strings(10, sortedSet(this.KnownVowels))
+	buf.strings(11, sortedSet(this.Connectives))
+	buf.int(12, int(this.Casing))
+	buf.int(13, this.MaxWordLen)
+	buf.int(14, this.Order)
+	buf.strings(15, sortedSet(this.GramSet))
+	buf.bool(16, this.Backoff)
+	buf.bool(17, this.Positional)
+	buf.pairs(18, this.StartSet)
+	buf.pairs(19, this.MidSet)
+	buf.pairs(20, this.EndSet)
+	buf.bool(21, this.Harmony)
+	buf.pairs(22, this.HarmonySet)
+	for _, sound := range sortedKeys(this.Spelling) {
+		var msg protoBuffer
+		msg.string(1, sound)
+		msg.string(2, this.Spelling[sound])
+		buf.message(23, msg)
+	}
+	for _, rule := range this.Rules {
+		var msg protoBuffer
+		if rule.Pattern != nil {
+			msg.string(1, rule.Pattern.String())
+		}
+		msg.string(2, rule.Replace)
+		buf.message(24, msg)
+	}
+	buf.bool(25, this.Weighted)
+	buf.int(26, this.MaxUnseen)
+	buf.double(27, this.Smoothing)
+	buf.pairs(28, this.Forbidden)
+	buf.pairCounts(29, this.PairCount)
+	for _, length := range sortedLengths(this.LengthCount) {
+		var msg protoBuffer
+		msg.int(1, length)
+		msg.int(2, this.LengthCount[length])
+		buf.message(30, msg)
+	}
+	buf.pairCounts(31, this.BoundaryCount)
+	return buf, nil
+}
+
+// Decodes a Traits message of codex.proto, replacing the traits with the
+// decoded ones. Unknown fields are skipped, so messages from newer versions
+// of the schema can be read. Fails if the message is malformed or a rule
+// doesn't compile.
+func (this *Traits) UnmarshalProto(input []byte) error {
+	var value traitsJSON
+	ints := map[int]*int{
+		1:  &value.MinNSounds,
+		2:  &value.MaxNSounds,
+		3:  &value.MinNVowels,
+		4:  &value.MaxNVowels,
+		5:  &value.MaxConseqVow,
+		6:  &value.MaxConseqCons,
+		13: &value.MaxWordLen,
+		14: &value.Order,
+		26: &value.MaxUnseen,
+	}
+	bools := map[int]*bool{
+		16: &value.Backoff,
+		17: &value.Positional,
+		21: &value.Harmony,
+		25: &value.Weighted,
+	}
+
+	reader := protoReader(input)
+	for len(reader) > 0 {
+		field, wire, err := reader.key()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case wire == protoVarint && (ints[field] != nil || bools[field] != nil || field == 12):
+			num, err := reader.varint()
+			if err != nil {
+				return err
+			}
+			if ints[field] != nil {
+				*ints[field] = int(num)
+			} else if bools[field] != nil {
+				*bools[field] = num != 0
+			} else {
+				value.Casing = Casing(num)
+			}
+
+		case field == 27 && wire == protoFixed64:
+			num, err := reader.fixed64()
+			if err != nil {
+				return err
+			}
+			value.Smoothing = math.Float64frombits(num)
+
+		case wire == protoBytes && isProtoStringField(field):
+			str, err := reader.bytes()
+			if err != nil {
+				return err
+			}
+			list := protoStringField(&value, field)
+			*list = append(*list, string(str))
+
+		case wire == protoBytes && isProtoPairField(field):
+			msg, err := reader.bytes()
+			if err != nil {
+				return err
+			}
+			fields, err := protoReader(msg).strings()
+			if err != nil {
+				return err
+			}
+			list := protoPairField(&value, field)
+			*list = append(*list, [2]string{fields[1], fields[2]})
+
+		case wire == protoBytes && (field == 29 || field == 31):
+			msg, err := reader.bytes()
+			if err != nil {
+				return err
+			}
+			pair, count, err := protoReader(msg).pairCount()
+			if err != nil {
+				return err
+			}
+			counts := &value.PairCount
+			if field == 31 {
+				counts = &value.BoundaryCount
+			}
+			if *counts == nil {
+				*counts = map[string]int{}
+			}
+			(*counts)[pair[0]+">"+pair[1]] = count
+
+		case wire == protoBytes && field == 23:
+			msg, err := reader.bytes()
+			if err != nil {
+				return err
+			}
+			fields, err := protoReader(msg).strings()
+			if err != nil {
+				return err
+			}
+			if value.Spelling == nil {
+				value.Spelling = map[string]string{}
+			}
+			value.Spelling[fields[1]] = fields[2]
+
+		case wire == protoBytes && field == 24:
+			msg, err := reader.bytes()
+			if err != nil {
+				return err
+			}
+			fields, err := protoReader(msg).strings()
+			if err != nil {
+				return err
+			}
+			value.Rules = append(value.Rules, ruleJSON{Pattern: fields[1], Replace: fields[2]})
+
+		case wire == protoBytes && field == 30:
+			msg, err := reader.bytes()
+			if err != nil {
+				return err
+			}
+			pair, err := protoReader(msg).ints()
+			if err != nil {
+				return err
+			}
+			if value.LengthCount == nil {
+				value.LengthCount = map[int]int{}
+			}
+			value.LengthCount[pair[1]] = pair[2]
+
+		default:
+			if err := reader.skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+	return this.fromMirror(value)
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Appends the key of a field with the given number and wire type.
+func (this *protoBuffer) key(field, wire int) {
+	*this = binary.AppendUvarint(*this, uint64(field<<3|wire))
+}
+
+// Appends an int64 field, unless it's zero.
+func (this *protoBuffer) int(field, value int) {
+	if value != 0 {
+		this.key(field, protoVarint)
+		*this = binary.AppendUvarint(*this, uint64(int64(value)))
+	}
+}
+
+// Appends a bool field, unless it's false.
+func (this *protoBuffer) bool(field int, value bool) {
+	if value {
+		this.int(field, 1)
+	}
+}
+
+// Appends a double field, unless it's zero.
+func (this *protoBuffer) double(field int, value float64) {
+	if value != 0 {
+		this.key(field, protoFixed64)
+		*this = binary.LittleEndian.AppendUint64(*this, math.Float64bits(value))
+	}
+}
+
+// Appends a string field, unless it's empty.
+func (this *protoBuffer) string(field int, value string) {
+	if value != "" {
+		this.bytes(field, value)
+	}
+}
+
+// Appends a length-delimited field, even if it's empty.
+func (this *protoBuffer) bytes(field int, value string) {
+	this.key(field, protoBytes)
+	*this = binary.AppendUvarint(*this, uint64(len(value)))
+	*this = append(*this, value...)
+}
+
+// Appends an embedded message field.
+func (this *protoBuffer) message(field int, msg protoBuffer) {
+	this.bytes(field, string(msg))
+}
+
+// Appends a repeated string field.
+func (this *protoBuffer) strings(field int, values []string) {
+	for _, value := range values {
+		this.bytes(field, value)
+	}
+}
+
+// Appends a repeated Pair field, in sorted order.
+func (this *protoBuffer) pairs(field int, set PairSet) {
+	for _, pair := range sortedPairSet(set) {
+		var msg protoBuffer
+		msg.string(1, pair[0])
+		msg.string(2, pair[1])
+		this.message(field, msg)
+	}
+}
+
+// Appends a repeated PairCount field, in sorted order.
+func (this *protoBuffer) pairCounts(field int, counts map[[2]string]int) {
+	for _, pair := range sortedPairSet(boundarySet(counts)) {
+		var msg protoBuffer
+		msg.string(1, pair[0])
+		msg.string(2, pair[1])
+		msg.int(3, counts[pair])
+		this.message(field, msg)
+	}
+}
+
+// Reads the key of the next field, returning its number and wire type.
+func (this *protoReader) key() (int, int, error) {
+	key, err := this.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	if key>>3 < 1 || key>>3 > math.MaxInt32 {
+		return 0, 0, errors.New("invalid field number " + strconv.FormatUint(key>>3, 10))
+	}
+	return int(key >> 3), int(key & 7), nil
+}
+
+// Reads a varint.
+func (this *protoReader) varint() (uint64, error) {
+	value, size := binary.Uvarint(*this)
+	if size <= 0 {
+		return 0, errors.New("invalid varint")
+	}
+	*this = (*this)[size:]
+	return value, nil
+}
+
+// Reads a fixed 64-bit value.
+func (this *protoReader) fixed64() (uint64, error) {
+	if len(*this) < 8 {
+		return 0, errors.New("unexpected end of input")
+	}
+	value := binary.LittleEndian.Uint64(*this)
+	*this = (*this)[8:]
+	return value, nil
+}
+
+// Reads a length-delimited value.
+func (this *protoReader) bytes() ([]byte, error) {
+	size, err := this.varint()
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(len(*this)) {
+		return nil, errors.New("unexpected end of input")
+	}
+	value := (*this)[:size]
+	*this = (*this)[size:]
+	return value, nil
+}
+
+// Skips a value of the given wire type.
+func (this *protoReader) skip(wire int) error {
+	var err error
+	switch wire {
+	case protoVarint:
+		_, err = this.varint()
+	case protoFixed64:
+		_, err = this.fixed64()
+	case protoBytes:
+		_, err = this.bytes()
+	case protoFixed32:
+		if len(*this) < 4 {
+			return errors.New("unexpected end of input")
+		}
+		*this = (*this)[4:]
+	default:
+		return errors.New("unsupported wire type " + strconv.Itoa(wire))
+	}
+	return err
+}
+
+// Reads the string fields 1 and 2 of a message, such as a Pair, skipping
+// others. Missing fields are empty. The result is indexed by field number.
+func (this protoReader) strings() ([3]string, error) {
+	var out [3]string
+	for len(this) > 0 {
+		field, wire, err := this.key()
+		if err != nil {
+			return out, err
+		}
+		if wire == protoBytes && field <= 2 {
+			value, err := this.bytes()
+			if err != nil {
+				return out, err
+			}
+			out[field] = string(value)
+		} else if err := this.skip(wire); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// Reads the int64 fields 1 and 2 of a message, such as a LengthCount, skipping
+// others. Missing fields are zero. The result is indexed by field number.
+func (this protoReader) ints() ([3]int, error) {
+	var out [3]int
+	for len(this) > 0 {
+		field, wire, err := this.key()
+		if err != nil {
+			return out, err
+		}
+		if wire == protoVarint && field <= 2 {
+			value, err := this.varint()
+			if err != nil {
+				return out, err
+			}
+			out[field] = int(value)
+		} else if err := this.skip(wire); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// Reads a PairCount message.
+func (this protoReader) pairCount() ([2]string, int, error) {
+	var pair [2]string
+	var count int
+	for len(this) > 0 {
+		field, wire, err := this.key()
+		if err != nil {
+			return pair, 0, err
+		}
+		switch {
+		case wire == protoBytes && field <= 2:
+			value, err := this.bytes()
+			if err != nil {
+				return pair, 0, err
+			}
+			pair[field-1] = string(value)
+		case wire == protoVarint && field == 3:
+			value, err := this.varint()
+			if err != nil {
+				return pair, 0, err
+			}
+			count = int(value)
+		default:
+			if err := this.skip(wire); err != nil {
+				return pair, 0, err
+			}
+		}
+	}
+	return pair, count, nil
+}
+
+/*********************************** Utils ***********************************/
+
+// Checks if the given field of the Traits message is a repeated string.
+func isProtoStringField(field int) bool {
+	switch field {
+	case 7, 9, 10, 11, 15:
+		return true
+	}
+	return false
+}
+
+// Returns the list of the mirror that holds the given repeated string field.
+func protoStringField(value *traitsJSON, field int) *[]string {
+	switch field {
+	case 7:
+		return &value.SoundSet
+	case 9:
+		return &value.KnownSounds
+	case 10:
+		return &value.KnownVowels
+	case 11:
+		return &value.Connectives
+	default:
+		return &value.GramSet
+	}
+}
+
+// Checks if the given field of the Traits message is a repeated Pair.
+func isProtoPairField(field int) bool {
+	switch field {
+	case 8, 18, 19, 20, 22, 28:
+		return true
+	}
+	return false
+}
+
+// Returns the list of the mirror that holds the given repeated Pair field.
+func protoPairField(value *traitsJSON, field int) *[][2]string {
+	switch field {
+	case 8:
+		return &value.PairSet
+	case 18:
+		return &value.StartSet
+	case 19:
+		return &value.MidSet
+	case 20:
+		return &value.EndSet
+	case 22:
+		return &value.HarmonySet
+	default:
+		return &value.Forbidden
+	}
+}
@@ -0,0 +1,67 @@
+package codex
+
+// Sources of randomness for generators, and warnings about the legacy global
+// source.
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+/********************************** Globals **********************************/
+
+// Optional function that receives warnings about deprecated behaviour, such as
+// the use of UseGlobalRand(). Nil by default, which discards the warnings.
+var Warn func(message string)
+
+// Set to 1 by UseGlobalRand(). Accessed atomically, since generators may be
+// created concurrently with the call.
+var globalRand int32
+
+// Makes sure the global source warning is reported once.
+var globalRandWarning sync.Once
+
+/********************************** Statics **********************************/
+
+// Restores the legacy behaviour of drawing every random number from the global
+// source of the "math/rand" package, shared with the rest of the program. By
+// default, each generator has its own source, seeded from the global one, so
+// generators don't affect each other or other users of the global source. The
+// legacy behaviour is deprecated and will be removed; while it's in effect, the
+// first generator reports a warning via Warn. This is meant to be called once
+// at startup, before creating any generators, but it's safe to call at any
+// time: generators created before the call keep their own sources.
+func UseGlobalRand() {
+	atomic.StoreInt32(&globalRand, 1)
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Creates the source of randomness for a new generator. Returns nil, meaning
+// the global source, if UseGlobalRand() is in effect.
+func newRand() *rand.Rand {
+	if atomic.LoadInt32(&globalRand) == 1 {
+		globalRandWarning.Do(func() {
+			warn("codex: UseGlobalRand() is deprecated; generators will use their own random sources")
+		})
+		return nil
+	}
+	return rand.New(rand.NewSource(rand.Int63()))
+}
+
+// Returns a random number in [0, n) drawn from the given source, or from the
+// global source if it's nil.
+func randIntn(rnd *rand.Rand, n int) int {
+	if rnd == nil {
+		return rand.Intn(n)
+	}
+	return rnd.Intn(n)
+}
+
+// Reports the given warning via Warn, if set.
+func warn(message string) {
+	if Warn != nil {
+		Warn(message)
+	}
+}
@@ -0,0 +1,74 @@
+package codex
+
+// Streaming of generated words through standard interfaces.
+
+import (
+	"io"
+)
+
+/*********************************** Type ************************************/
+
+// Reader streams the words of a generator function, optionally limited to a
+// budget of words. It implements io.Reader, writing one word per line, and
+// also provides the words one by one via Reader.Next(). Use NewReader() to
+// create one.
+type Reader struct {
+	gen    func() string
+	budget int
+	count  int
+	buf    []byte
+	err    error
+}
+
+/********************************** Methods **********************************/
+
+// Returns the next word. At the end of the stream, returns ErrExhausted if the
+// generator ran out of words, or ErrBudgetExceeded if the budget was used up.
+// Both match io.EOF per errors.Is().
+func (this *Reader) Next() (string, error) {
+	if this.err != nil {
+		return "", this.err
+	}
+	if this.budget > 0 && this.count >= this.budget {
+		this.err = ErrBudgetExceeded
+		return "", this.err
+	}
+	word := this.gen()
+	if word == "" {
+		this.err = ErrExhausted
+		return "", this.err
+	}
+	this.count++
+	return word, nil
+}
+
+// Implements io.Reader, writing each word followed by "\n". At the end of the
+// stream, returns io.EOF itself, as the io.Reader contract requires; the cause
+// is available from Reader.Err().
+func (this *Reader) Read(buf []byte) (int, error) {
+	for len(this.buf) == 0 {
+		word, err := this.Next()
+		if err != nil {
+			return 0, io.EOF
+		}
+		this.buf = append(this.buf[:0], word...)
+		this.buf = append(this.buf, '\n')
+	}
+	n := copy(buf, this.buf)
+	this.buf = this.buf[n:]
+	return n, nil
+}
+
+// Returns the cause of the end of the stream: ErrExhausted or
+// ErrBudgetExceeded. Returns nil if the stream hasn't ended.
+func (this *Reader) Err() error {
+	return this.err
+}
+
+/********************************** Statics **********************************/
+
+// Creates a reader that streams the words of the given generator function. If
+// `budget` is positive, the stream ends after that many words.
+func NewReader(gen func() string, budget int) *Reader {
+	return &Reader{gen: gen, budget: budget}
+}
@@ -0,0 +1,42 @@
+package codex
+
+// Recording and replay of generation sessions.
+
+import (
+	"math/rand"
+)
+
+/*********************************** Type ************************************/
+
+// Recording is a compact log of a generation session, sufficient to reproduce
+// its output exactly. Every random draw of a recorded generator comes from a
+// source seeded with the recorded seed, and every other decision is made in a
+// fixed order, so a generator replaying the recording makes the same draws and
+// decisions, and returns the same words in the same order. Recordings can be
+// stored as JSON.
+type Recording struct {
+	// Seed of the session's random source.
+	Seed int64
+}
+
+/********************************** Methods **********************************/
+
+// Same as Traits.Generator(), but also returns a recording of the session that
+// can be passed to Traits.ReplayGenerator(). The seed is drawn from the global
+// random source, so the caller doesn't need to manage seeds.
+func (this *Traits) RecordGenerator() (func() string, Recording) {
+	rec := Recording{Seed: rand.Int63()}
+	return this.ReplayGenerator(rec), rec
+}
+
+// Creates a generator that returns the same words, in the same order, as the
+// generator from the recorded session. This only holds for traits equal to the
+// traits of the recorded session.
+func (this *Traits) ReplayGenerator(rec Recording) func() string {
+	st := &state{
+		traits:  this,
+		rnd:     rand.New(rand.NewSource(rec.Seed)),
+		ordered: true,
+	}
+	return this.cased(st.next)
+}
@@ -0,0 +1,90 @@
+package codex
+
+// Selection of generated words by an external scoring model.
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+/*********************************** Type ************************************/
+
+// Reranker scores a batch of candidate words, returning one score per word;
+// higher is better. It's meant to wrap an external model, such as a neural
+// network that rates how natural a word looks. Returning an error aborts the
+// selection.
+type Reranker func(words []string) ([]float64, error)
+
+/********************************** Methods **********************************/
+
+// Generates `pool` random words, scores them with `rerank`, and returns the `n`
+// best ones, best first. Words are passed to `rerank` in batches of at most 64.
+// If `pool` is less than `n`, it defaults to 8 times `n`. If the traits' word
+// set is smaller than the pool, all of its words are scored. Returns an error
+// if `rerank` fails or returns a wrong number of scores.
+func (this *Traits) Rerank(n, pool int, rerank Reranker) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if rerank == nil {
+		return nil, errors.New("can't rerank with nil reranker")
+	}
+	if pool < n {
+		pool = n * rerankFactor
+	}
+
+	gen := this.Generator()
+	words := make([]string, 0, pool)
+	for len(words) < pool {
+		word := gen()
+		if word == "" {
+			break
+		}
+		words = append(words, word)
+	}
+
+	scores := make([]float64, 0, len(words))
+	for start := 0; start < len(words); start += rerankBatch {
+		end := start + rerankBatch
+		if end > len(words) {
+			end = len(words)
+		}
+		batch, err := rerank(words[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, errors.New("reranker returned " + strconv.Itoa(len(batch)) +
+				" scores for " + strconv.Itoa(end-start) + " words")
+		}
+		scores = append(scores, batch...)
+	}
+
+	sort.Stable(byScore{words, scores})
+	if len(words) > n {
+		words = words[:n]
+	}
+	return words, nil
+}
+
+/********************************** Statics **********************************/
+
+// Default ratio of generated candidates to selected words in Traits.Rerank().
+const rerankFactor = 8
+
+// Maximum number of words passed to a Reranker in one call.
+const rerankBatch = 64
+
+// Sorts words by descending scores.
+type byScore struct {
+	words  []string
+	scores []float64
+}
+
+func (this byScore) Len() int           { return len(this.words) }
+func (this byScore) Less(i, j int) bool { return this.scores[i] > this.scores[j] }
+func (this byScore) Swap(i, j int) {
+	this.words[i], this.words[j] = this.words[j], this.words[i]
+	this.scores[i], this.scores[j] = this.scores[j], this.scores[i]
+}
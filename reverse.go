@@ -0,0 +1,64 @@
+package codex
+
+// Reversed traits, which derive the words of the original traits backwards.
+
+/********************************** Methods **********************************/
+
+// Returns traits whose words are the words of these traits with their sounds
+// in reverse order: every pair of sounds is flipped, and so are higher-order
+// sequences and the positions of pairs, while the limits stay the same. Counts
+// of first sounds become counts of last sounds, and vice versa. Words
+// of two or more sounds correspond one to one. Generating from the reversed
+// traits builds words from the end backwards, which makes it efficient to
+// constrain their endings; Traits.GeneratorTo() relies on this. The traits are
+// not modified.
+func (this *Traits) Reverse() *Traits {
+	out := this.Clone()
+
+	out.PairSet = reversePairs(this.PairSet)
+	if this.PairCount != nil {
+		out.PairCount = make(map[[2]string]int, len(this.PairCount))
+		for pair, count := range this.PairCount {
+			out.PairCount[[2]string{pair[1], pair[0]}] = count
+		}
+	}
+
+	out.BoundaryCount = reverseBoundaries(this.BoundaryCount)
+
+	if this.GramSet != nil {
+		out.GramSet = make(Set, len(this.GramSet))
+		for gram := range this.GramSet {
+			out.GramSet.Add(gramKey(reverseSounds(gramSounds(gram))))
+		}
+	}
+
+	out.StartSet = reversePairs(this.EndSet)
+	out.MidSet = reversePairs(this.MidSet)
+	out.EndSet = reversePairs(this.StartSet)
+	out.Forbidden = reversePairs(this.Forbidden)
+	return out
+}
+
+/*********************************** Utils ***********************************/
+
+// Returns a new pair set with every pair of the given set flipped. A nil set
+// produces a nil set.
+func reversePairs(set PairSet) PairSet {
+	if set == nil {
+		return nil
+	}
+	out := make(PairSet, len(set))
+	for pair := range set {
+		out.Add([2]string{pair[1], pair[0]})
+	}
+	return out
+}
+
+// Returns a new slice with the given sounds in reverse order.
+func reverseSounds(sounds []string) []string {
+	out := make([]string, len(sounds))
+	for index, sound := range sounds {
+		out[len(sounds)-1-index] = sound
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package codex
+
+// Orthography rules that rewrite the spelling of generated words.
+
+import (
+	"regexp"
+)
+
+/*********************************** Type ************************************/
+
+// Rule rewrites every match of a pattern in a generated word. Rules fix
+// spellings that readers reject, which pair recombination regularly produces,
+// like "q" without "u" or a word-final "v" in English.
+type Rule struct {
+	// Pattern to rewrite, in the syntax of package regexp.
+	Pattern *regexp.Regexp
+	// Replacement of each match, which may refer to submatches, like "${1}",
+	// per Regexp.ReplaceAllString().
+	Replace string
+}
+
+// Rules for common English spelling:
+//   1) "q" is always followed by "u";
+//   2) words don't end with "v", which becomes "ve";
+//   3) "ck" only follows single vowels, and becomes "k" elsewhere.
+var englishRules = []Rule{
+	{regexp.MustCompile(`qu?`), "qu"},
+	{regexp.MustCompile(`v$`), "ve"},
+	{regexp.MustCompile(`(^|[^aeiouy]|[aeiouy]{2})ck`), "${1}k"},
+}
+
+/********************************** Statics **********************************/
+
+// Returns a copy of the built-in rules for common English spelling. Usage:
+//
+//	traits.Rules = EnglishRules()
+func EnglishRules() []Rule {
+	return copyRules(englishRules)
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Applies the traits' rules to the given word, in order.
+func (this *Traits) rewrite(word string) string {
+	if word == "" {
+		return word
+	}
+	for _, rule := range this.Rules {
+		word = rule.Pattern.ReplaceAllString(word, rule.Replace)
+	}
+	return word
+}
+
+// Returns a copy of the given rules. Nil rules produce nil rules.
+func copyRules(rules []Rule) []Rule {
+	if rules == nil {
+		return nil
+	}
+	return append([]Rule{}, rules...)
+}
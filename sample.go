@@ -0,0 +1,37 @@
+package codex
+
+// Uniform sampling of the whole word set.
+
+/********************************** Methods **********************************/
+
+// Returns `n` words chosen uniformly at random from the traits' word set.
+// Unlike Traits.WordsN(), whose generator favours some branches of the virtual
+// tree, every subset of `n` words is equally likely. This enumerates the whole
+// set once, using reservoir sampling, so it takes about as long as exhausting
+// a generator, but only holds `n` words in memory. If the set has fewer words,
+// returns all of them along with a *CountError.
+func (this *Traits) Sample(n int) (Set, error) {
+	if n <= 0 {
+		return Set{}, nil
+	}
+
+	rnd := newRand()
+	gen := this.SortedGenerator()
+	// Grown as needed, since `n` may far exceed the size of the word set.
+	var reservoir []string
+	seen := 0
+	for word := gen(); word != ""; word = gen() {
+		seen++
+		if len(reservoir) < n {
+			reservoir = append(reservoir, word)
+		} else if index := randIntn(rnd, seen); index < n {
+			reservoir[index] = word
+		}
+	}
+
+	words := Set.New(nil, reservoir...)
+	if len(words) < n {
+		return words, &CountError{Requested: n, Available: len(words)}
+	}
+	return words, nil
+}
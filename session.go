@@ -0,0 +1,51 @@
+package codex
+
+// Stateful source of words that never repeats a word across calls.
+
+/*********************************** Type ************************************/
+
+// Session derives words from traits and remembers every word it has returned,
+// so words never repeat across calls, until Session.Reset(). Unlike
+// Traits.WordsN(), which starts over on each call, repeated Session.WordsN()
+// calls return disjoint sets. Like generators, a session is not safe for
+// concurrent use. Use NewSession() to create one.
+type Session struct {
+	traits *Traits
+	gen    func() string
+}
+
+/********************************** Methods **********************************/
+
+// Returns the next word that the session hasn't returned yet, or "" when all
+// words have been returned.
+func (this *Session) Next() string {
+	return this.gen()
+}
+
+// Returns `n` random words that the session hasn't returned yet. If fewer
+// words remain, returns all of them along with a *CountError stating how many
+// there are, like Traits.WordsN().
+func (this *Session) WordsN(n int) (Set, error) {
+	words := Set{}
+	for len(words) < n {
+		word := this.gen()
+		if word == "" {
+			return words, &CountError{Requested: n, Available: len(words)}
+		}
+		words.Add(word)
+	}
+	return words, nil
+}
+
+// Forgets the returned words, so the session may return any word again.
+func (this *Session) Reset() {
+	this.gen = this.traits.Generator()
+}
+
+/********************************** Statics **********************************/
+
+// Creates a session that derives words from the given traits. The traits must
+// not be modified while the session is in use, except before Session.Reset().
+func NewSession(traits *Traits) *Session {
+	return &Session{traits: traits, gen: traits.Generator()}
+}
@@ -0,0 +1,87 @@
+package codex
+
+// Destinations for enumerations of words too big to hold in memory.
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+/*********************************** Type ************************************/
+
+// WordSink receives the words of an enumeration, one at a time, as passed to
+// Traits.WordsTo(). FileSink is the provided implementation.
+type WordSink interface {
+	// Receives the next word. An error stops the enumeration.
+	WriteWord(word string) error
+}
+
+// FileSink is a WordSink that writes words to a file, one per line, through a
+// buffer, optionally compressed with gzip. Use NewFileSink() to create one,
+// and call FileSink.Close() when done to flush the buffer and close the file.
+type FileSink struct {
+	file *os.File
+	zip  *gzip.Writer
+	buf  *bufio.Writer
+}
+
+/********************************** Methods **********************************/
+
+// Passes every word of the traits to the given sink, in the sorted order of
+// Traits.SortedGenerator(), without holding the words in memory: unlike a
+// random generator, which remembers the visited part of the word tree, the
+// traversal only keeps the path to the current word. Use it with a FileSink
+// to enumerate word sets that don't fit in memory and process them offline.
+// Returns the first error of the sink.
+func (this *Traits) WordsTo(sink WordSink) error {
+	stack := this.sortedStack()
+	for sounds := this.sortedNext(&stack); sounds != nil; sounds = this.sortedNext(&stack) {
+		if err := sink.WriteWord(this.styled(join(sounds, ""))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Implements WordSink, writing the word followed by a newline.
+func (this *FileSink) WriteWord(word string) error {
+	_, err := this.buf.WriteString(word + "\n")
+	return err
+}
+
+// Flushes the buffered words and closes the file. Returns the first error of
+// flushing, compressing or closing. The file is closed even if flushing fails.
+func (this *FileSink) Close() error {
+	err := this.buf.Flush()
+	if this.zip != nil {
+		if zipErr := this.zip.Close(); err == nil {
+			err = zipErr
+		}
+	}
+	if closeErr := this.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+/********************************** Statics **********************************/
+
+// Creates a file sink that writes to the file at the given path, creating or
+// truncating it. When `compress` is true, the file is compressed with gzip, and
+// may be read with gzip.NewReader() or zcat.
+func NewFileSink(path string, compress bool) (*FileSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	sink := &FileSink{file: file}
+	var writer io.Writer = file
+	if compress {
+		sink.zip = gzip.NewWriter(file)
+		writer = sink.zip
+	}
+	sink.buf = bufio.NewWriter(writer)
+	return sink, nil
+}
@@ -0,0 +1,102 @@
+package codex
+
+// Transitions between sounds that don't occur in the sample.
+
+import (
+	"sort"
+)
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Creates shallow child nodes for the given path, like sprout(), adding every
+// sound of the traits after the first sound when the traits allow unseen
+// pairs, and omitting sounds that form forbidden pairs. An empty path starts
+// from the StartToken, per Traits.transitions().
+func (this *Traits) successors(path []string) map[string]*tree {
+	if len(path) == 0 {
+		nodes := map[string]*tree{}
+		for pair := range this.PairSet {
+			if this.validStart(pair[:]) {
+				nodes[pair[0]] = nil
+			}
+		}
+		return nodes
+	}
+	return this.extendSuccessors(sprout(this.PairSet, path...), path)
+}
+
+// Same as Traits.successors(), looking up the pairs in the given table of
+// Traits.transitions() instead of scanning them, unless the table is nil.
+func (this *Traits) successorsIn(table *soundTable, path []string) map[string]*tree {
+	if table == nil {
+		return this.successors(path)
+	}
+	return this.extendSuccessors(table.sprout(path...), path)
+}
+
+// Adds unseen and omits forbidden successors of the given path to the given
+// nodes, which were sprouted from the PairSet.
+func (this *Traits) extendSuccessors(nodes map[string]*tree, path []string) map[string]*tree {
+	if this.MaxUnseen > 0 && len(path) > 0 {
+		for sound := range this.SoundSet {
+			nodes[sound] = nil
+		}
+	}
+	if len(this.Forbidden) > 0 && len(path) > 0 {
+		last := path[len(path)-1]
+		for sound := range nodes {
+			if this.Forbidden.Has([2]string{last, sound}) {
+				delete(nodes, sound)
+			}
+		}
+	}
+	return nodes
+}
+
+// Checks if the given sequence of sounds uses no more unseen pairs than the
+// traits allow, looking up pairs per Traits.hasPair(). Without unseen pairs,
+// traversal only ever visits pairs of the PairSet, so there's nothing to
+// count.
+func (this *Traits) validUnseen(table *soundTable, sounds []string) bool {
+	return this.MaxUnseen <= 0 || this.countUnseen(table, sounds) <= this.MaxUnseen
+}
+
+// Counts the distinct pairs of the given sequence of sounds that don't occur in
+// the PairSet, looking up pairs per Traits.hasPair().
+func (this *Traits) countUnseen(table *soundTable, sounds []string) (count int) {
+	for index := 1; index < len(sounds); index++ {
+		prev, current := sounds[index-1], sounds[index]
+		if !this.hasPair(table, prev, current) && countPair(sounds[:index], prev, current) == 0 {
+			count++
+		}
+	}
+	return
+}
+
+// Checks if the given pair of sounds occurs in the given table of
+// Traits.transitions(), or in the PairSet if the table is nil.
+func (this *Traits) hasPair(table *soundTable, sound, other string) bool {
+	if table == nil {
+		return this.PairSet.Has([2]string{sound, other})
+	}
+	return table.has(sound, other)
+}
+
+// Returns the weight of the given pair of sounds for weighted generators: its
+// weight per Traits.pairWeight(), or the Smoothing for an unseen pair, looking
+// up pairs per Traits.hasPair().
+func (this *Traits) smoothWeight(table *soundTable, pair [2]string) float64 {
+	if !this.hasPair(table, pair[0], pair[1]) {
+		return this.Smoothing
+	}
+	return float64(this.pairWeight(pair))
+}
+
+// Moves the sounds that form unseen pairs with the given last sound after the
+// others, keeping the order within each group. Looks up pairs per
+// Traits.hasPair().
+func (this *Traits) sortUnseen(table *soundTable, last string, sounds []string) {
+	sort.SliceStable(sounds, func(i, j int) bool {
+		return this.hasPair(table, last, sounds[i]) && !this.hasPair(table, last, sounds[j])
+	})
+}
@@ -0,0 +1,155 @@
+package codex
+
+// Enumeration of derived words in order, and mapping between words and their
+// positions in that order.
+
+import (
+	"errors"
+	"strings"
+)
+
+/*********************************** Type ************************************/
+
+// Position of an ordered traversal of the virtual tree: the path to a node,
+// the node's sorted children, and the index of the next child to descend into.
+type sortedFrame struct {
+	path   []string
+	sounds []string
+	index  int
+}
+
+/********************************** Methods **********************************/
+
+// Creates a generator function that returns the traits' words in lexicographic
+// order, one per call. Words are ordered by their sequences of sounds, each
+// word preceding the words it's a prefix of. This matches string order unless
+// the known sounds include multi-letter sounds that share a first letter with
+// other sounds: for example, with sounds "c" and "ch", every word that begins
+// with "c" followed by another sound precedes every word that begins with
+// "ch". The traversal is depth-first and doesn't hold the word set in memory.
+// When the set is exhausted, further calls return "".
+func (this *Traits) SortedGenerator() func() string {
+	stack := this.sortedStack()
+	return this.cased(func() string {
+		return join(this.sortedNext(&stack), "")
+	})
+}
+
+// Returns the word at the given zero-based index in the order of
+// Traits.SortedGenerator(). Together with Traits.IndexOf(), this lets callers
+// address words by number, for example to split the word set into ranges
+// generated on different machines. Only the subtrees of the virtual tree
+// skipped on the way to the word are counted, but in the worst case, that's
+// about as costly as enumerating every word. Returns an error if the index is
+// out of range.
+func (this *Traits) At(index uint64) (string, error) {
+	var path []string
+	for {
+		if this.isWord(path) {
+			if index == 0 {
+				return this.styled(join(path, "")), nil
+			}
+			index--
+		}
+
+		var next []string
+		for _, sound := range this.children(path) {
+			child := append(path[:len(path):len(path)], sound)
+			count := this.countFrom(child)
+			if index < count {
+				next = child
+				break
+			}
+			index -= count
+		}
+		if next == nil {
+			return "", errors.New("index out of range")
+		}
+		path = next
+	}
+}
+
+// Returns the zero-based index of the given word in the order of
+// Traits.SortedGenerator(); the reverse of Traits.At(). The word may have any
+// casing. Returns an error if the word isn't derived from the traits.
+func (this *Traits) IndexOf(word string) (uint64, error) {
+	sounds, err := getSounds(strings.ToLower(word), this.knownSounds())
+	if err != nil {
+		return 0, err
+	}
+	if !this.isWord(sounds) || !this.derivable(sounds) {
+		return 0, errors.New("the word is not derived from the traits")
+	}
+
+	var index uint64
+	for depth, sound := range sounds {
+		path := sounds[:depth]
+		if this.isWord(path) {
+			index++
+		}
+		for _, sibling := range this.children(path) {
+			if sibling == sound {
+				break
+			}
+			index += this.countFrom(append(path[:len(path):len(path)], sibling))
+		}
+	}
+	return index, nil
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Returns the initial stack of an ordered traversal of the virtual tree.
+func (this *Traits) sortedStack() []sortedFrame {
+	return []sortedFrame{{sounds: this.children(nil)}}
+}
+
+// Advances the given ordered traversal to the next word, returning its sounds,
+// or nil when the traversal is over.
+func (this *Traits) sortedNext(stack *[]sortedFrame) []string {
+	for len(*stack) > 0 {
+		frame := &(*stack)[len(*stack)-1]
+		if frame.index >= len(frame.sounds) {
+			*stack = (*stack)[:len(*stack)-1]
+			continue
+		}
+
+		path := append(frame.path[:len(frame.path):len(frame.path)], frame.sounds[frame.index])
+		frame.index++
+		*stack = append(*stack, sortedFrame{path: path, sounds: this.children(path)})
+
+		if this.isWord(path) {
+			return path
+		}
+	}
+	return nil
+}
+
+/********************************** Statics **********************************/
+
+// Maps the given seed to one of the words derived from the traits. The same
+// seed and traits always produce the same word, on every platform and in every
+// version of Go, since the mapping doesn't depend on any random source or map
+// iteration order. Seeds are scrambled before use, so consecutive seeds produce
+// unrelated words. Each call counts every word, which takes about as long as
+// exhausting a generator. Returns "" if the traits' word set is empty.
+func DeriveWord(traits *Traits, seed uint64) string {
+	count := traits.countFrom(nil)
+	if count == 0 {
+		return ""
+	}
+	word, _ := traits.At(mix64(seed) % count)
+	return word
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Scrambles the bits of the given number, using the finaliser of SplitMix64.
+func mix64(value uint64) uint64 {
+	value ^= value >> 30
+	value *= 0xbf58476d1ce4e5b9
+	value ^= value >> 27
+	value *= 0x94d049bb133111eb
+	value ^= value >> 31
+	return value
+}
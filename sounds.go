@@ -19,3 +19,70 @@ var knownVowels = Set.New(nil,
 	// ISO basic Latin monographs
 	"a", "e", "i", "o", "u", "y",
 )
+
+// Cyrillic glyphs and digraphs covering Russian, Ukrainian, Belarusian and
+// Bulgarian.
+var cyrillicSounds = Set.New(nil,
+	// Digraphs
+	"дж", "дз", "тс",
+	// Letters
+	"а", "б", "в", "г", "ґ", "д", "е", "ё", "є", "ж", "з", "и", "і", "ї", "й",
+	"к", "л", "м", "н", "о", "п", "р", "с", "т", "у", "ў", "ф", "х", "ц", "ч",
+	"ш", "щ", "ъ", "ы", "ь", "э", "ю", "я",
+)
+
+// Cyrillic vowel glyphs, including iotated ones.
+var cyrillicVowels = Set.New(nil,
+	"а", "е", "ё", "є", "и", "і", "ї", "о", "у", "ы", "э", "ю", "я",
+)
+
+// Returns a copy of the default set of known sounds, used by traits without
+// KnownSounds. Useful as a base for a custom set.
+func DefaultSounds() Set {
+	return copySet(knownSounds)
+}
+
+// Returns a copy of the default set of known vowels, used by traits without
+// KnownVowels. Useful as a base for a custom set.
+func DefaultVowels() Set {
+	return copySet(knownVowels)
+}
+
+// Returns a copy of the built-in set of Cyrillic sounds, for samples in
+// Russian, Ukrainian, Belarusian or Bulgarian. Includes the digraphs "дж", "дз"
+// and "тс". Usage:
+//
+//	traits := &Traits{KnownSounds: CyrillicSounds(), KnownVowels: CyrillicVowels()}
+func CyrillicSounds() Set {
+	return copySet(cyrillicSounds)
+}
+
+// Returns a copy of the built-in set of Cyrillic vowels. See CyrillicSounds().
+func CyrillicVowels() Set {
+	return copySet(cyrillicVowels)
+}
+
+// Creates a set of sounds from the letters of the given string, one sound per
+// letter. Combining marks stay with the letters they follow. Usage:
+//
+//	traits := &Traits{KnownVowels: Letters("aeiouyäöü")}
+func Letters(letters string) Set {
+	set := Set{}
+	offsets := letterOffsets(letters)
+	for index := 0; index+1 < len(offsets); index++ {
+		set.Add(letters[offsets[index]:offsets[index+1]])
+	}
+	return set
+}
+
+// Returns a copy of the given set. A nil set produces a nil set.
+func copySet(set Set) Set {
+	if set == nil {
+		return nil
+	}
+	out := make(Set, len(set))
+	for key := range set {
+		out.Add(key)
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package codex
+
+// Distribution of one stream of unique words among several consumers.
+
+import (
+	"sync"
+)
+
+/********************************** Statics **********************************/
+
+// Splits the given generator function into `n` generator functions that share
+// its words: each word of the original goes to exactly one of them, so the
+// words stay unique across all of them. Each generator may be used by its own
+// goroutine; the original is only called under a lock. To reduce contention,
+// each generator takes up to `buffer` words from the original at once and
+// keeps them to itself, so one consumer may hold words that another would
+// otherwise get. When the original is exhausted and a generator's buffer is
+// empty, further calls to it return "".
+func Split(gen func() string, n, buffer int) []func() string {
+	if buffer < 1 {
+		buffer = 1
+	}
+	var lock sync.Mutex
+
+	// Takes up to `buffer` words from the original generator.
+	take := func(words []string) []string {
+		lock.Lock()
+		defer lock.Unlock()
+		for len(words) < buffer {
+			word := gen()
+			if word == "" {
+				break
+			}
+			words = append(words, word)
+		}
+		return words
+	}
+
+	gens := make([]func() string, n)
+	for index := range gens {
+		var words []string
+		gens[index] = func() string {
+			if len(words) == 0 {
+				words = take(words[:0])
+				if len(words) == 0 {
+					return ""
+				}
+			}
+			word := words[0]
+			words = words[1:]
+			return word
+		}
+	}
+	return gens
+}
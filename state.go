@@ -3,6 +3,10 @@ package codex
 // Type that encapsulates word traits and maintains an internal state that is
 // mutated by, and affects, its tree traversal methods.
 
+import (
+	"math/rand"
+)
+
 /*********************************** Type ************************************/
 
 // A state object encapsulates word traits and maintains an internal state that
@@ -17,10 +21,52 @@ type state struct {
 	// Tree that reflects the visited parts of the virtual tree defined by the
 	// state's traits. It's built by state.walk() calls.
 	tree *tree
+
+	// Interned transitions of the state's traits, including those of the
+	// StartToken and the EndToken, built by the first state.walk() call. Nil
+	// for compact states.
+	table *soundTable
+
+	// If true, the state doesn't intern the transitions of its traits, and
+	// looks them up in the traits instead, which saves memory at the cost of
+	// speed. See LowMemoryProfile.
+	compact bool
+
+	// Optional sequence of sounds that every visited path must begin with.
+	// When empty, traversal starts at the root.
+	prefix []string
+
+	// Optional sequence of sounds that every yielded word must end with.
+	suffix []string
+
+	// If true, the traits are reversed, and yielded words are joined from their
+	// sounds in reverse order.
+	reversed bool
+
+	// Optional source of randomness. When nil, the global source is used.
+	rnd *rand.Rand
+
+	// If true, traversal order depends only on the source of randomness, which
+	// makes the output reproducible. Costs some speed.
+	ordered bool
 }
 
 /********************************** Methods **********************************/
 
+// Returns the next random word that hasn't been visited yet, or "" if the
+// state's virtual tree is exhausted.
+func (this *state) next() string {
+	var out string
+	this.walkRandom(func(sounds ...string) bool {
+		if this.reversed {
+			sounds = reverseSounds(sounds)
+		}
+		out = join(sounds, "")
+		return false
+	})
+	return out
+}
+
 // Walks the virtual tree of the state's traits, caching the visited parts in
 // the state's inner tree. This caching lets us skip repeated Traits.validPart()
 // checks, individual visited nodes, and fully visited subtrees. This
@@ -31,16 +77,24 @@ func (this *state) walk(iterator func(...string) bool, sounds ...string) bool {
 	if this.tree == nil {
 		this.tree = new(tree)
 	}
+	if this.table == nil && !this.compact {
+		this.table = newSoundTable(this.traits.transitions())
+	}
+	return this.walkNode(this.tree.at(sounds...), iterator, sounds)
+}
 
-	// Find or create a matching node for this path. If it doesn't have child
-	// nodes yet, make a shallow map to track valid paths.
-	node := this.tree.at(sounds...)
+// Same as state.walk(), starting at the given node of the inner tree, which
+// must match the given path. Descending node by node avoids looking up each
+// path from the root.
+func (this *state) walkNode(node *tree, iterator func(...string) bool, sounds []string) bool {
+	// If the node doesn't have child nodes yet, make a shallow map to track
+	// valid paths.
 	if node.nodes == nil {
-		node.nodes = sprout(this.traits.PairSet, sounds...)
+		node.nodes = this.traits.successorsIn(this.table, sounds)
 	}
 
 	// Loop over remaining child nodes and investigate their subtrees.
-	for _, sound := range randNodeValues(node.nodes) {
+	for _, sound := range this.childOrder(node, sounds) {
 		// Appending to sounds mutates their underlying array unless their cap was
 		// <= 2 or so. If the iterator was expected to store sound slices, we would
 		// allocate a new array for each path to avoid unexpected mutations. Right
@@ -48,18 +102,19 @@ func (this *state) walk(iterator func(...string) bool, sounds ...string) bool {
 		// is not exposed publicly and our own iterators don't store slices.
 		path := append(sounds, sound)
 		// Invalidate the path if it doesn't qualify as a partial word.
-		if !this.traits.validPart(path...) {
+		if !this.traits.validPartIn(this.table, path) {
 			delete(node.nodes, sound)
 			continue
 		}
+		child := node.at(sound)
 		// (1)(2) -> pre-order, (2)(1) -> post-order. Post-order is required by
 		// state.walkRandom().
 		// (2) Continue recursively.
-		if !this.walk(iterator, path...) {
+		if !this.walkNode(child, iterator, path) {
 			return false
 		}
 		// (1) If this path hasn't yet been visited, feed it to the iterator.
-		if !node.at(sound).visited {
+		if !child.visited {
 			if !iterator(path...) {
 				return false
 			}
@@ -77,16 +132,23 @@ func (this *state) walk(iterator func(...string) bool, sounds ...string) bool {
 // post-order. We only visit paths that qualify as valid complete words and
 // haven't been visited before.
 func (this *state) walkRandom(iterator func(...string) bool) bool {
-	return this.walk(func(sounds ...string) bool {
-		for _, index := range permutate(len(sounds)) {
-			if index < 1 {
+	// Paths shorter than the prefix belong to other subtrees, and paths shorter
+	// than the minimum aren't words.
+	minIndex := this.traits.minWordSounds() - 1
+	if len(this.prefix)-1 > minIndex {
+		minIndex = len(this.prefix) - 1
+	}
+
+	done := this.walk(func(sounds ...string) bool {
+		for _, index := range permutate(this.rnd, len(sounds)) {
+			if index < minIndex {
 				continue
 			}
 			path := sounds[:index+1]
 			node := this.tree.at(path...)
 			if !node.visited {
 				node.visited = true
-				if this.traits.checkPart(path...) {
+				if this.complete(path) {
 					if !iterator(path...) {
 						return false
 					}
@@ -94,5 +156,66 @@ func (this *state) walkRandom(iterator func(...string) bool) bool {
 			}
 		}
 		return true
-	})
+	}, this.prefix...)
+	if !done {
+		return false
+	}
+
+	// The walker never feeds the prefix itself to the iterator, so if it has no
+	// descendants, it hasn't been visited yet.
+	if len(this.prefix) > 0 {
+		node := this.tree.at(this.prefix...)
+		if !node.visited {
+			node.visited = true
+			if this.complete(this.prefix) {
+				return iterator(this.prefix...)
+			}
+		}
+	}
+	return true
+}
+
+// Takes a valid partial word and checks if it's also a complete word that ends
+// with the state's suffix, if any. The last sound must have a transition to
+// the EndToken. Called after state.walk(), which builds the table, if any.
+func (this *state) complete(sounds []string) bool {
+	if len(sounds) == 0 || len(sounds) < len(this.suffix) {
+		return false
+	}
+	if !this.traits.validLast(this.table, sounds) {
+		return false
+	}
+	tail := sounds[len(sounds)-len(this.suffix):]
+	for index := range tail {
+		if tail[index] != this.suffix[index] {
+			return false
+		}
+	}
+	return this.traits.checkPart(sounds...)
+}
+
+// Returns the remaining child sounds of the given node, at the given path, in
+// random order. For weighted traits, sounds of frequent pairs tend to come
+// first, and so do sounds that frequently begin words. Sounds of unseen pairs
+// come last, unless the traits are weighted and smoothed, in which case
+// they're weighted like the other pairs.
+func (this *state) childOrder(node *tree, sounds []string) []string {
+	values := randNodeValues(this.rnd, node.nodes, this.ordered)
+	if len(sounds) == 0 {
+		if this.traits.Weighted {
+			weightedShuffle(this.rnd, values, func(sound string) float64 {
+				return float64(this.traits.startWeight(sound))
+			})
+		}
+		return values
+	}
+	last := sounds[len(sounds)-1]
+	if this.traits.Weighted {
+		weightedShuffle(this.rnd, values, func(sound string) float64 {
+			return this.traits.smoothWeight(this.table, [2]string{last, sound})
+		})
+	} else if this.traits.MaxUnseen > 0 {
+		this.traits.sortUnseen(this.table, last, values)
+	}
+	return values
 }
@@ -0,0 +1,73 @@
+package codex
+
+// Statistics that help judge whether a sample is rich enough.
+
+import (
+	"math"
+)
+
+/*********************************** Type ************************************/
+
+// Stats summarises the richness of a traits object.
+type Stats struct {
+	// Number of sounds and pairs of sounds.
+	NSounds int
+	NPairs  int
+	// Average number of sounds that may follow a sound.
+	Branching float64
+	// Average uncertainty of the next sound, in bits, with transitions weighted
+	// like in Traits.Matrix(). 0 means that each sound has one successor; each
+	// additional bit doubles the effective number of choices.
+	Entropy float64
+	// Estimated number of derived words. See Traits.EstimateCount().
+	NWords float64
+}
+
+/********************************** Methods **********************************/
+
+// Returns statistics of the traits. The number of derived words is estimated
+// by Traits.EstimateCount(), so it varies between calls.
+func (this *Traits) Stats() Stats {
+	matrix := this.Matrix()
+	stats := Stats{
+		NSounds: len(this.SoundSet),
+		NPairs:  len(this.PairSet),
+		NWords:  this.EstimateCount(),
+	}
+
+	// Only transitions between sounds count, not the start and end of words.
+	var total float64
+	var nRows int
+	for sound, successors := range matrix.Transitions {
+		if sound == StartToken {
+			continue
+		}
+
+		var weight, branching int
+		for successor, count := range successors {
+			if successor != EndToken {
+				branching++
+				weight += count
+			}
+		}
+		if branching == 0 {
+			continue
+		}
+		nRows++
+		stats.Branching += float64(branching)
+
+		for successor, count := range successors {
+			if successor != EndToken {
+				chance := float64(count) / float64(weight)
+				stats.Entropy -= float64(weight) * chance * math.Log2(chance)
+			}
+		}
+		total += float64(weight)
+	}
+	if nRows > 0 {
+		stats.Branching /= float64(nRows)
+		stats.Entropy /= total
+	}
+
+	return stats
+}
@@ -0,0 +1,182 @@
+package codex
+
+// Incremental training: adding and removing sample words one at a time, without
+// reanalysing the whole sample or holding it in memory.
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+/********************************** Methods **********************************/
+
+// Checks if the given word can be examined as a sample word, without modifying
+// the traits. Returns nil if Traits.Examine() would accept it, or a *WordError
+// that tells why it would be rejected: for example, which symbol isn't a known
+// sound, and where it is. Interactive tools can use this to show users why
+// their examples are unusable.
+func (this *Traits) Explain(word string) error {
+	if this == nil {
+		return errors.New("can't examine with nil pointer")
+	}
+	_, err := this.sampleSounds(word, this.knownSounds())
+	return err
+}
+
+// Analyses the given word and merges its attributes into self, updating the
+// sounds, pairs, counts and limits. This is the same as calling Traits.Examine()
+// with a single word, and lets interactive tools add examples as the user
+// provides them without reanalysing the whole sample. If the word is invalid,
+// returns an error and leaves the traits unchanged.
+func (this *Traits) AddWord(word string) error {
+	return this.examineWord(word, 1, this.knownSounds())
+}
+
+// Analyses the given words, like Traits.Examine(), counting each word as many
+// times as its weight, such as its frequency in a corpus. This lets training on
+// real frequency lists emphasise common words: their pairs of sounds weigh more
+// in PairCount, and so in weighted generators and Traits.Score(), and their
+// lengths weigh more in LengthCount. The limits and sets are the same as when
+// examining each word once. Words are examined in sorted order, so the first
+// invalid word is reported consistently; the words before it stay examined.
+// Returns an error for weights less than 1.
+func (this *Traits) ExamineWeighted(words map[string]int) error {
+	if this == nil {
+		return errors.New("can't examine with nil pointer")
+	}
+	known := this.knownSounds()
+	for _, word := range sortedWeightKeys(words) {
+		if words[word] < 1 {
+			return wordError(word, "the weight must be positive")
+		}
+		if err := this.examineWord(word, words[word], known); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Analyses the words read from the given reader, one per line, like
+// Traits.Examine(). The words are streamed: each is examined as soon as it's
+// read, and only the learned counts are kept, so the sample may be a
+// dictionary of any size. Blank lines are skipped, and surrounding whitespace,
+// including "\r" of Windows line endings, is ignored. Returns the first error
+// of reading or examining; the words before it stay examined.
+func (this *Traits) ExamineReader(reader io.Reader) error {
+	if this == nil {
+		return errors.New("can't examine with nil pointer")
+	}
+	known := this.knownSounds()
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" {
+			continue
+		}
+		if err := this.examineWord(word, 1, known); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Subtracts the contribution of the given sample word from the traits: the
+// counts of each of its pairs of sounds and of its length are decremented, and
+// pairs that no longer occur in the sample are deleted, along with sounds and
+// higher-order sequences left without pairs. This relies on PairCount, which
+// Traits.Examine() fills, to tell which pairs other words still use. The
+// limits on sounds and vowels, the positions of pairs and vowel harmony aren't
+// counted, so they're kept as they are, save for those of deleted pairs and
+// sounds.
+//
+// Returns an error and leaves the traits unchanged if the word doesn't consist
+// of known sounds, or if its pairs aren't counted often enough for it to have
+// been examined.
+func (this *Traits) RemoveWord(word string) error {
+	sounds, err := getSounds(word, this.knownSounds())
+	if err != nil {
+		return err
+	}
+
+	counts := map[[2]string]int{}
+	for i := 0; i+1 < len(sounds); i++ {
+		counts[[2]string{sounds[i], sounds[i+1]}]++
+	}
+	if len(counts) == 0 {
+		return wordError(word, "less than two sounds found")
+	}
+	for pair, count := range counts {
+		if !this.PairSet.Has(pair) || this.PairCount[pair] < count {
+			return wordError(word, "the word is not in the examined sample")
+		}
+	}
+
+	for pair, count := range counts {
+		this.PairCount[pair] -= count
+		if this.PairCount[pair] == 0 {
+			this.PairSet.Del(pair)
+		}
+	}
+	if this.LengthCount[len(sounds)] > 0 {
+		this.LengthCount[len(sounds)]--
+		if this.LengthCount[len(sounds)] == 0 {
+			delete(this.LengthCount, len(sounds))
+		}
+	}
+	this.removeBoundaries(sounds)
+	this.delOrphans()
+	return nil
+}
+
+/********************************** Statics **********************************/
+
+// Shortcut to creating a traits object and calling its Traits.ExamineReader().
+// Use this instead of NewTraits() to learn from a word list too big to load
+// into memory, such as a dictionary file:
+//
+//	file, err := os.Open("words.txt")
+//	traits, err := NewTraitsFromReader(file)
+func NewTraitsFromReader(reader io.Reader) (*Traits, error) {
+	traits := new(Traits)
+	if err := traits.ExamineReader(reader); err != nil {
+		return nil, err
+	}
+	return traits, nil
+}
+
+/*********************************** Utils ***********************************/
+
+// Returns the words of the given weights, sorted.
+func sortedWeightKeys(weights map[string]int) []string {
+	words := make([]string, 0, len(weights))
+	for word := range weights {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// Returns the lengths of the given length counts, sorted.
+func sortedLengths(counts map[int]int) []int {
+	lengths := make([]int, 0, len(counts))
+	for length := range counts {
+		lengths = append(lengths, length)
+	}
+	sort.Ints(lengths)
+	return lengths
+}
+
+// Returns a copy of the given length counts. A nil map produces a nil map.
+func copyLengthCount(counts map[int]int) map[int]int {
+	if counts == nil {
+		return nil
+	}
+	out := make(map[int]int, len(counts))
+	for length, count := range counts {
+		out[length] = count
+	}
+	return out
+}
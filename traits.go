@@ -2,6 +2,9 @@ package codex
 
 import (
 	"errors"
+	"io"
+	"strconv"
+	"strings"
 )
 
 /**
@@ -19,25 +22,117 @@ import (
 // object unequivocally defines an unordered set of synthetic words that may be
 // derived from it (see the definitions below).
 type Traits struct {
-	// Minimum and maximum number of sounds.
+	// Minimum and maximum number of sounds. Derived words have at least two
+	// sounds, unless MinNSounds is explicitly set to 1.
 	MinNSounds int
 	MaxNSounds int
 	// Minimum and maximum number of vowels.
 	MinNVowels int
 	MaxNVowels int
-	// Maximum number of consequtive vowels.
-	MaxConseqVow int
-	// Maximum number of consequtive consonants.
+	// Maximum number of consequtive vowels and consonants: the longest vowel
+	// and consonant clusters in derived words. Learned from the sample, which
+	// only ever raises them; set them after analysis to tune plausibility.
+	MaxConseqVow  int
 	MaxConseqCons int
 	// Set of sounds that occur in the words.
 	SoundSet Set
 	// Set of pairs of sounds that occur in the words.
 	PairSet PairSet
+	// Number of times each pair of sounds occurs in the words. Pairs missing
+	// from it count as occurring once.
+	PairCount map[[2]string]int
+	// Number of sample words with each number of sounds: the distribution of
+	// word lengths in the sample, which Traits.Score() and Traits.TopK() take
+	// into account.
+	LengthCount map[int]int
+	// Number of times each sound begins and ends the words, keyed by its
+	// transition from the StartToken or to the EndToken, like {StartToken, "n"}
+	// and {"a", EndToken}. Sounds that may begin or end derived words, but are
+	// missing from it, count as doing so once.
+	BoundaryCount map[[2]string]int
+	// If true, generators favour frequent pairs of sounds: each next sound is
+	// more likely to be tried first in proportion to the count of its pair.
+	Weighted bool
+	// Maximum number of distinct pairs of sounds that don't occur in the sample,
+	// which a derived word may use. Defaults to 0, meaning none. Raising it
+	// trades strictness for variety, which helps with tiny samples. Unseen
+	// pairs join sounds of the SoundSet and obey all other limits, so they have
+	// no effect on positional traits or traits of Order 2 or more. Words still
+	// begin and end with sounds of seen pairs. Generators try unseen pairs after
+	// the seen ones.
+	MaxUnseen int
+	// Pseudo-count added to each pair of sounds, seen or unseen, in the
+	// transition model of Traits.Score() and Traits.TopK(). This is Laplace
+	// smoothing, for which 1 is the classic choice; 0 disables it, leaving
+	// unseen pairs impossible. Weighted generators also use it as the count of
+	// unseen pairs, instead of trying them last.
+	Smoothing float64
+	// Pairs of sounds that derived words never use, even if they occur in the
+	// sample or MaxUnseen allows them. See Traits.ForbidPair().
+	Forbidden PairSet
+
+	// Number of preceding sounds each sound is conditioned on. When 2 or more,
+	// Traits.Examine() records each sequence of Order + 1 sounds in GramSet,
+	// and derived words may only consist of such sequences. 0 and 1 both mean
+	// pairs only.
+	Order int
+	// Set of sequences of Order + 1 sounds that occur in the words, each
+	// encoded as sounds joined by ">".
+	GramSet Set
+	// If true, Traits.Examine() also records the shorter sequences, down to
+	// three sounds, and derived words back off to them: when the last Order
+	// sounds never continue in the sample, the next sound only has to follow
+	// fewer of them, down to a pair. This keeps higher orders from dead-ending
+	// with realistic samples.
+	Backoff bool
+
+	// If true, Traits.Examine() records which pairs of sounds occur at the
+	// start, in the middle and at the end of the words, and derived words only
+	// use pairs in those positions. This keeps words from starting with
+	// clusters that only ever end the sample words.
+	Positional bool
+	// Sets of pairs of sounds that begin, continue and end the words.
+	StartSet PairSet
+	MidSet   PairSet
+	EndSet   PairSet
+
+	// If true, Traits.Examine() records which vowels occur together in the
+	// words, and derived words only combine such vowels. This models vowel
+	// harmony, which keeps languages like Finnish from mixing front and back
+	// vowels in a word.
+	Harmony bool
+	// Set of pairs of different vowels that occur together in a word, each in
+	// sorted order.
+	HarmonySet PairSet
 
 	// Replacement sound set to use instead of the default `knownSounds`.
 	KnownSounds Set
-	// Replacement sound set to use instead of the default `knownVowels`.
+	// Replacement sound set to use instead of the default `knownVowels`. When
+	// KnownSounds is empty, these vowels are known sounds in addition to the
+	// defaults.
 	KnownVowels Set
+	// Optional glyphs, such as "'" and "-", that join the parts of a word, like
+	// in "o'brien" or "jean-luc". They're known sounds in addition to the
+	// others, but neither vowels nor consonants: they break up clusters, and
+	// derived words never begin or end with them.
+	Connectives Set
+
+	// Casing of generated words. Defaults to lowercase.
+	Casing Casing
+	// Optional spelling of sounds in generated words, such as phonemes from
+	// Traits.ExaminePhonemes() mapped to letters. Sounds missing from the map
+	// are spelled as they are.
+	Spelling map[string]string
+	// Optional rules that rewrite the spelling of generated words, applied in
+	// order after Spelling and before Casing. Rewritten words may repeat.
+	Rules []Rule
+
+	// Maximum length of sample words accepted by Traits.Examine(), in letters
+	// (characters along with their combining marks). Defaults to 32 when zero.
+	// Raise it explicitly for samples with long words, such as those of
+	// agglutinative languages, keeping in mind that longer words make the word
+	// set grow explosively.
+	MaxWordLen int
 }
 
 /**
@@ -78,8 +173,9 @@ func (this *Traits) Examine(words []string) error {
 	}
 
 	// Examine each word and merge traits.
+	known := this.knownSounds()
 	for _, word := range words {
-		if err := this.examineWord(word); err != nil {
+		if err := this.examineWord(word, 1, known); err != nil {
 			return err
 		}
 	}
@@ -91,42 +187,222 @@ func (this *Traits) Examine(words []string) error {
 // are guaranteed to never repeat and be randomly distributed in the traits'
 // word set. When the set is exhausted, further calls return "".
 func (this *Traits) Generator() func() string {
-	st := &state{traits: this}
-	return func() string {
-		var out string
-		st.walkRandom(func(sounds ...string) bool {
-			out = join(sounds, "")
-			return false
-		})
-		return out
+	st := &state{traits: this, rnd: newRand()}
+	return this.cased(st.next)
+}
+
+// Returns `n` random words from a new generator. If the traits' word set has
+// fewer words, returns all of them along with a *CountError stating how many
+// there are, so the caller either gets exactly `n` words or an error. Runs on
+// one goroutine; to collect every word on all cores, use Traits.Words().
+func (this *Traits) WordsN(n int) (Set, error) {
+	gen := this.Generator()
+	words := Set{}
+	for len(words) < n {
+		word := gen()
+		if word == "" {
+			return words, &CountError{Requested: n, Available: len(words)}
+		}
+		words.Add(word)
+	}
+	return words, nil
+}
+
+// Writes `n` random words from a new generator to the given writer, each
+// followed by the given separator, such as "\n". Unlike Traits.WordsN(), each
+// word is written as soon as it's found and isn't kept, so the number of words
+// is limited only by the word set; wrap the writer in a bufio.Writer when
+// writing many words to a file. If the word set has fewer words, writes all of
+// them and returns a *CountError. Returns the first error of writing.
+func (this *Traits) WriteWordsN(writer io.Writer, n int, sep string) error {
+	gen := this.Generator()
+	for count := 0; count < n; count++ {
+		word := gen()
+		if word == "" {
+			return &CountError{Requested: n, Available: count}
+		}
+		if _, err := io.WriteString(writer, word+sep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the set of sound pairs that begin at least one derived word. Any of
+// them may be passed to Traits.GeneratorFrom().
+func (this *Traits) Starts() PairSet {
+	starts := PairSet{}
+	for pair := range this.PairSet {
+		if this.validPart(pair[0], pair[1]) && this.hasWordFrom(pair[:]) {
+			starts.Add(pair)
+		}
+	}
+	return starts
+}
+
+// Same as Traits.Generator(), but only yields words that begin with the given
+// pair of sounds. If the pair is not among Traits.Starts(), the generator is
+// exhausted from the start.
+func (this *Traits) GeneratorFrom(start [2]string) func() string {
+	if !this.PairSet.Has(start) || !this.validPart(start[:]...) {
+		return func() string { return "" }
+	}
+	st := &state{traits: this, prefix: start[:], rnd: newRand()}
+	return this.cased(st.next)
+}
+
+// Returns the set of sound pairs that end at least one derived word. Any of
+// them may be passed to Traits.GeneratorTo().
+func (this *Traits) Ends() PairSet {
+	ends := PairSet{}
+	var reversed *Traits
+	for pair := range this.PairSet {
+		if !this.validEnd(pair) {
+			continue
+		}
+		// Words are searched backwards from the pair, like in
+		// Traits.GeneratorTo().
+		if reversed == nil {
+			reversed = this.Reverse()
+		}
+		if reversed.hasWordFrom([]string{pair[1], pair[0]}) {
+			ends.Add(pair)
+		}
+	}
+	return ends
+}
+
+// Same as Traits.Generator(), but only yields words that end with the given
+// pair of sounds. If the pair is not among Traits.Ends(), the generator is
+// exhausted from the start. Words are built from the end backwards using the
+// reversed traits, so only words with the given ending are ever visited.
+func (this *Traits) GeneratorTo(end [2]string) func() string {
+	if !this.PairSet.Has(end) || !this.validEnd(end) {
+		return func() string { return "" }
+	}
+	st := &state{
+		traits:   this.Reverse(),
+		prefix:   []string{end[1], end[0]},
+		reversed: true,
+		rnd:      newRand(),
+	}
+	return this.cased(st.next)
+}
+
+// Returns a copy of the set of sounds that occur in the words. Unlike the
+// SoundSet field, the copy may be modified without affecting the traits.
+func (this *Traits) Sounds() Set {
+	return copySet(this.SoundSet)
+}
+
+// Returns a copy of the set of sounds the traits classify as vowels: either
+// KnownVowels, or the default known vowels.
+func (this *Traits) Vowels() Set {
+	return copySet(this.knownVowels())
+}
+
+// Returns a copy of the set of pairs of sounds that occur in the words. Unlike
+// the PairSet field, the copy may be modified without affecting the traits.
+func (this *Traits) Pairs() PairSet {
+	pairs := make(PairSet, len(this.PairSet))
+	for pair := range this.PairSet {
+		pairs.Add(pair)
+	}
+	return pairs
+}
+
+// Returns a deep copy of the traits, which shares no sets or maps with them.
+// Copying a Traits value directly shares its sets and maps, so editing the
+// copy would also edit the original; clone traits before specialising them
+// with methods that modify them, such as Traits.DelPair().
+func (this *Traits) Clone() *Traits {
+	out := *this
+	out.SoundSet = copySet(this.SoundSet)
+	out.PairSet = copyPairSet(this.PairSet)
+	out.GramSet = copySet(this.GramSet)
+	out.StartSet = copyPairSet(this.StartSet)
+	out.MidSet = copyPairSet(this.MidSet)
+	out.EndSet = copyPairSet(this.EndSet)
+	out.HarmonySet = copyPairSet(this.HarmonySet)
+	out.Forbidden = copyPairSet(this.Forbidden)
+	out.KnownSounds = copySet(this.KnownSounds)
+	out.KnownVowels = copySet(this.KnownVowels)
+	out.Connectives = copySet(this.Connectives)
+	out.Rules = copyRules(this.Rules)
+	out.PairCount = copyPairCount(this.PairCount)
+	out.LengthCount = copyLengthCount(this.LengthCount)
+	out.BoundaryCount = copyPairCount(this.BoundaryCount)
+	if this.Spelling != nil {
+		out.Spelling = make(map[string]string, len(this.Spelling))
+		for sound, spelling := range this.Spelling {
+			out.Spelling[sound] = spelling
+		}
+	}
+	return &out
+}
+
+// Returns the minimum and maximum number of sounds in derived words.
+func (this *Traits) LengthRange() (min, max int) {
+	return this.MinNSounds, this.MaxNSounds
+}
+
+// Overrides the minimum and maximum number of sounds in derived words, which
+// are otherwise learned from the sample. Small samples often learn overly
+// tight bounds; widening them admits shorter or longer words. A minimum of 1
+// admits single-sound words. Returns an error if the bounds are invalid.
+func (this *Traits) SetLengthRange(min, max int) error {
+	if min < 1 || max < min {
+		return errors.New("invalid length range " + strconv.Itoa(min) + ".." + strconv.Itoa(max))
+	}
+	this.MinNSounds, this.MaxNSounds = min, max
+	return nil
+}
+
+// Returns the maximum number of consequtive vowels and consonants in derived
+// words.
+func (this *Traits) ClusterLimits() (vowels, consonants int) {
+	return this.MaxConseqVow, this.MaxConseqCons
+}
+
+// Overrides the maximum number of consequtive vowels and consonants in derived
+// words, which are otherwise learned from the sample. Lower limits suit
+// Hawaiian-style words with simple syllables; higher limits admit Slavic-style
+// consonant clusters. Since Examine() only ever raises the limits, call this
+// after analysis. Returns an error if a limit is below 1.
+func (this *Traits) SetClusterLimits(vowels, consonants int) error {
+	if vowels < 1 || consonants < 1 {
+		return errors.New("invalid cluster limits " + strconv.Itoa(vowels) + ", " + strconv.Itoa(consonants))
 	}
+	this.MaxConseqVow, this.MaxConseqCons = vowels, consonants
+	return nil
+}
+
+// Checks if the given word is among the words derived from the traits: it must
+// consist of known sounds, only use pairs of sounds from the traits, and
+// satisfy their limits. This lets applications validate user-entered words
+// against the style of a sample. Casing is ignored; spelling is not undone, so
+// for traits with Spelling, pass words in sounds.
+func (this *Traits) Matches(word string) bool {
+	sounds, err := getSounds(strings.ToLower(word), this.knownSounds())
+	return err == nil && this.isWord(sounds) && this.derivable(sounds)
 }
 
 /*--------------------------------- Private ---------------------------------*/
 
-// Takes a word, extracts its characteristics, and merges them into self. If the
-// word doesn't satisfy our limitations, returns an error.
-func (this *Traits) examineWord(word string) error {
+// Takes a word, extracts its characteristics, and merges them into self,
+// counting the word the given number of times. The known sounds are passed in,
+// so callers that examine many words compute them once. If the word doesn't
+// satisfy our limitations, returns an error.
+func (this *Traits) examineWord(word string, weight int, known Set) error {
 	if this == nil {
 		return errors.New("can't examine with nil pointer")
 	}
 
-	// Make sure the length is okay.
-	if !validLength(word) {
-		return errors.New("the word is too short or too long")
-	}
-
-	// Split into sounds.
-	sounds, err := getSounds(word, this.knownSounds())
+	sounds, err := this.sampleSounds(word, known)
 	if err != nil {
 		return err
 	}
 
-	// Mandate that at least two sounds are found.
-	if len(sounds) < 2 {
-		return errors.New("less than two sounds found")
-	}
-
 	// Merge min and max total number of sounds.
 	n := len(sounds)
 	if this.MinNSounds == 0 || n < this.MinNSounds {
@@ -175,6 +451,39 @@ func (this *Traits) examineWord(word string) error {
 		}
 	}
 
+	// Count occurrences of pairs of sounds.
+	if this.PairCount == nil {
+		this.PairCount = map[[2]string]int{}
+	}
+	for i := 0; i+1 < len(sounds); i++ {
+		this.PairCount[[2]string{sounds[i], sounds[i+1]}] += weight
+	}
+
+	// Count occurrences of lengths.
+	if this.LengthCount == nil {
+		this.LengthCount = map[int]int{}
+	}
+	this.LengthCount[len(sounds)] += weight
+
+	// Count occurrences of first and last sounds.
+	this.examineBoundaries(sounds, weight)
+
+	// Merge set of higher-order sequences, if enabled.
+	this.examineGrams(sounds)
+
+	// Merge positions of pairs, if enabled.
+	this.examinePositions(sounds)
+
+	// Merge vowels that occur together, if enabled.
+	this.examineHarmony(sounds)
+
+	// Drop forbidden pairs, which the sample mustn't bring back.
+	if len(this.Forbidden) > 0 {
+		if forbidden := getPairs(sounds).Intersect(this.Forbidden); len(forbidden) > 0 {
+			this.ForbidPairs(forbidden)
+		}
+	}
+
 	/*
 		// Disabled for now; this causes a combinatorial explosion so bad that test
 		// duration goes from seconds to minutes, if not hours. We should add an
@@ -187,12 +496,73 @@ func (this *Traits) examineWord(word string) error {
 	return nil
 }
 
+// Splits the given sample word into the given known sounds, or returns a
+// *WordError if it can't be examined.
+func (this *Traits) sampleSounds(word string, known Set) ([]string, error) {
+	// Make sure the length is okay.
+	if !validLength(word, this.maxWordLen()) {
+		return nil, wordError(word, "the word is too short or too long")
+	}
+
+	// Split into sounds.
+	sounds, err := getSounds(word, known)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mandate that at least two sounds are found.
+	if len(sounds) < 2 {
+		return nil, wordError(word, "less than two sounds found")
+	}
+	return sounds, nil
+}
+
+// Returns the weight of the given pair of sounds: its count in PairCount, or 1
+// if it has none.
+func (this *Traits) pairWeight(pair [2]string) int {
+	if count := this.PairCount[pair]; count > 0 {
+		return count
+	}
+	return 1
+}
+
+// Returns either the maximum sample word length associated with the traits, or
+// the default maximum.
+func (this *Traits) maxWordLen() int {
+	if this.MaxWordLen > 0 {
+		return this.MaxWordLen
+	}
+	return defaultMaxWordLen
+}
+
 // Returns either the set of known sounds associated with the traits, or the
-// default known sounds.
+// default known sounds, along with any connectives. Custom vowels are known
+// sounds too, so without custom KnownSounds, any custom vowels missing from the
+// defaults are added to them.
 func (this *Traits) knownSounds() Set {
+	if len(this.Connectives) > 0 {
+		return unite(this.knownLetters(), this.Connectives)
+	}
+	return this.knownLetters()
+}
+
+// Same as Traits.knownSounds(), but without connectives.
+func (this *Traits) knownLetters() Set {
 	if len(this.KnownSounds) > 0 {
 		return this.KnownSounds
 	}
+	var sounds Set
+	for vowel := range this.KnownVowels {
+		if !knownSounds.Has(vowel) {
+			if sounds == nil {
+				sounds = copySet(knownSounds)
+			}
+			sounds.Add(vowel)
+		}
+	}
+	if sounds != nil {
+		return sounds
+	}
 	return knownSounds
 }
 
@@ -205,14 +575,46 @@ func (this *Traits) knownVowels() Set {
 	return knownVowels
 }
 
+// Returns the smallest number of sounds in a derived word. Words have at least
+// two sounds, unless single-sound words were requested explicitly by setting
+// MinNSounds to 1. Traits.Examine() never does that, since it only accepts
+// words of two or more sounds.
+func (this *Traits) minWordSounds() int {
+	if this.MinNSounds == 1 {
+		return 1
+	}
+	return 2
+}
+
 // Checks whether the given combination of sounds satisfies the conditions for
 // a partial word. This is defined as follows:
-//   1) the sounds don't exceed any of the numeric criteria in the given traits;
-//   2) if there's only one sound, it must be the first sound in at least one
-//      of the sound pairs in the given traits;
-//   3) if there's at least one pair, the sequence of pairs must be valid as
-//      defined in Traits.validPairs.
+//  1. the sounds don't exceed any of the numeric criteria in the given traits;
+//  2. the first sound must be the first sound in at least one of the sound
+//     pairs in the given traits, which is the transition from the StartToken
+//     per Traits.transitions();
+//  3. if there's at least one pair, the sequence of pairs must be valid as
+//     defined in Traits.validPairs, and use no more pairs missing from the
+//     sample than allowed, as defined in Traits.validUnseen;
+//  4. if the traits have an order of 2 or more, the sequence must consist of
+//     known higher-order sequences, as defined in Traits.validGrams;
+//  5. if the traits have harmony, the vowels must occur together in the
+//     sample, as defined in Traits.validHarmony;
+//  6. if the traits are positional, the pairs must occur in their positions,
+//     as defined in Traits.validPositions;
+//  7. the sequence must not begin with a connective.
 func (this *Traits) validPart(sounds ...string) bool {
+	return this.validPartIn(nil, sounds)
+}
+
+// Same as Traits.validPart(), looking up pairs per Traits.hasPair(), and the
+// transition from the StartToken per Traits.validFirst().
+func (this *Traits) validPartIn(table *soundTable, sounds []string) bool {
+	return this.validSequence(table, sounds) && this.validPositions(sounds) && this.validFirst(table, sounds)
+}
+
+// Checks conditions (1) and (3) to (5) of Traits.validPart(), regardless of the
+// positions of pairs, looking up pairs per Traits.hasPair().
+func (this *Traits) validSequence(table *soundTable, sounds []string) bool {
 	// Check numeric criteria.
 	if this.countVowels(sounds) > this.MaxNVowels ||
 		this.maxConsequtiveVowels(sounds) > this.MaxConseqVow ||
@@ -220,35 +622,46 @@ func (this *Traits) validPart(sounds ...string) bool {
 		return false
 	}
 
-	// If there's only one sound, check if it's among the first sounds of pairs.
-	if len(sounds) == 1 {
-		for pair := range this.PairSet {
-			if pair[0] == sounds[0] {
-				return true
-			}
-		}
-	}
-
 	// Check if the pair sequence is valid per Traits.validPairs.
 	if len(sounds) > 1 && !this.validPairs(sounds) {
 		return false
 	}
 
+	// Check unseen pairs per Traits.validUnseen.
+	if !this.validUnseen(table, sounds) {
+		return false
+	}
+
+	// Check higher-order sequences per Traits.validGrams.
+	if !this.validGrams(sounds) {
+		return false
+	}
+
+	// Check vowel harmony per Traits.validHarmony.
+	if !this.validHarmony(sounds) {
+		return false
+	}
+
 	return true
 }
 
 // Checks whether the given sequence of sounds satisfies the criteria for a
 // complete word. This is defined as follows:
-//   1) the sequence satisfies the partial criteria per Traits.validPart();
-//   2) the sequence satisfies the complete criteria per Traits.checkPart().
+//  1. the sequence satisfies the partial criteria per Traits.validPart();
+//  2. the sequence satisfies the complete criteria per Traits.checkPart().
 func (this *Traits) validComplete(sounds ...string) bool {
 	return this.validPart(sounds...) && this.checkPart(sounds...)
 }
 
 // Takes a valid partial word and checks if it's also a valid complete word,
 // using the following criteria:
-//   1) the number of vowels must fit within the bounds;
-//   2) the number of sounds must fit within the bounds.
+//  1. the number of vowels must fit within the bounds;
+//  2. the number of sounds must fit within the bounds;
+//  3. if the traits are positional, the last pair must be one that ends words;
+//  4. the last sound must be the second sound in at least one of the sound
+//     pairs, and not a connective, which is the transition to the EndToken
+//     per Traits.transitions().
+//
 // The behaviour of this method for input values other than partial words is
 // undefined.
 func (this *Traits) checkPart(sounds ...string) bool {
@@ -261,16 +674,18 @@ func (this *Traits) checkPart(sounds ...string) bool {
 	if len(sounds) < this.MinNSounds || len(sounds) > this.MaxNSounds {
 		return false
 	}
-	return true
+	// Check the ending.
+	return this.validEnding(sounds) && this.validLast(nil, sounds)
 }
 
 // Verifies the validity of the sequence of sound pairs comprising the given
 // word. Defined as follows:
-//   1) the sequence must consist of sound pairs in the given traits; this is
-//      implicitly guaranteed by the current tree traversal algorithms, so we
-//      skip this check to save performance;
-//   2) no sound pair immediately follows itself (e.g. "tata" in "ratatater");
-//   3) no sound pair occurs more than twice.
+//  1. the sequence must consist of sound pairs in the given traits; this is
+//     implicitly guaranteed by the current tree traversal algorithms, so we
+//     skip this check to save performance;
+//  2. no sound pair immediately follows itself (e.g. "tata" in "ratatater");
+//  3. no sound pair occurs more than twice.
+//
 // This has been somewhat optimised. Might stand for further improvement.
 func (this *Traits) validPairs(sounds []string) bool {
 	if len(sounds) < 2 {
@@ -320,6 +735,9 @@ func (this *Traits) maxConsequtiveVowels(sounds []string) int {
 // Returns the biggest number of consequtive consonants that occurs in the given
 // sound sequence.
 func (this *Traits) maxConsequtiveConsonants(sounds []string) int {
+	if len(this.Connectives) > 0 {
+		return maxConsequtiveConsonantsBetween(sounds, this.knownVowels(), this.Connectives)
+	}
 	return maxConsequtiveConsonants(sounds, this.knownVowels())
 }
 
@@ -340,3 +758,16 @@ func NewTraits(words []string) (*Traits, error) {
 	}
 	return traits, nil
 }
+
+// Same as NewTraits(), but splits the words into the given sounds instead of
+// the default known sounds. The sounds may be multigraphs of any length, such as
+// "dzh" or "tsch"; where they overlap, the longest match wins. Vowels are still
+// the default known vowels; to use other vowels, create the traits with both
+// KnownSounds and KnownVowels and call Traits.Examine().
+func NewTraitsWithSounds(words []string, sounds Set) (*Traits, error) {
+	traits := &Traits{KnownSounds: sounds}
+	if err := traits.Examine(words); err != nil {
+		return nil, err
+	}
+	return traits, nil
+}
@@ -3,9 +3,11 @@ package codex
 // Utility functions and types.
 
 import (
-	"errors"
 	"math/rand"
+	"sort"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 /********************************* Utilities *********************************/
@@ -17,21 +19,45 @@ func init() {
 }
 
 // Takes a word and splits it into a series of known glyphs representing sounds.
+// Glyphs may have any number of letters; at each position, the longest
+// matching glyph wins, so with known "s", "c", "h" and "sch", the word "schon"
+// begins with "sch". Letters are defined by letterOffsets(), so glyphs may use
+// any script, and combining marks stay with their letters.
 func getSounds(word string, known Set) ([]string, error) {
-	sounds := make([]string, 0, len(word))
+	longest := 0
+	for glyph := range known {
+		if n := countLetters(glyph); n > longest {
+			longest = n
+		}
+	}
+
+	offsets := letterOffsets(word)
+	nLetters := len(offsets) - 1
+
+	sounds := make([]string, 0, nLetters)
 	// Loop over the word, matching known glyphs. Break if no match is found.
-	for i := 0; i < len(word); i++ {
-		// Check for a known digraph.
-		if i+2 <= len(word) && known.Has(word[i:i+2]) {
-			sounds = append(sounds, word[i:i+2])
-			i++
-			// Check for a known monograph.
-		} else if known.Has(word[i : i+1]) {
-			sounds = append(sounds, word[i:i+1])
-			// Otherwise return an error.
-		} else {
-			return nil, errors.New("encountered unknown symbol")
+	for i := 0; i < nLetters; {
+		size := longest
+		if i+size > nLetters {
+			size = nLetters - i
+		}
+		// Check for known glyphs, from longest to shortest.
+		for ; size > 0; size-- {
+			if known.Has(word[offsets[i]:offsets[i+size]]) {
+				break
+			}
+		}
+		// Otherwise return an error.
+		if size == 0 {
+			return nil, &WordError{
+				Word:   word,
+				Reason: "encountered unknown symbol",
+				Offset: offsets[i],
+				Symbol: word[offsets[i]:offsets[i+1]],
+			}
 		}
+		sounds = append(sounds, word[offsets[i]:offsets[i+size]])
+		i += size
 	}
 	// Return the found glyphs.
 	return sounds, nil
@@ -53,9 +79,44 @@ func addReversePairs(pairs PairSet) {
 	}
 }
 
-// Checks if the given word is too short or too long.
-func validLength(word string) bool {
-	return len(word) > 1 && len(word) < 33
+// Default maximum length of sample words, in letters.
+const defaultMaxWordLen = 32
+
+// Checks if the given word is too short, or longer than the given number of
+// letters, as defined by letterOffsets().
+func validLength(word string, max int) bool {
+	n := countLetters(word)
+	return n > 1 && n <= max
+}
+
+// Returns the byte offsets of the letters of the given word, followed by the
+// length of the word. A letter is a code point along with any combining marks
+// that follow it, such as the tone marks of Vietnamese or pinyin, so "a"
+// followed by U+0301 is one letter, like the precomposed "á". This
+// approximates grapheme clusters well enough for alphabets.
+func letterOffsets(word string) []int {
+	offsets := make([]int, 0, len(word)+1)
+	for offset, char := range word {
+		if offset == 0 || !isMark(char) {
+			offsets = append(offsets, offset)
+		}
+	}
+	return append(offsets, len(word))
+}
+
+// Counts the letters of the given word, as defined by letterOffsets().
+func countLetters(word string) (count int) {
+	for offset, char := range word {
+		if offset == 0 || !isMark(char) {
+			count++
+		}
+	}
+	return
+}
+
+// Checks if the given character is a combining mark.
+func isMark(char rune) bool {
+	return char >= utf8.RuneSelf && unicode.Is(unicode.M, char)
 }
 
 // Copy of Join from the standard package `strings`.
@@ -80,14 +141,20 @@ func join(a []string, sep string) string {
 	return string(b)
 }
 
-func permutate(length int) []int {
-	return rand.Perm(length)
+// Returns a random permutation of [0, length) drawn from the given source, or
+// from the global source if it's nil.
+func permutate(rnd *rand.Rand, length int) []int {
+	if rnd == nil {
+		return rand.Perm(length)
+	}
+	return rnd.Perm(length)
 }
 
-// Shuffles a slice of strings in-place, using the Fisher–Yates method.
-func shuffle(values []string) {
+// Shuffles a slice of strings in-place, using the Fisher–Yates method. Draws
+// from the given source, or from the global source if it's nil.
+func shuffle(rnd *rand.Rand, values []string) {
 	for i := range values {
-		j := rand.Intn(i + 1)
+		j := randIntn(rnd, i+1)
 		values[i], values[j] = values[j], values[i]
 	}
 }
@@ -107,84 +174,45 @@ func nodeValues(nodes map[string]*tree) (result []string) {
 	return
 }
 
-// Gets the node values from the given map of child nodes and shuffles it.
-func randNodeValues(nodes map[string]*tree) (result []string) {
+// Gets the node values from the given map of child nodes and shuffles it. If
+// `ordered` is true, the values are sorted before shuffling, so the result
+// depends only on the source rather than on map iteration order.
+func randNodeValues(rnd *rand.Rand, nodes map[string]*tree, ordered bool) (result []string) {
 	result = nodeValues(nodes)
 	if len(result) == 0 {
 		return
 	}
-	shuffle(result)
+	if ordered {
+		sort.Strings(result)
+	}
+	shuffle(rnd, result)
 	return
 }
 
 // Creates shallow child nodes for a tree from the given pairs on the given
-// path.
+// path. An empty path starts from the StartToken, so the children are the
+// sounds that may begin words, if the pairs include such transitions.
 func sprout(pairs PairSet, path ...string) (nodes map[string]*tree) {
 	nodes = map[string]*tree{}
-	if len(path) == 0 {
-		// If no sound were passed, start from the root.
-		for pair := range pairs {
-			if _, ok := nodes[pair[0]]; ok {
-				continue
-			}
-			nodes[pair[0]] = nil
-		}
-		// Otherwise continue from the given path.
-	} else {
-		// [ ... sounds ... ( last sound ] <- pair -> next sound )
-		//
-		// We investigate pairs that begin with the last sound of the given
-		// preceding sounds. Their second sounds form a set that, when individually
-		// appended to the preceding sounds, form foundation paths for child
-		// subtrees. We register these second sounds on the child node map.
-		for pair := range pairs {
-			if pair[0] == path[len(path)-1] {
-				nodes[pair[1]] = nil
-			}
+	last := StartToken
+	if len(path) > 0 {
+		last = path[len(path)-1]
+	}
+	// [ ... sounds ... ( last sound ] <- pair -> next sound )
+	//
+	// We investigate pairs that begin with the last sound of the given
+	// preceding sounds. Their second sounds form a set that, when individually
+	// appended to the preceding sounds, form foundation paths for child
+	// subtrees. We register these second sounds on the child node map. The
+	// EndToken ends words rather than extending them, so it's never a child.
+	for pair := range pairs {
+		if pair[0] == last && pair[1] != EndToken {
+			nodes[pair[1]] = nil
 		}
 	}
 	return
 }
 
-/********************************** PairSet **********************************/
-
-/**
- * Performance note: tried a slice version, and it significantly decreased the
- * package's benchmark performance. Sticking with a map for now.
- */
-
-// PairSet behaves like a set of pairs of strings.
-type PairSet map[[2]string]struct{}
-
-// Creates a new set from the given keys. Usage:
-//   PairSet.New(nil, [2]string{"one", "other"})
-func (PairSet) New(keys ...[2]string) PairSet {
-	set := make(PairSet, len(keys))
-	for _, key := range keys {
-		set.Add(key)
-	}
-	return set
-}
-
-// Adds the given element.
-func (this *PairSet) Add(key [2]string) {
-	if *this == nil {
-		*this = PairSet{}
-	}
-	(*this)[key] = struct{}{}
-}
-
-// Deletes the given element.
-func (this *PairSet) Del(key [2]string) {
-	delete((*this), key)
-}
-
-// Checks for the presence of the given element.
-func (this *PairSet) Has(key [2]string) bool {
-	_, ok := (*this)[key]
-	return ok
-}
-
 /*********************************** tree ************************************/
 
 // A tree that defines a set of string sequences. Node values represent sounds.
@@ -203,6 +231,10 @@ type tree struct {
 func (this *tree) at(path ...string) (node *tree) {
 	node = this
 	for _, value := range path {
+		// Nodes above a state's prefix are never walked, so they may be bare.
+		if node.nodes == nil {
+			node.nodes = map[string]*tree{}
+		}
 		if node.nodes[value] == nil {
 			node.nodes[value] = new(tree)
 		}
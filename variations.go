@@ -0,0 +1,51 @@
+package codex
+
+// Near-misses of a single word.
+
+/********************************** Statics **********************************/
+
+// Treats the given word as the only sample and returns up to `n` random words
+// similar to it, never including the word itself. A single word is a sparse
+// sample, so its traits are relaxed: pairs of sounds may also occur in reverse,
+// each word may have one transition that doesn't occur in the word either way,
+// and each numeric limit is loosened by one. Returns an error if the word is
+// invalid.
+func Variations(word string, n int) (Set, error) {
+	traits, err := NewTraits([]string{word})
+	if err != nil {
+		return nil, err
+	}
+	traits.relax()
+
+	gen := traits.Generator()
+	words := Set{}
+	for len(words) < n {
+		variation := gen()
+		if variation == "" {
+			break
+		}
+		if variation != word {
+			words.Add(variation)
+		}
+	}
+	return words, nil
+}
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Adds reverse pairs, allows one unseen pair, and loosens each numeric limit
+// by one.
+func (this *Traits) relax() {
+	addReversePairs(this.PairSet)
+	this.MaxUnseen = 1
+	if this.MinNSounds > 2 {
+		this.MinNSounds--
+	}
+	this.MaxNSounds++
+	if this.MinNVowels > 0 {
+		this.MinNVowels--
+	}
+	this.MaxNVowels++
+	this.MaxConseqVow++
+	this.MaxConseqCons++
+}
@@ -0,0 +1,34 @@
+package codex
+
+// Weighted ordering of sounds, used by generators of weighted traits.
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+/*--------------------------------- Private ---------------------------------*/
+
+// Orders the given sounds randomly, such that each sound is more likely to come
+// early in proportion to its weight. Uses the method of Efraimidis and
+// Spirakis: each sound gets a random key drawn from the exponential
+// distribution with its weight as the rate, and sounds are sorted by key.
+// Sounds with no weight come last.
+func weightedShuffle(rnd *rand.Rand, sounds []string, weight func(string) float64) {
+	random := rand.Float64
+	if rnd != nil {
+		random = rnd.Float64
+	}
+	keys := make(map[string]float64, len(sounds))
+	for _, sound := range sounds {
+		if weight := weight(sound); weight > 0 {
+			keys[sound] = -math.Log(1-random()) / weight
+		} else {
+			keys[sound] = math.Inf(1)
+		}
+	}
+	sort.Slice(sounds, func(i, j int) bool {
+		return keys[sounds[i]] < keys[sounds[j]]
+	})
+}
@@ -0,0 +1,351 @@
+package codex_test
+
+// Manifest of the exported API. Each declaration below fails to compile if the
+// corresponding identifier is removed or its signature changes, so breaking
+// changes can't land by accident. Additions don't break users and don't need
+// to be listed, but should be, to protect them from then on. Changing this
+// file means breaking compatibility.
+
+import (
+	"encoding"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/Mitranim/codex"
+)
+
+/********************************* Functions *********************************/
+
+var (
+	_ func([]string) (*codex.Traits, error)                                 = codex.NewTraits
+	_ func([]string, codex.Set) (*codex.Traits, error)                      = codex.NewTraitsWithSounds
+	_ func(io.Reader) (*codex.Traits, error)                                = codex.NewTraitsFromReader
+	_ func([]byte) (*codex.Traits, error)                                   = codex.NewTraitsFromFoliant
+	_ func(io.Reader) ([]string, error)                                     = codex.LoadWords
+	_ func(fs.FS, string) ([]string, error)                                 = codex.LoadWordsFS
+	_ func(io.Reader) (map[string]int, error)                               = codex.LoadFrequencies
+	_ func(io.Reader) (*codex.Traits, error)                                = codex.LoadModel
+	_ func([]string, int) (*codex.Traits, error)                            = codex.NewTraitsN
+	_ func([]string) (*codex.Traits, error)                                 = codex.NewTraitsFromSyllables
+	_ func([]string, []string, []string) (*codex.Traits, error)             = codex.NewTraitsFromTable
+	_ func(*codex.Traits, *codex.Traits, bool) func() (string, string)      = codex.NameGenerator
+	_ func(*codex.Traits, *codex.Traits, int, bool) [][2]string             = codex.NameN
+	_ func(*codex.Traits, *codex.Traits, string) func() string              = codex.CompoundGenerator
+	_ func(string, string, int) (codex.Set, error)                          = codex.Blend
+	_ func(*codex.Traits, *codex.Traits, float64) (*codex.Traits, error)    = codex.Interpolate
+	_ func(*codex.Traits, uint64) string                                    = codex.DeriveWord
+	_ func(func() string, func(string) string) func() string                = codex.Unique
+	_ func(func() string, func(string) string, codex.WordSet) func() string = codex.UniqueWith
+	_ func(int, float64) *codex.BloomSet                                    = codex.NewBloomSet
+	_ func(*codex.BloomSet, string)                                         = (*codex.BloomSet).Add
+	_ func(*codex.BloomSet, string) bool                                    = (*codex.BloomSet).Has
+	_ func(*codex.BloomSet) int                                             = (*codex.BloomSet).Len
+	_ func(*codex.BloomSet) int                                             = (*codex.BloomSet).Size
+	_ func() codex.Set                                                      = codex.DefaultSounds
+	_ func() codex.Set                                                      = codex.DefaultVowels
+	_ func(string) codex.Set                                                = codex.Letters
+	_ func([]string, float64) codex.Set                                     = codex.DiscoverDigraphs
+	_ func() codex.Set                                                      = codex.CyrillicSounds
+	_ func() codex.Set                                                      = codex.CyrillicVowels
+	_ func() []codex.Rule                                                   = codex.EnglishRules
+	_ func(func() string, int) *codex.Reader                                = codex.NewReader
+	_ func(string, bool) (*codex.FileSink, error)                           = codex.NewFileSink
+	_ func(*codex.Traits) *codex.Model                                      = codex.NewModel
+	_ func(*codex.Traits) *codex.Session                                    = codex.NewSession
+	_ func([]string) codex.Casing                                           = codex.DetectCasing
+	_ func(func() string, int, int) []func() string                         = codex.Split
+	_ func([]string, func(string, string) int)                              = codex.SortWords
+	_ func(string) string                                                   = codex.ExactKey
+	_ func(string) string                                                   = codex.CaseKey
+	_ func(string) string                                                   = codex.DiacriticKey
+	_ func(string) string                                                   = codex.PhoneticKey
+	_ func()                                                                = codex.UseGlobalRand
+	_ *func(string)                                                         = &codex.Warn
+	_ func(string, int) (codex.Set, error)                                  = codex.Variations
+)
+
+/********************************** Methods **********************************/
+
+var (
+	_ func(*codex.Traits, []string) error                               = (*codex.Traits).Examine
+	_ func(*codex.Traits, []string) error                               = (*codex.Traits).ExaminePhonemes
+	_ func(*codex.Traits, string) bool                                  = (*codex.Traits).Matches
+	_ func(*codex.Traits, string) float64                               = (*codex.Traits).Score
+	_ func(*codex.Traits, io.Writer, int, rune) error                   = (*codex.Traits).WriteCSV
+	_ func(*codex.Traits, *codex.Traits) *codex.Traits                  = (*codex.Traits).Merge
+	_ func(*codex.Traits, *codex.Traits) *codex.Traits                  = (*codex.Traits).Intersect
+	_ func(*codex.Traits, *codex.Traits) *codex.Traits                  = (*codex.Traits).Exclude
+	_ func(*codex.Traits, string) error                                 = (*codex.Traits).AddWord
+	_ func(*codex.Traits, map[string]int) error                         = (*codex.Traits).ExamineWeighted
+	_ func(*codex.Traits, io.Reader) error                              = (*codex.Traits).ExamineReader
+	_ func(*codex.Traits, io.Writer) error                              = (*codex.Traits).SaveModel
+	_ func(*codex.Traits, string) error                                 = (*codex.Traits).RemoveWord
+	_ func(*codex.Traits, string) error                                 = (*codex.Traits).Explain
+	_ func(*codex.Traits) *codex.Traits                                 = (*codex.Traits).Clone
+	_ func(*codex.Traits, int, int) error                               = (*codex.Traits).SetLengthRange
+	_ func(*codex.Traits, int, int) error                               = (*codex.Traits).SetClusterLimits
+	_ func(*codex.Traits) *codex.Traits                                 = (*codex.Traits).Reverse
+	_ func(*codex.Traits) func() string                                 = (*codex.Traits).Generator
+	_ func(*codex.Traits, codex.MemoryProfile) func() string            = (*codex.Traits).GeneratorWith
+	_ func(*codex.Traits) codex.Set                                     = (*codex.Traits).Sounds
+	_ func(*codex.Traits) codex.PairSet                                 = (*codex.Traits).Pairs
+	_ func(*codex.Traits) (int, int)                                    = (*codex.Traits).LengthRange
+	_ func(*codex.Traits) (int, int)                                    = (*codex.Traits).ClusterLimits
+	_ func(*codex.Traits) codex.Set                                     = (*codex.Traits).Vowels
+	_ func(*codex.Traits, int) (codex.Set, error)                       = (*codex.Traits).WordsN
+	_ func(*codex.Traits) codex.Set                                     = (*codex.Traits).Words
+	_ func(*codex.Traits, io.Writer, int, string) error                 = (*codex.Traits).WriteWordsN
+	_ func(*codex.Traits) codex.CountSet                                = (*codex.Traits).Derivations
+	_ func(*codex.Traits) uint64                                        = (*codex.Traits).Count
+	_ func(*codex.Traits) float64                                       = (*codex.Traits).EstimateCount
+	_ func(*codex.Traits) codex.PairSet                                 = (*codex.Traits).Starts
+	_ func(*codex.Traits, [2]string) func() string                      = (*codex.Traits).GeneratorFrom
+	_ func(*codex.Traits) codex.PairSet                                 = (*codex.Traits).Ends
+	_ func(*codex.Traits, [2]string) func() string                      = (*codex.Traits).GeneratorTo
+	_ func(*codex.Traits) func() string                                 = (*codex.Traits).UniformGenerator
+	_ func(*codex.Traits) func() string                                 = (*codex.Traits).SortedGenerator
+	_ func(*codex.Traits) *codex.Enumeration                            = (*codex.Traits).Enumerate
+	_ func(*codex.Traits, codex.Checkpoint) (*codex.Enumeration, error) = (*codex.Traits).Resume
+	_ func(*codex.Traits) (func() string, codex.Recording)              = (*codex.Traits).RecordGenerator
+	_ func(*codex.Traits, codex.Recording) func() string                = (*codex.Traits).ReplayGenerator
+	_ func(*codex.Traits, uint64) (string, error)                       = (*codex.Traits).At
+	_ func(*codex.Traits, string) (uint64, error)                       = (*codex.Traits).IndexOf
+	_ func(*codex.Traits, int) (codex.Set, error)                       = (*codex.Traits).Sample
+	_ func(*codex.Traits, int) []string                                 = (*codex.Traits).TopK
+	_ func(*codex.Traits, int, int, codex.Reranker) ([]string, error)   = (*codex.Traits).Rerank
+	_ func(*codex.Traits, codex.WordSink) error                         = (*codex.Traits).WordsTo
+	_ func(*codex.Traits, string, int) (codex.Set, error)               = (*codex.Traits).Mutate
+	_ func(*codex.Traits, int) (codex.SyllableTable, codex.Fidelity)    = (*codex.Traits).SyllableTable
+	_ func(*codex.Traits) codex.Matrix                                  = (*codex.Traits).Matrix
+	_ func(*codex.Traits, codex.Matrix) error                           = (*codex.Traits).ImportMatrix
+	_ func(*codex.Traits) codex.Stats                                   = (*codex.Traits).Stats
+	_ func(*codex.Traits) uint64                                        = (*codex.Traits).Hash
+	_ func(codex.Traits) ([]byte, error)                                = codex.Traits.MarshalProto
+	_ func(*codex.Traits, []byte) error                                 = (*codex.Traits).UnmarshalProto
+	_ func(codex.Traits) ([]byte, error)                                = codex.Traits.MarshalFoliant
+	_ func(*codex.Traits, [2]string) error                              = (*codex.Traits).AddPair
+	_ func(*codex.Traits, [2]string)                                    = (*codex.Traits).DelPair
+	_ func(*codex.Traits, string)                                       = (*codex.Traits).DelSound
+	_ func(*codex.Traits, int) int                                      = (*codex.Traits).Prune
+	_ func(*codex.Traits, string, string)                               = (*codex.Traits).ForbidPair
+	_ func(*codex.Traits, codex.PairSet)                                = (*codex.Traits).ForbidPairs
+	_ func(*codex.Traits) error                                         = (*codex.Traits).Validate
+
+	_ func(codex.Set, ...string) codex.Set                         = codex.Set.New
+	_ func(*codex.Set, string)                                     = (*codex.Set).Add
+	_ func(*codex.Set, string)                                     = (*codex.Set).Del
+	_ func(*codex.Set, string) bool                                = (*codex.Set).Has
+	_ func(codex.Set) []string                                     = codex.Set.Slice
+	_ func(codex.Set) []string                                     = codex.Set.Sorted
+	_ func(codex.Set, codex.Set) codex.Set                         = codex.Set.Union
+	_ func(codex.Set, codex.Set) codex.Set                         = codex.Set.Intersect
+	_ func(codex.Set, codex.Set) codex.Set                         = codex.Set.Diff
+	_ func(codex.Set, func(string) bool) codex.Set                 = codex.Set.Filter
+	_ func(codex.Set, func(string) string) codex.Set               = codex.Set.Map
+	_ func(codex.Set) (string, bool)                               = codex.Set.Pick
+	_ func(codex.Set, int) []string                                = codex.Set.PickN
+	_ func(*codex.CountSet, string)                                = (*codex.CountSet).Add
+	_ func(codex.CountSet, string) int                             = codex.CountSet.Count
+	_ func(codex.CountSet) codex.Set                               = codex.CountSet.Set
+	_ func(codex.CountSet) []string                                = codex.CountSet.Ranked
+	_ func(codex.SetOrder, ...string) *codex.OrderedSet            = codex.NewOrderedSet
+	_ func(*codex.OrderedSet) codex.SetOrder                       = (*codex.OrderedSet).Order
+	_ func(*codex.OrderedSet, string)                              = (*codex.OrderedSet).Add
+	_ func(*codex.OrderedSet, string)                              = (*codex.OrderedSet).Del
+	_ func(*codex.OrderedSet, string) bool                         = (*codex.OrderedSet).Has
+	_ func(*codex.OrderedSet) int                                  = (*codex.OrderedSet).Len
+	_ func(*codex.OrderedSet) []string                             = (*codex.OrderedSet).Slice
+	_ func(*codex.OrderedSet) codex.Set                            = (*codex.OrderedSet).Set
+	_ func(*codex.OrderedSet, *codex.OrderedSet) *codex.OrderedSet = (*codex.OrderedSet).Union
+	_ func(*codex.OrderedSet, *codex.OrderedSet) *codex.OrderedSet = (*codex.OrderedSet).Intersect
+	_ func(*codex.OrderedSet, *codex.OrderedSet) *codex.OrderedSet = (*codex.OrderedSet).Diff
+	_ func(*codex.OrderedSet, func(string) bool) *codex.OrderedSet = (*codex.OrderedSet).Filter
+	_ func(codex.PairSet, ...[2]string) codex.PairSet              = codex.PairSet.New
+	_ func(*codex.PairSet, [2]string)                              = (*codex.PairSet).Add
+	_ func(*codex.PairSet, [2]string)                              = (*codex.PairSet).Del
+	_ func(*codex.PairSet, [2]string) bool                         = (*codex.PairSet).Has
+	_ func(codex.PairSet) [][2]string                              = codex.PairSet.Sorted
+	_ func(codex.PairSet, codex.PairSet) codex.PairSet             = codex.PairSet.Union
+	_ codex.SetOf[string]                                          = codex.Set{}
+	_ codex.SetOf[[2]string]                                       = codex.PairSet{}
+)
+
+var (
+	_ func(*codex.Model) *codex.Traits                   = (*codex.Model).Traits
+	_ func(*codex.Model, *codex.Traits)                  = (*codex.Model).Swap
+	_ func(*codex.Model, codex.SwapPolicy) func() string = (*codex.Model).Generator
+)
+
+var (
+	_ func(*codex.Session) string                  = (*codex.Session).Next
+	_ func(*codex.Session, int) (codex.Set, error) = (*codex.Session).WordsN
+	_ func(*codex.Session)                         = (*codex.Session).Reset
+)
+
+var (
+	_ func(*codex.Reader) (string, error)       = (*codex.Reader).Next
+	_ func(*codex.Reader) error                 = (*codex.Reader).Err
+	_ error                                     = codex.ErrExhausted
+	_ error                                     = codex.ErrBudgetExceeded
+	_ func(*codex.Enumeration) string           = (*codex.Enumeration).Next
+	_ func(*codex.Enumeration) codex.Checkpoint = (*codex.Enumeration).Checkpoint
+	_ func(*codex.FileSink, string) error       = (*codex.FileSink).WriteWord
+	_ func(*codex.FileSink) error               = (*codex.FileSink).Close
+	_ error                                     = codex.ErrNotModel
+)
+
+/******************************** Interfaces *********************************/
+
+var (
+	_ json.Marshaler                 = codex.Traits{}
+	_ json.Unmarshaler               = (*codex.Traits)(nil)
+	_ encoding.TextMarshaler         = codex.Traits{}
+	_ encoding.TextUnmarshaler       = (*codex.Traits)(nil)
+	_ encoding.TextMarshaler         = codex.Set{}
+	_ encoding.TextUnmarshaler       = (*codex.Set)(nil)
+	_ json.Marshaler                 = codex.Set{}
+	_ json.Unmarshaler               = (*codex.Set)(nil)
+	_ json.Marshaler                 = codex.PairSet{}
+	_ json.Unmarshaler               = (*codex.PairSet)(nil)
+	_ codex.WordSet                  = (*codex.Set)(nil)
+	_ codex.WordSet                  = (*codex.BloomSet)(nil)
+	_ encoding.TextMarshaler         = codex.PairSet{}
+	_ encoding.TextUnmarshaler       = (*codex.PairSet)(nil)
+	_ encoding.BinaryMarshaler       = codex.Traits{}
+	_ encoding.BinaryUnmarshaler     = (*codex.Traits)(nil)
+	_ error                          = (*codex.CountError)(nil)
+	_ error                          = (*codex.WordError)(nil)
+	_ error                          = (*codex.VersionError)(nil)
+	_ io.Reader                      = (*codex.Reader)(nil)
+	_ codex.WordSink                 = (*codex.FileSink)(nil)
+	_ io.Closer                      = (*codex.FileSink)(nil)
+	_ interface{ String() string }   = codex.Set{}
+	_ interface{ GoString() string } = codex.Set{}
+	_ interface{ String() string }   = codex.PairSet{}
+	_ interface{ GoString() string } = codex.PairSet{}
+)
+
+/********************************* Constants *********************************/
+
+var (
+	_ = [...]codex.Casing{codex.LowerCase, codex.TitleCase, codex.UpperCase}
+	_ = [...]codex.SetOrder{codex.InsertionOrder, codex.SortedOrder}
+	_ = [...]codex.SwapPolicy{codex.FinishOld, codex.SwitchNext}
+	_ = [...]codex.MemoryProfile{codex.FastProfile, codex.LowMemoryProfile}
+	_ = [...]string{codex.StartToken, codex.EndToken}
+)
+
+/********************************** Fields ***********************************/
+
+// Verifies that exported struct types have at least the listed fields, with
+// the listed types. Unlike functions, fields can't be checked at compile time
+// without also constraining their order, so this is a runtime test.
+func Test_API_Fields(t *testing.T) {
+	// t.SkipNow()
+
+	manifest := []struct {
+		value  interface{}
+		fields map[string]interface{}
+	}{
+		{codex.Traits{}, map[string]interface{}{
+			"MinNSounds":    0,
+			"MaxNSounds":    0,
+			"MinNVowels":    0,
+			"MaxNVowels":    0,
+			"MaxConseqVow":  0,
+			"MaxConseqCons": 0,
+			"SoundSet":      codex.Set{},
+			"PairSet":       codex.PairSet{},
+			"PairCount":     map[[2]string]int{},
+			"LengthCount":   map[int]int{},
+			"BoundaryCount": map[[2]string]int{},
+			"Weighted":      false,
+			"MaxUnseen":     0,
+			"Smoothing":     float64(0),
+			"Forbidden":     codex.PairSet{},
+			"KnownSounds":   codex.Set{},
+			"KnownVowels":   codex.Set{},
+			"Connectives":   codex.Set{},
+			"Casing":        codex.Casing(0),
+			"Spelling":      map[string]string{},
+			"Rules":         []codex.Rule{},
+			"MaxWordLen":    0,
+			"Order":         0,
+			"GramSet":       codex.Set{},
+			"Backoff":       false,
+			"Positional":    false,
+			"StartSet":      codex.PairSet{},
+			"MidSet":        codex.PairSet{},
+			"EndSet":        codex.PairSet{},
+			"Harmony":       false,
+			"HarmonySet":    codex.PairSet{},
+		}},
+		{codex.CountError{}, map[string]interface{}{
+			"Requested": 0,
+			"Available": 0,
+		}},
+		{codex.SyllableTable{}, map[string]interface{}{
+			"Prefixes": codex.Set{},
+			"Middles":  codex.Set{},
+			"Suffixes": codex.Set{},
+		}},
+		{codex.Fidelity{}, map[string]interface{}{
+			"NWords":       0,
+			"NSkipped":     0,
+			"MissingPairs": codex.PairSet{},
+			"ExtraPairs":   codex.PairSet{},
+		}},
+		{codex.Matrix{}, map[string]interface{}{
+			"Transitions": map[string]map[string]int{},
+			"Starts":      codex.Set{},
+			"Ends":        codex.Set{},
+		}},
+		{codex.Stats{}, map[string]interface{}{
+			"NSounds":   0,
+			"NPairs":    0,
+			"Branching": float64(0),
+			"Entropy":   float64(0),
+			"NWords":    float64(0),
+		}},
+		{codex.WordError{}, map[string]interface{}{
+			"Word":   "",
+			"Reason": "",
+			"Offset": 0,
+			"Symbol": "",
+		}},
+		{codex.Rule{}, map[string]interface{}{
+			"Pattern": (*regexp.Regexp)(nil),
+			"Replace": "",
+		}},
+		{codex.Checkpoint{}, map[string]interface{}{
+			"Hash":   uint64(0),
+			"Sounds": []string(nil),
+			"Count":  uint64(0),
+		}},
+		{codex.VersionError{}, map[string]interface{}{
+			"Version":   0,
+			"Supported": 0,
+		}},
+		{codex.Recording{}, map[string]interface{}{
+			"Seed": int64(0),
+		}},
+	}
+
+	for _, entry := range manifest {
+		typ := reflect.TypeOf(entry.value)
+		for name, value := range entry.fields {
+			field, ok := typ.FieldByName(name)
+			if !ok {
+				t.Fatalf("%v: missing field %v", typ, name)
+			}
+			if field.Type != reflect.TypeOf(value) {
+				t.Fatalf("%v: expected field %v of type %v, got %v",
+					typ, name, reflect.TypeOf(value), field.Type)
+			}
+		}
+	}
+}
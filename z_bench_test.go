@@ -54,6 +54,17 @@ func Benchmark_Generator_All_LargeDataset(b *testing.B) {
 	}
 }
 
+// Large source dataset -> Traits.Words() on all cores
+func Benchmark_Words_LargeDataset(b *testing.B) {
+	// b.SkipNow()
+
+	traits, _ := NewTraits(testManyWords)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		traits.Words()
+	}
+}
+
 // Traits.Generator() -> generate default count
 func Benchmark_Generator_N(b *testing.B) {
 	// b.SkipNow()
@@ -3,10 +3,26 @@ package codex
 // Tests.
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
 )
 
 /********************************** Globals **********************************/
@@ -154,156 +170,4023 @@ func Test_Generator(t *testing.T) {
 	// t.Log("words in sample:", words)
 }
 
+// Verifies that both memory profiles yield the same words, and that the
+// low-memory profile doesn't intern the transitions of the traits.
+func Test_Traits_GeneratorWith(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	plain, err := NewTraits([]string{"goblin", "smoke"})
+	tmust(t, err)
+	smoothed, err := NewTraitsWithSounds([]string{"nola", "lux"}, Letters("alnoux"))
+	tmust(t, err)
+	smoothed.MaxUnseen = 1
+	smoothed.ForbidPair("l", "a")
+
+	for _, traits := range []*Traits{plain, smoothed} {
+		expected := collectAll(traits)
+		for _, profile := range []MemoryProfile{FastProfile, LowMemoryProfile} {
+			gen := traits.GeneratorWith(profile)
+			words := Set{}
+			for word := gen(); word != ""; word = gen() {
+				words.Add(word)
+			}
+			if !reflect.DeepEqual(words, expected) {
+				t.Fatalf("expected profile %v to yield %v, got %v", profile, expected, words)
+			}
+		}
+	}
+
+	st := &state{traits: plain, compact: true}
+	if st.next() == "" || st.table != nil {
+		t.Fatal("expected a compact state to make words without a table")
+	}
+}
+
 // Verifies that the words returned from a generator match its source traits.
 func Test_Generator_Words_Match_Traits(t *testing.T) {
 	// t.SkipNow()
 
-	Test_Generator(t)
+	Test_Generator(t)
+
+	traits, _ := NewTraits(testLimitedWords)
+	words := collectAll(traits)
+
+	test_Words_Match_Traits(t, traits, words)
+}
+
+// Verifies that NewTraits() produces an error with invalid input.
+func Test_Invalid_Input(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	invalids := []string{
+		"", "a", "CAPITALS", "Capitalised", "with space",
+		"numbers134125", "łàtîñôñè", "кириллица",
+	}
+
+	for _, invalid := range invalids {
+		traits, err := NewTraits([]string{invalid})
+		if traits != nil || err == nil {
+			t.Fatalf("expected nil traits and non-nil error, got %v and %v", traits, err)
+		}
+	}
+}
+
+// Verifies that Traits.Examine() and NewTraits() are equivalent.
+func Test_Traits_Examine(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	traits := new(Traits)
+	tmust(t, traits.Examine(testDefWords))
+
+	other, _ := NewTraits(testDefWords)
+
+	if !reflect.DeepEqual(traits, other) {
+		t.Fatal("expected new(Traits) + Traits.Examine() to be equivalent to NewTraits()")
+	}
+
+	if !reflect.DeepEqual(collectAll(traits), collectAll(other)) {
+		t.Fatal("expected resulting word sets to be equivalent")
+	}
+}
+
+// Verifies that a Traits object uses internal known sounds, if available.
+func Test_Traits_KnownSounds(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Examine(t)
+
+	traits := new(Traits)
+
+	traits.KnownSounds = Set.New(nil,
+		"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m",
+	)
+
+	if reflect.DeepEqual(traits.knownSounds(), knownSounds) {
+		t.Fatal("expected Traits.knownSounds() to return the internal sound set")
+	}
+
+	if traits.Examine(testDefWords) == nil {
+		t.Fatal("expected Traits.Examine() to fail when used with a limited sound set")
+	}
+}
+
+// Verifies that a Traits object uses internal known vowels, if available.
+func Test_Traits_KnownVowels(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+	Test_Traits_Examine(t)
+
+	traits := new(Traits)
+
+	traits.KnownVowels = Set.New(nil,
+		"a", "e", "i",
+	)
+
+	if reflect.DeepEqual(traits.knownVowels(), knownVowels) {
+		t.Fatal("expected Traits.knownVowels() to return the internal vowel set")
+	}
+
+	if traits.Examine(testDefWords) != nil {
+		t.Fatal("expected Traits.Examine() to complete successfully when used with a limited vowel set")
+	}
+
+	other, _ := NewTraits(testDefWords)
+	if reflect.DeepEqual(traits, other) {
+		t.Fatalf("expected Traits.Examine() with custom vowels to produce different traits")
+	}
+
+	if reflect.DeepEqual(collectAll(traits), collectAll(other)) {
+		t.Fatal("expected resulting word sets to be different")
+	}
+}
+
+// Verifies that words from a generator are randomly distributed. Rudimental and
+// naive, todo remember some math and use a real probability function.
+func Test_Generator_Random_Distribution(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, _ := NewTraits(testDefWords)
+
+	// Make a sorted list of words.
+	gen := traits.Generator()
+	unordered := Set{}
+	for word := gen(); word != ""; word = gen() {
+		unordered.Add(word)
+	}
+	words := make([]string, 0, len(unordered))
+	for word := range unordered {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	// Limit of how many tight groups to permit.
+	maxTightGroups := len(words) / testDefCount / 10
+	if maxTightGroups == 0 {
+		maxTightGroups = 1
+	}
+
+	// Counter of tight group occurrences.
+	count := 0
+
+	// Prepare a generator that makes words in chunks.
+	wordsN := generatorN(traits)
+
+	// Loop over generator results and count how many times all indices from
+	// a sample fall within a tight range (let's say 1/5th the length).
+	for sample := wordsN(testDefCount); len(sample) > 0; sample = wordsN(testDefCount) {
+		indices := make([]int, 0, len(sample))
+		for word := range sample {
+			indices = append(indices, findIndex(words, word))
+		}
+		if maximum(indices)-minimum(indices) < len(words)/5 {
+			count++
+		}
+	}
+
+	if count > maxTightGroups {
+		t.Fatalf("for %v sorted words, %v out of %v samples were tightly grouped", len(words), count, len(words)/testDefCount+1)
+	}
+}
+
+// Verifies that Traits.GeneratorFrom() yields exactly the words that begin with
+// the given pair.
+func Test_Traits_GeneratorFrom(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, _ := NewTraits(testDefWords)
+	all := collectAll(traits)
+
+	for start := range traits.Starts() {
+		expected := Set{}
+		for word := range all {
+			sounds, err := getSounds(word, traits.knownSounds())
+			tmust(t, err)
+			if sounds[0] == start[0] && sounds[1] == start[1] {
+				expected.Add(word)
+			}
+		}
+
+		words := Set{}
+		gen := traits.GeneratorFrom(start)
+		for word := gen(); word != ""; word = gen() {
+			if words.Has(word) {
+				t.Fatal("repeated output from generator:", word)
+			}
+			words.Add(word)
+		}
+
+		if !reflect.DeepEqual(words, expected) {
+			t.Fatalf("mismatch for start %v: expected %v, got %v", start, expected, words)
+		}
+	}
+
+	if traits.GeneratorFrom([2]string{"z", "z"})() != "" {
+		t.Fatal("expected no output for an unknown start")
+	}
+}
+
+// Verifies that Traits.GeneratorTo() yields exactly the words that end with the
+// given pair.
+func Test_Traits_GeneratorTo(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, _ := NewTraits(testDefWords)
+	all := collectAll(traits)
+
+	for end := range traits.Ends() {
+		expected := Set{}
+		for word := range all {
+			sounds, err := getSounds(word, traits.knownSounds())
+			tmust(t, err)
+			if sounds[len(sounds)-2] == end[0] && sounds[len(sounds)-1] == end[1] {
+				expected.Add(word)
+			}
+		}
+
+		words := Set{}
+		gen := traits.GeneratorTo(end)
+		for word := gen(); word != ""; word = gen() {
+			if words.Has(word) {
+				t.Fatal("repeated output from generator:", word)
+			}
+			words.Add(word)
+		}
+
+		if !reflect.DeepEqual(words, expected) {
+			t.Fatalf("mismatch for end %v: expected %v, got %v", end, expected, words)
+		}
+	}
+
+	if traits.GeneratorTo([2]string{"z", "z"})() != "" {
+		t.Fatal("expected no output for an unknown end")
+	}
+}
+
+// Verifies that Traits.Starts() and Traits.Ends() only have pairs that begin or
+// end at least one word.
+func Test_Traits_Starts_Ends(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_GeneratorFrom(t)
+	Test_Traits_GeneratorTo(t)
+
+	traits, _ := NewTraits([]string{"nebula", "aurora", "quasar", "lyre"})
+	traits.MaxNSounds = 4
+	traits.MinNVowels = 3
+
+	starts, ends := traits.Starts(), traits.Ends()
+	for start := range starts {
+		if traits.GeneratorFrom(start)() == "" {
+			t.Fatalf("expected words beginning with %q", start)
+		}
+	}
+	for end := range ends {
+		if traits.GeneratorTo(end)() == "" {
+			t.Fatalf("expected words ending with %q", end)
+		}
+	}
+
+	// Short words with three vowels never begin with "qu", and never end with it.
+	if starts.Has([2]string{"q", "u"}) || ends.Has([2]string{"q", "u"}) {
+		t.Fatalf("expected no pairs that can't reach a word, got %v and %v", starts, ends)
+	}
+}
+
+// Verifies that NameN() makes unique names whose parts are related on request.
+func Test_NameN(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	first, _ := NewTraits(testDefWords)
+	last, _ := NewTraits(testLimitedWords)
+
+	names := NameN(first, last, testDefCount, true)
+	if len(names) != testDefCount {
+		t.Fatalf("expected %v names, got %v", testDefCount, len(names))
+	}
+
+	seen := Set{}
+	for _, name := range names {
+		if seen.Has(name[0]) {
+			t.Fatal("repeated first name:", name[0])
+		}
+		seen.Add(name[0])
+		if !sharesSound(first.soundSet(name[0]), last.soundSet(name[1])) {
+			t.Fatalf("expected related name parts, got %v", name)
+		}
+	}
+
+	for _, n := range []int{0, -1} {
+		if names := NameN(first, last, n, true); len(names) != 0 {
+			t.Fatalf("expected no names for %v, got %v", n, names)
+		}
+	}
+}
+
+// Verifies the junction rules of CompoundGenerator().
+func Test_CompoundGenerator(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	head, _ := NewTraits([]string{"oak", "ash"})
+	tail, _ := NewTraits([]string{"ford", "kin"})
+
+	if word := compound(head, tail, "oak", "kin", "e"); word != "oakin" {
+		t.Fatalf("expected a repeated boundary sound to be dropped, got %v", word)
+	}
+	if word := compound(head, tail, "oak", "ford", "e"); word != "oakeford" {
+		t.Fatalf("expected a linking vowel between consonants, got %v", word)
+	}
+	if word := compound(head, tail, "oak", "ford", ""); word != "oakford" {
+		t.Fatalf("expected no linking vowel when disabled, got %v", word)
+	}
+
+	gen := CompoundGenerator(head, tail, "e")
+	words := Set{}
+	for word := gen(); word != ""; word = gen() {
+		if words.Has(word) {
+			t.Fatal("repeated output from generator:", word)
+		}
+		words.Add(word)
+	}
+	if len(words) == 0 {
+		t.Fatal("no output received from generator")
+	}
+
+	// Exhaustion only comes after every head is joined with every tail.
+	expected := Set{}
+	for first := range collectAll(head) {
+		for second := range collectAll(tail) {
+			expected.Add(compound(head, tail, first, second, "e"))
+		}
+	}
+	if !reflect.DeepEqual(words, expected) {
+		t.Fatalf("expected every combination of stems\nexpected: %v\ngot: %v", expected, words)
+	}
+}
+
+// Verifies that the JSON encoding of traits round-trips and is canonical.
+func Test_Traits_JSON(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	traits, _ := NewTraits(testDefWords)
+	input, err := json.Marshal(traits)
+	tmust(t, err)
+
+	decoded := new(Traits)
+	tmust(t, json.Unmarshal(input, decoded))
+	if !reflect.DeepEqual(traits, decoded) {
+		t.Fatalf("expected decoded traits to equal the original: %#v vs %#v", traits, decoded)
+	}
+
+	// Reversing the sample order changes map insertion order, but must not
+	// change the encoding.
+	reversed := make([]string, 0, len(testDefWords))
+	for index := len(testDefWords) - 1; index >= 0; index-- {
+		reversed = append(reversed, testDefWords[index])
+	}
+	other, _ := NewTraits(reversed)
+	for i := 0; i < 8; i++ {
+		output, err := json.Marshal(other)
+		tmust(t, err)
+		if string(output) != string(input) {
+			t.Fatalf("expected canonical encoding, got:\n%s\n%s", input, output)
+		}
+	}
+}
+
+// Verifies that the binary encoding of traits round-trips, doesn't depend on
+// the order of the sample, is smaller than JSON, and rejects garbage.
+func Test_Traits_Binary(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	input, err := traits.MarshalBinary()
+	tmust(t, err)
+
+	decoded := new(Traits)
+	tmust(t, decoded.UnmarshalBinary(input))
+	if !reflect.DeepEqual(traits, decoded) {
+		t.Fatalf("expected decoded traits to equal the original: %#v vs %#v", traits, decoded)
+	}
+
+	reversed := make([]string, 0, len(testDefWords))
+	for index := len(testDefWords) - 1; index >= 0; index-- {
+		reversed = append(reversed, testDefWords[index])
+	}
+	other, err := NewTraits(reversed)
+	tmust(t, err)
+	output, err := other.MarshalBinary()
+	tmust(t, err)
+	decoded = new(Traits)
+	tmust(t, decoded.UnmarshalBinary(output))
+	if decoded.Hash() != traits.Hash() {
+		t.Fatalf("expected traits of the same words in any order to decode equal")
+	}
+
+	// The savings show on samples big enough to outweigh the fixed overhead of
+	// the format.
+	rnd := rand.New(rand.NewSource(1))
+	words := make([]string, 2000)
+	for index := range words {
+		var word []byte
+		for len(word) < 6 {
+			word = append(word, "abcdefghijklmnopqrstuvwxyz"[rnd.Intn(26)])
+		}
+		words[index] = string(word)
+	}
+	big, err := NewTraits(words)
+	tmust(t, err)
+	input, err = big.MarshalBinary()
+	tmust(t, err)
+	encoded, err := json.Marshal(big)
+	tmust(t, err)
+	if len(input) >= len(encoded) {
+		t.Fatalf("expected the binary encoding to be smaller than JSON, got %v and %v bytes",
+			len(input), len(encoded))
+	}
+
+	if decoded.UnmarshalBinary([]byte("garbage")) == nil {
+		t.Fatalf("expected garbage to fail to decode")
+	}
+}
+
+// Verifies that model files round-trip, and that other input and files of
+// newer versions are rejected with clear errors.
+func Test_SaveModel(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Binary(t)
+
+	traits, _ := NewTraits(testDefWords)
+	var buf bytes.Buffer
+	tmust(t, traits.SaveModel(&buf))
+	file := buf.Bytes()
+
+	loaded, err := LoadModel(bytes.NewReader(file))
+	tmust(t, err)
+	if !reflect.DeepEqual(loaded, traits) {
+		t.Fatalf("expected loaded traits to equal the saved ones: %#v vs %#v", traits, loaded)
+	}
+
+	for _, input := range []string{"", "CDX", "{\"SoundSet\": []}"} {
+		if _, err := LoadModel(strings.NewReader(input)); err != ErrNotModel {
+			t.Fatalf("expected ErrNotModel for input %q, got: %v", input, err)
+		}
+	}
+
+	newer := append([]byte(nil), file...)
+	newer[len(modelMagic)+1] = modelVersion + 1
+	_, err = LoadModel(bytes.NewReader(newer))
+	var versionErr *VersionError
+	if !errors.As(err, &versionErr) || versionErr.Version != modelVersion+1 {
+		t.Fatalf("expected a version error, got: %v", err)
+	}
+
+	if _, err := LoadModel(bytes.NewReader(file[:len(file)/2])); err == nil {
+		t.Fatalf("expected a truncated model file to fail to load")
+	}
+
+	failure := errors.New("failure")
+	if _, err := LoadModel(iotest.ErrReader(failure)); err != failure {
+		t.Fatalf("expected the error of the reader, got: %v", err)
+	}
+}
+
+// Verifies that the protobuf encoding of traits follows the wire format,
+// round-trips, skips unknown fields and rejects malformed input.
+func Test_Traits_Proto(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+
+	// Field 1 as a varint, then field 8 as a Pair message.
+	output, err := Traits{MinNSounds: 300, PairSet: PairSet.New(nil, [2]string{"a", "b"})}.MarshalProto()
+	tmust(t, err)
+	expected := []byte{0x08, 0xac, 0x02, 0x42, 0x06, 0x0a, 0x01, 'a', 0x12, 0x01, 'b'}
+	if !bytes.Equal(output, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, output)
+	}
+
+	traits, _ := NewTraits(testDefWords)
+	input, err := traits.MarshalProto()
+	tmust(t, err)
+	decoded := new(Traits)
+	tmust(t, decoded.UnmarshalProto(input))
+	if !reflect.DeepEqual(traits, decoded) {
+		t.Fatalf("expected decoded traits to equal the original: %#v vs %#v", traits, decoded)
+	}
+
+	other, _ := NewTraits(testDefWords)
+	output, err = other.MarshalProto()
+	tmust(t, err)
+	if !bytes.Equal(output, input) {
+		t.Fatalf("expected canonical encoding")
+	}
+
+	// Field 99 of each wire type, as added by a newer schema.
+	newer := append(append([]byte(nil), input...),
+		0x98, 0x06, 0x01,
+		0x99, 0x06, 1, 2, 3, 4, 5, 6, 7, 8,
+		0x9a, 0x06, 0x02, 'h', 'i',
+		0x9d, 0x06, 1, 2, 3, 4,
+	)
+	decoded = new(Traits)
+	tmust(t, decoded.UnmarshalProto(newer))
+	if !reflect.DeepEqual(traits, decoded) {
+		t.Fatalf("expected unknown fields to be skipped")
+	}
+
+	for _, input := range [][]byte{
+		input[:len(input)-1],
+		{0x08},
+		{0x00, 0x01},
+		{0x0b},
+		{0x3a, 0x05, 'a'},
+		{0xc2, 0x01, 0x03, 0x0a, 0x01, '('},
+	} {
+		if decoded.UnmarshalProto(input) == nil {
+			t.Fatalf("expected %#v to fail to decode", input)
+		}
+	}
+}
+
+// Verifies that traits convert to and from the JSON shape of foliant, in
+// either shape of sets, and that malformed input is rejected.
+func Test_Traits_Foliant(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+
+	traits, _ := NewTraits(testDefWords)
+	input, err := traits.MarshalFoliant()
+	tmust(t, err)
+	imported, err := NewTraitsFromFoliant(input)
+	tmust(t, err)
+	expected := &Traits{
+		MinNSounds:    traits.MinNSounds,
+		MaxNSounds:    traits.MaxNSounds,
+		MinNVowels:    traits.MinNVowels,
+		MaxNVowels:    traits.MaxNVowels,
+		MaxConseqVow:  traits.MaxConseqVow,
+		MaxConseqCons: traits.MaxConseqCons,
+		SoundSet:      traits.SoundSet,
+		PairSet:       traits.PairSet,
+	}
+	if !reflect.DeepEqual(imported, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, imported)
+	}
+
+	output, err := Traits{}.MarshalFoliant()
+	tmust(t, err)
+	expectedJSON := `{"minNSounds":0,"maxNSounds":0,"minNVowels":0,"maxNVowels":0,` +
+		`"maxConseqVow":0,"maxConseqCons":0,"soundSet":[],"pairSet":[]}`
+	if string(output) != expectedJSON {
+		t.Fatalf("expected %v, got %s", expectedJSON, output)
+	}
+
+	imported, err = NewTraitsFromFoliant([]byte(`{
+		"minNSounds": 2, "maxNSounds": 3, "minNVowels": 1, "maxNVowels": 2,
+		"maxConseqVow": 1, "maxConseqCons": 1,
+		"soundSet": {"a": true, "ŋ": true, "x": false},
+		"pairSet": {"a": {"ŋ": true}, "ŋ": ["a"]}
+	}`))
+	tmust(t, err)
+	if !reflect.DeepEqual(imported.SoundSet, Set.New(nil, "a", "ŋ")) {
+		t.Fatalf("unexpected sounds: %#v", imported.SoundSet)
+	}
+	if !reflect.DeepEqual(imported.PairSet, PairSet.New(nil, [2]string{"a", "ŋ"}, [2]string{"ŋ", "a"})) {
+		t.Fatalf("unexpected pairs: %#v", imported.PairSet)
+	}
+	if !imported.KnownSounds.Has("ŋ") || !imported.KnownSounds.Has("b") {
+		t.Fatalf("expected the unknown sound to be added to the default sounds: %#v", imported.KnownSounds)
+	}
+
+	for _, input := range []string{
+		`[]`,
+		`{"soundSet": "a", "pairSet": []}`,
+		`{"soundSet": ["a"], "pairSet": [["a"]]}`,
+		`{"maxNSounds": 2, "soundSet": ["a", "b"], "pairSet": [["a", "c"]]}`,
+	} {
+		if _, err := NewTraitsFromFoliant([]byte(input)); err == nil {
+			t.Fatalf("expected %v to fail to import", input)
+		}
+	}
+}
+
+// Verifies that the text dump of traits round-trips and rejects garbage.
+func Test_Traits_Dump(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	traits, _ := NewTraits(testDefWords)
+	input, err := traits.MarshalText()
+	tmust(t, err)
+
+	decoded := new(Traits)
+	tmust(t, decoded.UnmarshalText(input))
+	if !reflect.DeepEqual(traits, decoded) {
+		t.Fatalf("expected parsed traits to equal the original: %#v vs %#v", traits, decoded)
+	}
+
+	for _, invalid := range []string{"pair a", "sound", "min-sounds x", "unknown 1"} {
+		if decoded.UnmarshalText([]byte(invalid)) == nil {
+			t.Fatalf("expected an error when parsing %q", invalid)
+		}
+	}
+}
+
+// Verifies that Traits.Mutate() returns distinct derivable variants.
+func Test_Traits_Mutate(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, _ := NewTraits(testDefWords)
+
+	variants, err := traits.Mutate("nebula", testDefCount)
+	tmust(t, err)
+	if len(variants) == 0 || len(variants) > testDefCount {
+		t.Fatalf("expected between 1 and %v variants, got %v", testDefCount, len(variants))
+	}
+	if variants.Has("nebula") {
+		t.Fatal("expected the original word to be excluded")
+	}
+
+	all := collectAll(traits)
+	for word := range variants {
+		if !all.Has(word) {
+			t.Fatal("expected a derivable variant, got:", word)
+		}
+	}
+
+	if _, err := traits.Mutate("кириллица", testDefCount); err == nil {
+		t.Fatal("expected an error for a word with unknown sounds")
+	}
+
+	for _, n := range []int{0, -1} {
+		variants, err := traits.Mutate("nebula", n)
+		tmust(t, err)
+		if len(variants) != 0 {
+			t.Fatalf("expected no variants for n = %d, got %#v", n, variants)
+		}
+	}
+}
+
+// Verifies that manual editing keeps traits consistent.
+func Test_Traits_Edit(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	traits, _ := NewTraits([]string{"goblin", "smoke"})
+	tmust(t, traits.Validate())
+
+	tmust(t, traits.AddPair([2]string{"n", "z"}))
+	if !traits.PairSet.Has([2]string{"n", "z"}) || !traits.SoundSet.Has("z") {
+		t.Fatal("expected the pair and its sounds to be added")
+	}
+	tmust(t, traits.Validate())
+
+	if traits.AddPair([2]string{"n", "ж"}) == nil {
+		t.Fatal("expected an error when adding a pair with an unknown sound")
+	}
+
+	traits.DelPair([2]string{"n", "z"})
+	if traits.SoundSet.Has("z") {
+		t.Fatal("expected an orphaned sound to be deleted")
+	}
+	tmust(t, traits.Validate())
+
+	traits.DelSound("o")
+	for pair := range traits.PairSet {
+		if pair[0] == "o" || pair[1] == "o" {
+			t.Fatal("expected pairs with a deleted sound to be deleted, found:", pair)
+		}
+	}
+	tmust(t, traits.Validate())
+
+	traits.ForbidPair("g", "o")
+	if traits.AddPair([2]string{"g", "o"}) == nil || traits.PairSet.Has([2]string{"g", "o"}) {
+		t.Fatal("expected an error when adding a forbidden pair")
+	}
+
+	// Added pairs of positional traits may occur anywhere in words.
+	positional := &Traits{Positional: true}
+	tmust(t, positional.Examine([]string{"goblin", "smoke"}))
+	tmust(t, positional.AddPair([2]string{"i", "z"}))
+	tmust(t, positional.Validate())
+	if words := collectAll(positional); !words.Has("gobliz") {
+		t.Fatal("expected words with the added pair of positional traits")
+	}
+
+	traits.SoundSet.Add("x")
+	if traits.Validate() == nil {
+		t.Fatal("expected an error for a sound that doesn't occur in any pair")
+	}
+}
+
+// Verifies that Blend() makes hybrids that begin like one word and end like
+// the other.
+func Test_Blend(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_GeneratorFrom(t)
+	Test_Traits_GeneratorTo(t)
+
+	words, err := Blend("nebula", "aurora", testDefCount)
+	tmust(t, err)
+	if len(words) == 0 {
+		t.Fatal("expected at least one hybrid")
+	}
+	for word := range words {
+		if word == "nebula" || word == "aurora" {
+			t.Fatal("expected the original words to be excluded, got:", word)
+		}
+		if word[:2] != "ne" || word[len(word)-2:] != "ra" {
+			t.Fatal("expected a hybrid of both words, got:", word)
+		}
+	}
+
+	if _, err := Blend("nebula", "кириллица", testDefCount); err == nil {
+		t.Fatal("expected an error for an invalid word")
+	}
+}
+
+// Verifies that generators apply the traits' casing.
+func Test_Traits_Casing(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, _ := NewTraits(testDefWords)
+	lower := collectAll(traits)
+
+	for casing, transform := range map[Casing]func(string) string{
+		TitleCase: func(word string) string { return strings.ToUpper(word[:1]) + word[1:] },
+		UpperCase: strings.ToUpper,
+	} {
+		traits.Casing = casing
+		expected := Set{}
+		for word := range lower {
+			expected.Add(transform(word))
+		}
+		if words := collectAll(traits); !reflect.DeepEqual(words, expected) {
+			t.Fatalf("casing %v mismatch: expected %v, got %v", casing, expected, words)
+		}
+
+		text, err := traits.MarshalText()
+		tmust(t, err)
+		decoded := new(Traits)
+		tmust(t, decoded.UnmarshalText(text))
+		if decoded.Casing != casing {
+			t.Fatalf("expected casing %v to survive the text dump, got %v", casing, decoded.Casing)
+		}
+	}
+
+	if TitleCase.apply("łatin") != "Łatin" {
+		t.Fatal("expected title casing to handle multi-byte letters")
+	}
+}
+
+// Verifies that DetectCasing() finds the casing that most words follow.
+func Test_DetectCasing(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Casing(t)
+
+	for _, test := range []struct {
+		words    []string
+		expected Casing
+	}{
+		{nil, LowerCase},
+		{[]string{"goblin", "smoke"}, LowerCase},
+		{[]string{"Goblin", "Smoke", "orc"}, TitleCase},
+		{[]string{"Łatin", "McLeod", "ORC"}, TitleCase},
+		{[]string{"GOBLIN", "SMOKE", "Orc"}, UpperCase},
+		{[]string{"Goblin", "smoke"}, LowerCase},
+		{[]string{"Goblin", "SMOKE"}, TitleCase},
+		{[]string{"o'neil", "O'Neil", "O'NEIL"}, LowerCase},
+	} {
+		if casing := DetectCasing(test.words); casing != test.expected {
+			t.Fatalf("expected casing %v for %v, got %v", test.expected, test.words, casing)
+		}
+	}
+}
+
+// Verifies that traits imported from other formats reproduce their words.
+func Test_Import(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, err := NewTraitsFromTable([]string{"kal", "mor"}, []string{"a"}, []string{"dor", "in"})
+	tmust(t, err)
+	words := collectAll(traits)
+	for _, word := range []string{"kaldor", "morin", "kalain", "morador"} {
+		if !words.Has(word) {
+			t.Fatal("expected imported traits to derive the table word:", word)
+		}
+	}
+
+	traits, err = NewTraitsFromSyllables([]string{"ka", "ri", "mo"})
+	tmust(t, err)
+	words = collectAll(traits)
+	for _, word := range []string{"kari", "moka", "rimo"} {
+		if !words.Has(word) {
+			t.Fatal("expected imported traits to derive the syllable word:", word)
+		}
+	}
+
+	if _, err := NewTraitsFromSyllables([]string{"ка"}); err == nil {
+		t.Fatal("expected an error for invalid syllables")
+	}
+}
+
+// Verifies syllabification and the fidelity report of Traits.SyllableTable().
+func Test_Traits_SyllableTable(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Import(t)
+
+	traits, _ := NewTraits(testDefWords)
+
+	for word, expected := range map[string][]string{
+		"nebula": {"ne", "bu", "la"},
+		"thorax": {"tho", "rax"},
+		"quasar": {"qua", "sar"},
+		"go":     {"go"},
+	} {
+		sounds, _ := getSounds(word, traits.knownSounds())
+		if syllables := traits.syllables(sounds); !reflect.DeepEqual(syllables, expected) {
+			t.Fatalf("expected %q to split into %v, got %v", word, expected, syllables)
+		}
+	}
+
+	table, fidelity := traits.SyllableTable(1000)
+	if len(table.Prefixes) == 0 || len(table.Suffixes) == 0 {
+		t.Fatal("expected a non-empty table")
+	}
+	if fidelity.NWords == 0 || fidelity.NWords > 1000 {
+		t.Fatal("unexpected number of words:", fidelity.NWords)
+	}
+	for pair := range fidelity.MissingPairs {
+		if !traits.PairSet.Has(pair) {
+			t.Fatal("expected missing pairs to come from the traits, got:", pair)
+		}
+	}
+	for pair := range fidelity.ExtraPairs {
+		if traits.PairSet.Has(pair) {
+			t.Fatal("expected extra pairs to be absent from the traits, got:", pair)
+		}
+	}
+}
+
+// Verifies that Traits.UniformGenerator() yields every word exactly once and
+// draws words with equal probability.
+func Test_Traits_UniformGenerator(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, _ := NewTraits(testDefWords)
+	words := Set{}
+	gen := traits.UniformGenerator()
+	for word := gen(); word != ""; word = gen() {
+		if words.Has(word) {
+			t.Fatal("repeated output from generator:", word)
+		}
+		words.Add(word)
+	}
+	if !reflect.DeepEqual(words, collectAll(traits)) {
+		t.Fatal("expected the uniform generator to yield the complete word set")
+	}
+
+	// Tally the first words of many generators over a small word set.
+	traits, _ = NewTraits([]string{"goblin", "smoke"})
+	total := len(collectAll(traits))
+	const draws = 7000
+	counts := map[string]int{}
+	for i := 0; i < draws; i++ {
+		counts[traits.UniformGenerator()()]++
+	}
+	if len(counts) != total {
+		t.Fatalf("expected all %v words to be drawn, got %v", total, counts)
+	}
+	expected := draws / total
+	for word, count := range counts {
+		if count < expected*3/4 || count > expected*5/4 {
+			t.Fatalf("word %q drawn %v times, expected about %v", word, count, expected)
+		}
+	}
+}
+
+// Verifies that Traits.Matrix() reflects the pairs of the traits.
+func Test_Traits_Matrix(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	traits, _ := NewTraits(testDefWords)
+	matrix := traits.Matrix()
+
+	pairs := PairSet{}
+	for sound, successors := range matrix.Transitions {
+		for successor, weight := range successors {
+			if weight <= 0 {
+				t.Fatalf("expected a positive weight for %v>%v, got %v", sound, successor, weight)
+			}
+			if !isBoundary([2]string{sound, successor}) {
+				pairs.Add([2]string{sound, successor})
+			}
+		}
+	}
+	if !reflect.DeepEqual(pairs, traits.PairSet) {
+		t.Fatal("expected the transitions to match the pairs of the traits")
+	}
+
+	if !matrix.Starts.Has("n") || !matrix.Ends.Has("a") {
+		t.Fatal("expected start and end sounds of the sample to be included")
+	}
+	if len(matrix.Transitions[StartToken]) != len(matrix.Starts) {
+		t.Fatal("expected a transition from the start token to each start sound")
+	}
+	if weight := matrix.Transitions["a"][EndToken]; weight != 2 {
+		t.Fatalf("expected the transition to the end token to be weighted by the sample, got %v", weight)
+	}
+}
+
+// Verifies that Traits.TopK() returns the most likely words in order.
+func Test_Traits_TopK(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Matrix(t)
+
+	traits, _ := NewTraits(testDefWords)
+	chances := traits.chances()
+
+	// Brute force: score every word and sort.
+	var all []candidate
+	for word := range collectAll(traits) {
+		sounds, _ := getSounds(word, traits.knownSounds())
+		all = append(all, candidate{word: word, score: chances.logLikelihood(sounds)})
+	}
+	sortCandidates(all)
+
+	top := traits.TopK(testDefCount)
+	if len(top) != testDefCount {
+		t.Fatalf("expected %v words, got %v", testDefCount, len(top))
+	}
+	for index, word := range top {
+		if word != all[index].word {
+			t.Fatalf("expected word #%v to be %q, got %q", index, all[index].word, word)
+		}
+	}
+
+	if !reflect.DeepEqual(top, traits.TopK(testDefCount)) {
+		t.Fatal("expected deterministic output")
+	}
+}
+
+// Verifies that Traits.WordsN() returns exactly n words or an error.
+func Test_Traits_WordsN(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, _ := NewTraits(testDefWords)
+	words, err := traits.WordsN(testDefCount)
+	tmust(t, err)
+	if len(words) != testDefCount {
+		t.Fatalf("expected %v words, got %v", testDefCount, len(words))
+	}
+
+	total := len(collectAll(traits))
+	words, err = traits.WordsN(total + 1)
+	countErr, ok := err.(*CountError)
+	if !ok {
+		t.Fatalf("expected a *CountError, got %#v", err)
+	}
+	if countErr.Available != total || len(words) != total {
+		t.Fatalf("expected %v available words, got %v and %v", total, countErr.Available, len(words))
+	}
+}
+
+// Verifies that Traits.WriteWordsN() writes exactly n distinct words, or all
+// of them with an error, and stops at the first error of writing.
+func Test_Traits_WriteWordsN(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_WordsN(t)
+
+	traits, _ := NewTraits(testDefWords)
+	var buf strings.Builder
+	tmust(t, traits.WriteWordsN(&buf, testDefCount, "\n"))
+	words := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(words) != testDefCount || len(Set.New(nil, words...)) != testDefCount {
+		t.Fatalf("expected %v distinct words, got: %#v", testDefCount, words)
+	}
+
+	total := len(collectAll(traits))
+	buf.Reset()
+	err := traits.WriteWordsN(&buf, total+1, ",")
+	countErr, ok := err.(*CountError)
+	if !ok || countErr.Available != total {
+		t.Fatalf("expected a *CountError with %v available words, got %#v", total, err)
+	}
+	if count := strings.Count(buf.String(), ","); count != total {
+		t.Fatalf("expected %v words to be written, got %v", total, count)
+	}
+
+	writer := &failingWriter{limit: 3}
+	if err := traits.WriteWordsN(writer, testDefCount, "\n"); err != errWrite {
+		t.Fatalf("expected the error of the writer, got: %v", err)
+	}
+	if writer.count != 4 {
+		t.Fatalf("expected writing to stop at the first error, got %v writes", writer.count)
+	}
+}
+
+// Verifies that Traits.WriteCSV() writes a header and a row per word with its
+// score and length, in CSV or TSV.
+func Test_Traits_WriteCSV(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_WriteWordsN(t)
+
+	traits, _ := NewTraits(testDefWords)
+	for _, comma := range []rune{',', '\t'} {
+		var buf bytes.Buffer
+		tmust(t, traits.WriteCSV(&buf, testDefCount, comma))
+
+		reader := csv.NewReader(&buf)
+		reader.Comma = comma
+		rows, err := reader.ReadAll()
+		tmust(t, err)
+		if len(rows) != testDefCount+1 {
+			t.Fatalf("expected a header and %v rows, got %v rows", testDefCount, len(rows))
+		}
+		if !reflect.DeepEqual(rows[0], []string{"word", "score", "length"}) {
+			t.Fatalf("unexpected header: %#v", rows[0])
+		}
+		for _, row := range rows[1:] {
+			score, err := strconv.ParseFloat(row[1], 64)
+			tmust(t, err)
+			if math.Abs(score-traits.Score(row[0])) > 0.0001 {
+				t.Fatalf("expected score %v for %q, got %v", traits.Score(row[0]), row[0], score)
+			}
+			if row[2] != strconv.Itoa(len([]rune(row[0]))) {
+				t.Fatalf("unexpected length %v for %q", row[2], row[0])
+			}
+		}
+	}
+
+	total := len(collectAll(traits))
+	var buf bytes.Buffer
+	err := traits.WriteCSV(&buf, total+1, ',')
+	if countErr, ok := err.(*CountError); !ok || countErr.Available != total {
+		t.Fatalf("expected a *CountError with %v available words, got %#v", total, err)
+	}
+	if count := strings.Count(buf.String(), "\n"); count != total+1 {
+		t.Fatalf("expected a header and %v rows, got %v lines", total, count)
+	}
+
+	if traits.WriteCSV(&buf, 1, '"') == nil {
+		t.Fatalf("expected an invalid delimiter to fail")
+	}
+	if err := traits.WriteCSV(&failingWriter{}, testDefCount, ','); err != errWrite {
+		t.Fatalf("expected the error of the writer, got: %v", err)
+	}
+}
+
+// Verifies that Traits.WordsTo() passes every word to the sink in sorted
+// order, and that file sinks write them, compressed or not.
+func Test_Traits_WordsTo(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_WriteWordsN(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	expected := collectAll(traits)
+	sorted := collectGen(traits.SortedGenerator())
+
+	for _, compress := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "words")
+		sink, err := NewFileSink(path, compress)
+		tmust(t, err)
+		tmust(t, traits.WordsTo(sink))
+		tmust(t, sink.Close())
+
+		file, err := os.Open(path)
+		tmust(t, err)
+		defer file.Close()
+		var reader io.Reader = file
+		if compress {
+			reader, err = gzip.NewReader(file)
+			tmust(t, err)
+		}
+		words, err := LoadWords(reader)
+		tmust(t, err)
+		if !reflect.DeepEqual(Set.New(nil, words...), expected) {
+			t.Fatalf("expected the file to have every word, got %v of %v", len(words), len(expected))
+		}
+		if !reflect.DeepEqual(words, sorted) {
+			t.Fatalf("expected the words in sorted order")
+		}
+	}
+
+	if _, err := NewFileSink(filepath.Join(t.TempDir(), "missing", "words"), false); err == nil {
+		t.Fatalf("expected an error for a missing directory")
+	}
+
+	sink := &failingSink{limit: 3}
+	if err := traits.WordsTo(sink); err != errWrite {
+		t.Fatalf("expected the error of the sink, got: %v", err)
+	}
+	if sink.count != 4 {
+		t.Fatalf("expected the enumeration to stop at the first error, got %v words", sink.count)
+	}
+}
+
+// Verifies that an enumeration interrupted at any point and resumed from its
+// checkpoint returns the same words as the sorted generator, and that
+// checkpoints of other traits are rejected.
+func Test_Traits_Resume(t *testing.T) {
+	// t.SkipNow()
+
+	Test_SortedGenerator(t)
+
+	traits, _ := NewTraits(testDefWords)
+	expected := collectGen(traits.SortedGenerator())
+
+	enum := traits.Enumerate()
+	var words []string
+	for word := enum.Next(); word != ""; word = enum.Next() {
+		words = append(words, word)
+
+		// Simulates a restart after every word, through JSON.
+		input, err := json.Marshal(enum.Checkpoint())
+		tmust(t, err)
+		var checkpoint Checkpoint
+		tmust(t, json.Unmarshal(input, &checkpoint))
+		enum, err = traits.Resume(checkpoint)
+		tmust(t, err)
+	}
+	if !reflect.DeepEqual(words, expected) {
+		t.Fatalf("expected the resumed enumeration to match the sorted generator")
+	}
+	if count := enum.Checkpoint().Count; count != uint64(len(expected)) {
+		t.Fatalf("expected the checkpoint to count %v words, got %v", len(expected), count)
+	}
+	if enum.Next() != "" {
+		t.Fatalf("expected the enumeration to stay exhausted")
+	}
+
+	resumed, err := traits.Resume(Checkpoint{Hash: traits.Hash()})
+	tmust(t, err)
+	if word := resumed.Next(); word != expected[0] {
+		t.Fatalf("expected an empty checkpoint to start over, got %q", word)
+	}
+
+	other, _ := NewTraits([]string{"goblin", "smoke"})
+	if _, err := other.Resume(enum.Checkpoint()); err == nil {
+		t.Fatalf("expected a checkpoint of other traits to be rejected")
+	}
+	if _, err := traits.Resume(Checkpoint{Hash: traits.Hash(), Sounds: []string{"q", "q"}}); err == nil {
+		t.Fatalf("expected a checkpoint with an underived word to be rejected")
+	}
+}
+
+// Verifies that Traits.ImportMatrix() reverses Traits.Matrix().
+func Test_Traits_ImportMatrix(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Matrix(t)
+
+	traits, _ := NewTraits(testDefWords)
+
+	imported := &Traits{
+		MinNSounds:    traits.MinNSounds,
+		MaxNSounds:    traits.MaxNSounds,
+		MinNVowels:    traits.MinNVowels,
+		MaxNVowels:    traits.MaxNVowels,
+		MaxConseqVow:  traits.MaxConseqVow,
+		MaxConseqCons: traits.MaxConseqCons,
+		LengthCount:   traits.LengthCount,
+	}
+	tmust(t, imported.ImportMatrix(traits.Matrix()))
+	if !reflect.DeepEqual(imported.Matrix(), traits.Matrix()) {
+		t.Fatal("expected imported traits to have the original matrix")
+	}
+
+	// Start and end sounds that didn't begin or end sample words count once,
+	// which the matrix makes explicit.
+	imported.BoundaryCount = traits.BoundaryCount
+	if !reflect.DeepEqual(imported, traits) {
+		t.Fatalf("expected imported traits to equal the original: %#v vs %#v", imported, traits)
+	}
+
+	invalid := Matrix{Transitions: map[string]map[string]int{"a": {"ж": 1}}}
+	if imported.ImportMatrix(invalid) == nil {
+		t.Fatal("expected an error for an unknown sound")
+	}
+}
+
+// Verifies that Variations() returns distinct near-misses of a word.
+func Test_Variations(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	words, err := Variations("nebula", testDefCount)
+	tmust(t, err)
+	if len(words) != testDefCount {
+		t.Fatalf("expected %v variations, got %v", testDefCount, len(words))
+	}
+	if words.Has("nebula") {
+		t.Fatal("expected the original word to be excluded")
+	}
+
+	if _, err := Variations("кириллица", testDefCount); err == nil {
+		t.Fatal("expected an error for an invalid word")
+	}
+
+	seed, err := NewTraits([]string{"nebula"})
+	tmust(t, err)
+	all, err := Variations("nebula", 1<<20)
+	tmust(t, err)
+	unseen := false
+	for word := range all {
+		sounds, err := getSounds(word, seed.knownSounds())
+		tmust(t, err)
+		for pair := range getPairs(sounds) {
+			if !seed.PairSet.Has(pair) && !seed.PairSet.Has([2]string{pair[1], pair[0]}) {
+				unseen = true
+			}
+		}
+	}
+	if !unseen {
+		t.Fatal("expected some variation to use a pair missing from the word in either order")
+	}
+}
+
+// Verifies that Rerank() selects the best-scored words in batches.
+func Test_Rerank(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	var scored int
+	byLength := func(words []string) ([]float64, error) {
+		if len(words) > rerankBatch {
+			t.Fatalf("expected batches of at most %v words, got %v", rerankBatch, len(words))
+		}
+		scored += len(words)
+		scores := make([]float64, len(words))
+		for index, word := range words {
+			scores[index] = float64(len(word))
+		}
+		return scores, nil
+	}
+
+	words, err := traits.Rerank(testDefCount, 0, byLength)
+	tmust(t, err)
+	if len(words) != testDefCount {
+		t.Fatalf("expected %v words, got %v", testDefCount, len(words))
+	}
+	if scored != testDefCount*rerankFactor {
+		t.Fatalf("expected %v scored words, got %v", testDefCount*rerankFactor, scored)
+	}
+	for index := 1; index < len(words); index++ {
+		if len(words[index]) > len(words[index-1]) {
+			t.Fatalf("expected words to be sorted by score, got: %v", words)
+		}
+	}
+
+	short := func(words []string) ([]float64, error) { return nil, nil }
+	if _, err := traits.Rerank(testDefCount, 0, short); err == nil {
+		t.Fatal("expected an error for a wrong number of scores")
+	}
+}
+
+// Verifies that SortedGenerator() yields the whole word set, ordered by
+// sequences of sounds.
+func Test_SortedGenerator(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	gen := traits.SortedGenerator()
+	words := Set{}
+	var prev []string
+	for word := gen(); word != ""; word = gen() {
+		sounds, err := getSounds(word, traits.knownSounds())
+		tmust(t, err)
+		if prev != nil && !lessSounds(prev, sounds) {
+			t.Fatalf("expected %v to precede %v", join(prev, ""), word)
+		}
+		prev = sounds
+		words.Add(word)
+	}
+
+	if all := collectAll(traits); !reflect.DeepEqual(words, all) {
+		t.Fatalf("expected %v words, got %v", len(all), len(words))
+	}
+}
+
+// Verifies that ReplayGenerator() reproduces a recorded session.
+func Test_RecordGenerator(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	gen, rec := traits.RecordGenerator()
+	var recorded []string
+	for word := gen(); word != "" && len(recorded) < testDefCount*10; word = gen() {
+		recorded = append(recorded, word)
+	}
+
+	gen = traits.ReplayGenerator(rec)
+	for _, word := range recorded {
+		if replayed := gen(); replayed != word {
+			t.Fatalf("expected replayed word %v, got %v", word, replayed)
+		}
+	}
+}
+
+// Verifies that At() and IndexOf() map between words and their positions in
+// sorted order.
+func Test_At(t *testing.T) {
+	// t.SkipNow()
+
+	Test_SortedGenerator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	// Each lookup may count most of the tree, so we only check every few words.
+	const stride = 17
+	gen := traits.SortedGenerator()
+	var index uint64
+	for word := gen(); word != ""; word, index = gen(), index+1 {
+		if index%stride != 0 {
+			continue
+		}
+
+		at, err := traits.At(index)
+		tmust(t, err)
+		if at != word {
+			t.Fatalf("expected word %v at index %v, got %v", word, index, at)
+		}
+
+		indexOf, err := traits.IndexOf(word)
+		tmust(t, err)
+		if indexOf != index {
+			t.Fatalf("expected index %v of word %v, got %v", index, word, indexOf)
+		}
+	}
+
+	if _, err := traits.At(index); err == nil {
+		t.Fatal("expected an error for an index out of range")
+	}
+	if _, err := traits.IndexOf("xyz"); err == nil {
+		t.Fatal("expected an error for a word not derived from the traits")
+	}
+}
+
+// Verifies that DeriveWord() maps seeds to words deterministically.
+func Test_DeriveWord(t *testing.T) {
+	// t.SkipNow()
+
+	Test_At(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	all := collectAll(traits)
+
+	words := Set{}
+	for seed := uint64(0); seed < testDefCount; seed++ {
+		word := DeriveWord(traits, seed)
+		if !all.Has(word) {
+			t.Fatalf("expected a derived word for seed %v, got %v", seed, word)
+		}
+		if again := DeriveWord(traits, seed); again != word {
+			t.Fatalf("expected seed %v to produce %v again, got %v", seed, word, again)
+		}
+		words.Add(word)
+	}
+	if len(words) < testDefCount/2 {
+		t.Fatalf("expected consecutive seeds to produce varied words, got: %v", words)
+	}
+
+	if word := DeriveWord(new(Traits), 0); word != "" {
+		t.Fatalf("expected no word for empty traits, got %v", word)
+	}
+}
+
+// Verifies that UseGlobalRand() makes generators draw from the global source,
+// and reports a warning once.
+func Test_UseGlobalRand(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	defer func() {
+		atomic.StoreInt32(&globalRand, 0)
+		globalRandWarning, Warn = sync.Once{}, nil
+	}()
+
+	var warnings []string
+	Warn = func(message string) { warnings = append(warnings, message) }
+
+	if newRand() == nil {
+		t.Fatal("expected generators to have their own sources by default")
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("expected no warnings by default, got: %v", warnings)
+	}
+
+	// Generators may be created while the source is switched.
+	var group sync.WaitGroup
+	for index := 0; index < 4; index++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			newRand()
+		}()
+	}
+	UseGlobalRand()
+	group.Wait()
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	for index := 0; index < testDefCount; index++ {
+		if word := traits.Generator()(); word == "" {
+			t.Fatal("expected generators to work with the global source")
+		}
+	}
+	if newRand() != nil {
+		t.Fatal("expected generators to use the global source")
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected exactly one warning, got: %v", warnings)
+	}
+}
+
+// Verifies that Unique() skips words with repeated keys, and that the key
+// functions normalise as documented.
+func Test_Unique(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	words := []string{"Nebula", "nebula", "Nébula", "nebbula", "aurora"}
+	generate := func(key func(string) string) []string {
+		index := 0
+		gen := Unique(func() string {
+			if index >= len(words) {
+				return ""
+			}
+			index++
+			return words[index-1]
+		}, key)
+		return collectGen(gen)
+	}
+
+	cases := []struct {
+		key    func(string) string
+		expect []string
+	}{
+		{ExactKey, words},
+		{CaseKey, []string{"Nebula", "Nébula", "nebbula", "aurora"}},
+		{DiacriticKey, []string{"Nebula", "nebbula", "aurora"}},
+		{PhoneticKey, []string{"Nebula", "aurora"}},
+	}
+	for _, testCase := range cases {
+		if result := generate(testCase.key); !reflect.DeepEqual(result, testCase.expect) {
+			t.Fatalf("expected %v, got %v", testCase.expect, result)
+		}
+	}
+
+	for _, word := range []string{"cafe", "qaphe", "Kaffe"} {
+		if key := PhoneticKey(word); key != "kafe" {
+			t.Fatalf("expected phonetic key kafe for %v, got %v", word, key)
+		}
+	}
+}
+
+// Verifies that Sample() returns random subsets of the whole word set.
+func Test_Sample(t *testing.T) {
+	// t.SkipNow()
+
+	Test_SortedGenerator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	all := collectAll(traits)
+
+	words, err := traits.Sample(testDefCount)
+	tmust(t, err)
+	if len(words) != testDefCount {
+		t.Fatalf("expected %v words, got %v", testDefCount, len(words))
+	}
+	for word := range words {
+		if !all.Has(word) {
+			t.Fatalf("unexpected word: %v", word)
+		}
+	}
+
+	other, err := traits.Sample(testDefCount)
+	tmust(t, err)
+	if reflect.DeepEqual(words, other) {
+		t.Fatalf("expected different samples, got %v twice", words)
+	}
+
+	// Sizes far beyond the set mustn't be allocated up front.
+	for _, n := range []int{len(all) + 1, 1 << 40} {
+		words, err = traits.Sample(n)
+		if _, ok := err.(*CountError); !ok {
+			t.Fatalf("expected a *CountError, got %v", err)
+		}
+		if !reflect.DeepEqual(words, all) {
+			t.Fatal("expected every word when the sample exceeds the set")
+		}
+	}
+}
+
+// Verifies that the getters return copies of the traits' data.
+func Test_Getters(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	sounds := traits.Sounds()
+	if !reflect.DeepEqual(sounds, traits.SoundSet) {
+		t.Fatalf("expected sounds %v, got %v", traits.SoundSet, sounds)
+	}
+	sounds.Add("zz")
+	if traits.SoundSet.Has("zz") {
+		t.Fatal("expected Sounds() to return a copy")
+	}
+
+	pairs := traits.Pairs()
+	if !reflect.DeepEqual(pairs, traits.PairSet) {
+		t.Fatalf("expected pairs %v, got %v", traits.PairSet, pairs)
+	}
+	pairs.Add([2]string{"zz", "zz"})
+	if traits.PairSet.Has([2]string{"zz", "zz"}) {
+		t.Fatal("expected Pairs() to return a copy")
+	}
+
+	if min, max := traits.LengthRange(); min != traits.MinNSounds || max != traits.MaxNSounds {
+		t.Fatalf("expected length range %v-%v, got %v-%v",
+			traits.MinNSounds, traits.MaxNSounds, min, max)
+	}
+}
+
+// Verifies that SortWords() sorts by byte order or by the given comparison.
+func Test_SortWords(t *testing.T) {
+	// t.SkipNow()
+
+	words := []string{"éclair", "zeta", "Alpha", "eta"}
+	SortWords(words, nil)
+	if expect := []string{"Alpha", "eta", "zeta", "éclair"}; !reflect.DeepEqual(words, expect) {
+		t.Fatalf("expected %v, got %v", expect, words)
+	}
+
+	// Case- and accent-insensitive comparison, standing in for a collator.
+	compare := func(a, b string) int {
+		return strings.Compare(DiacriticKey(a), DiacriticKey(b))
+	}
+	SortWords(words, compare)
+	if expect := []string{"Alpha", "éclair", "eta", "zeta"}; !reflect.DeepEqual(words, expect) {
+		t.Fatalf("expected %v, got %v", expect, words)
+	}
+}
+
+// Verifies that NewTraitsWithSounds() splits words into the given sounds.
+func Test_NewTraitsWithSounds(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	sounds := Set.New(nil, "a", "e", "o", "dz", "k", "r", "n")
+	traits, err := NewTraitsWithSounds([]string{"dzokran", "kodze"}, sounds)
+	tmust(t, err)
+
+	if !traits.SoundSet.Has("dz") || traits.SoundSet.Has("d") {
+		t.Fatalf("expected dz to be a single sound, got sounds: %v", traits.SoundSet)
+	}
+	if !traits.PairSet.Has([2]string{"o", "dz"}) {
+		t.Fatalf("expected pair o>dz, got pairs: %v", traits.PairSet)
+	}
+
+	if _, err := NewTraitsWithSounds([]string{"theron"}, sounds); err == nil {
+		t.Fatal("expected an error for sounds outside the inventory")
+	}
+}
+
+// Verifies that single-sound words are derived only when explicitly requested.
+func Test_SingleSoundWords(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	traits.MinNVowels = 0
+	traits.MaxNSounds = 2
+
+	single := func(words []string) (count int) {
+		for _, word := range words {
+			if sounds, _ := getSounds(word, traits.knownSounds()); len(sounds) == 1 {
+				count++
+			}
+		}
+		return
+	}
+
+	if count := single(sortedSet(collectAll(traits))); count != 0 {
+		t.Fatalf("expected no single-sound words by default, got %v", count)
+	}
+
+	traits.MinNSounds = 1
+	all := collectAll(traits)
+	if single(sortedSet(all)) == 0 {
+		t.Fatal("expected single-sound words with MinNSounds = 1")
+	}
+	if count := traits.countFrom(nil); count != uint64(len(all)) {
+		t.Fatalf("expected the generator to yield all %v words, got %v", count, len(all))
+	}
+	if single(collectGen(traits.SortedGenerator())) == 0 {
+		t.Fatal("expected single-sound words from the sorted generator")
+	}
+	if single(traits.TopK(len(all))) == 0 {
+		t.Fatal("expected single-sound words from TopK()")
+	}
+}
+
+// Verifies that getSounds() splits words into the longest known glyphs.
+func Test_getSounds_Multigraphs(t *testing.T) {
+	// t.SkipNow()
+
+	known := Set.New(nil, "t", "s", "c", "h", "sch", "tsch", "u", "ough", "o", "g", "e", "r")
+
+	cases := map[string][]string{
+		"tschax": nil,
+		"tschu":  {"tsch", "u"},
+		"schur":  {"sch", "u", "r"},
+		"tough":  {"t", "ough"},
+		"togh":   {"t", "o", "g", "h"},
+		"chest":  {"c", "h", "e", "s", "t"},
+	}
+	for word, expect := range cases {
+		sounds, err := getSounds(word, known)
+		if expect == nil {
+			if err == nil {
+				t.Fatalf("expected an error for %v, got %v", word, sounds)
+			}
+			continue
+		}
+		tmust(t, err)
+		if !reflect.DeepEqual(sounds, expect) {
+			t.Fatalf("expected %v to split into %v, got %v", word, expect, sounds)
+		}
+	}
+}
+
+// Verifies that MaxWordLen raises the limit on sample word length, and
+// survives encoding.
+func Test_MaxWordLen(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+	Test_Traits_Dump(t)
+
+	long := []string{"kumarrustenkatselemattomuudellansakaan", "kaupunginvaltuusto"}
+
+	if _, err := NewTraits(long); err == nil {
+		t.Fatal("expected an error for words longer than the default limit")
+	}
+
+	traits := &Traits{MaxWordLen: 48}
+	tmust(t, traits.Examine(long))
+
+	test_Traits_Encodings(t, traits)
+}
+
+// Verifies that higher-order traits only derive words made of known sequences
+// of sounds, and keep them through encoding and editing.
+func Test_NewTraitsN(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+	Test_Traits_Dump(t)
+	Test_Traits_Edit(t)
+
+	if _, err := NewTraitsN(testDefWords, 0); err == nil {
+		t.Fatal("expected an error for order 0")
+	}
+
+	pairs, err := NewTraitsN(testDefWords, 1)
+	tmust(t, err)
+	if len(pairs.GramSet) != 0 {
+		t.Fatalf("expected no n-grams for order 1, got %v", pairs.GramSet)
+	}
+
+	traits, err := NewTraitsN(testDefWords, 2)
+	tmust(t, err)
+	if !traits.GramSet.Has("n>e>b") || traits.GramSet.Has("n>e") {
+		t.Fatalf("expected trigrams, got %v", traits.GramSet)
+	}
+	tmust(t, traits.Validate())
+
+	all := collectAll(traits)
+	if len(all) == 0 || len(all) >= len(collectAll(pairs)) {
+		t.Fatalf("expected fewer words than with pairs, got %v", len(all))
+	}
+	for word := range all {
+		sounds, err := getSounds(word, traits.knownSounds())
+		tmust(t, err)
+		for i := 0; i+2 < len(sounds); i++ {
+			if !traits.GramSet.Has(gramKey(sounds[i : i+3])) {
+				t.Fatalf("word %v has an unknown trigram: %v", word, sounds[i:i+3])
+			}
+		}
+	}
+
+	test_Traits_Encodings(t, traits)
+
+	traits.DelPair([2]string{"n", "e"})
+	if traits.GramSet.Has("n>e>b") {
+		t.Fatal("expected n-grams with a deleted pair to be deleted")
+	}
+	tmust(t, traits.Validate())
+}
+
+// Verifies that Split() distributes every word to exactly one consumer, even
+// when the consumers run concurrently.
+func Test_Split(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	all := collectAll(traits)
+
+	gens := Split(traits.Generator(), 4, testDefCount)
+	results := make([][]string, len(gens))
+	var wait sync.WaitGroup
+	for index, gen := range gens {
+		wait.Add(1)
+		go func(index int, gen func() string) {
+			defer wait.Done()
+			results[index] = collectGen(gen)
+		}(index, gen)
+	}
+	wait.Wait()
+
+	words := Set{}
+	for _, result := range results {
+		for _, word := range result {
+			if words.Has(word) {
+				t.Fatalf("word %v was given to more than one consumer", word)
+			}
+			words.Add(word)
+		}
+	}
+	if !reflect.DeepEqual(words, all) {
+		t.Fatalf("expected %v words in total, got %v", len(all), len(words))
+	}
+}
+
+// Verifies that generators of a model follow their swap policy when the traits
+// Verifies that a session never repeats a word across calls until reset.
+func Test_Session(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_WordsN(t)
+
+	traits, err := NewTraits([]string{"goblin", "smoke"})
+	tmust(t, err)
+	all := collectAll(traits)
+	half := len(all) / 2
+
+	session := NewSession(traits)
+	first, err := session.WordsN(half)
+	tmust(t, err)
+	second, err := session.WordsN(len(all))
+	if len(first) != half || len(second) != len(all)-half {
+		t.Fatalf("expected %v and %v words, got %v and %v", half, len(all)-half, len(first), len(second))
+	}
+	var countErr *CountError
+	if !errors.As(err, &countErr) || countErr.Available != len(all)-half {
+		t.Fatalf("expected a *CountError with %v available, got %v", len(all)-half, err)
+	}
+	for word := range first {
+		if second.Has(word) {
+			t.Fatalf("expected no repeats across calls, got %v twice", word)
+		}
+	}
+	if word := session.Next(); word != "" {
+		t.Fatalf("expected an exhausted session, got %v", word)
+	}
+
+	session.Reset()
+	words, err := session.WordsN(len(all))
+	tmust(t, err)
+	if len(words) != len(all) {
+		t.Fatalf("expected all %v words after a reset, got %v", len(all), len(words))
+	}
+}
+
+// are replaced, including concurrently.
+func Test_Model(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	old, err := NewTraits([]string{"nebula", "aurora"})
+	tmust(t, err)
+	fresh, err := NewTraits([]string{"goblin", "smoke"})
+	tmust(t, err)
+	oldWords, freshWords := collectAll(old), collectAll(fresh)
+
+	model := NewModel(old)
+	finish := model.Generator(FinishOld)
+	switchNext := model.Generator(SwitchNext)
+	finish()
+	switchNext()
+	model.Swap(fresh)
+
+	if word := finish(); !oldWords.Has(word) {
+		t.Fatalf("expected FinishOld to keep using the old traits, got %v", word)
+	}
+	if word := switchNext(); !freshWords.Has(word) {
+		t.Fatalf("expected SwitchNext to use the new traits, got %v", word)
+	}
+	if word := model.Generator(FinishOld)(); !freshWords.Has(word) {
+		t.Fatalf("expected new generators to use the new traits, got %v", word)
+	}
+
+	// Swap back and forth while several generators are in use.
+	union := Set{}
+	for word := range oldWords {
+		union.Add(word)
+	}
+	for word := range freshWords {
+		union.Add(word)
+	}
+
+	done := make(chan struct{})
+	var wait sync.WaitGroup
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		for index := 0; ; index++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if index%2 == 0 {
+				model.Swap(old)
+			} else {
+				model.Swap(fresh)
+			}
+		}
+	}()
+
+	errs := make(chan string, 8)
+	var consumers sync.WaitGroup
+	for index := 0; index < 8; index++ {
+		consumers.Add(1)
+		go func(policy SwapPolicy) {
+			defer consumers.Done()
+			gen := model.Generator(policy)
+			words := Set{}
+			for word := gen(); word != ""; word = gen() {
+				if words.Has(word) || !union.Has(word) {
+					errs <- word
+					return
+				}
+				words.Add(word)
+			}
+		}(SwapPolicy(index % 2))
+	}
+	consumers.Wait()
+	close(done)
+	wait.Wait()
+	close(errs)
+
+	for word := range errs {
+		t.Fatalf("unexpected or repeated word: %v", word)
+	}
+
+	// Nil traits derive no words until replaced.
+	model = NewModel(nil)
+	for _, policy := range []SwapPolicy{FinishOld, SwitchNext} {
+		if word := model.Generator(policy)(); word != "" {
+			t.Fatalf("expected no words from nil traits, got %q", word)
+		}
+	}
+	gen := model.Generator(SwitchNext)
+	model.Swap(old)
+	if word := gen(); word == "" {
+		t.Fatal("expected words after replacing nil traits")
+	}
+}
+
+// Verifies that pairs are counted during analysis, that weighted generators
+// favour frequent pairs, and that counts survive encoding and editing.
+func Test_PairCount(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+	Test_Traits_Dump(t)
+	Test_Traits_Edit(t)
+
+	words := []string{"te"}
+	for len(words) < 21 {
+		words = append(words, "ta")
+	}
+	traits, err := NewTraits(words)
+	tmust(t, err)
+	if expect := map[[2]string]int{{"t", "a"}: 20, {"t", "e"}: 1}; !reflect.DeepEqual(traits.PairCount, expect) {
+		t.Fatalf("expected pair counts %v, got %v", expect, traits.PairCount)
+	}
+	if weight := traits.Matrix().Transitions["t"]["a"]; weight != 20 {
+		t.Fatalf("expected a transition weight of 20, got %v", weight)
+	}
+
+	firsts := func() (count int) {
+		for i := 0; i < 100; i++ {
+			if traits.Generator()() == "ta" {
+				count++
+			}
+		}
+		return
+	}
+	if count := firsts(); count > 80 {
+		t.Fatalf("expected unweighted generators to treat pairs equally, got %v%% frequent", count)
+	}
+	traits.Weighted = true
+	if count := firsts(); count < 80 {
+		t.Fatalf("expected weighted generators to favour frequent pairs, got %v%% frequent", count)
+	}
+
+	test_Traits_Encodings(t, traits)
+
+	traits.DelPair([2]string{"t", "e"})
+	if _, ok := traits.PairCount[[2]string{"t", "e"}]; ok {
+		t.Fatal("expected the count of a deleted pair to be deleted")
+	}
+	tmust(t, traits.Validate())
+}
+
+// Verifies that readers end with errors that match io.EOF, and compose with
+// standard library streaming code.
+func Test_Reader(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Generator(t)
+
+	for _, err := range []error{ErrExhausted, ErrBudgetExceeded} {
+		if !errors.Is(err, io.EOF) {
+			t.Fatalf("expected %v to match io.EOF", err)
+		}
+	}
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	reader := NewReader(traits.Generator(), testDefCount)
+	if reader.Err() != nil {
+		t.Fatalf("expected no error before the end, got %v", reader.Err())
+	}
+	output, err := io.ReadAll(reader)
+	tmust(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	if len(lines) != testDefCount || len(Set.New(nil, lines...)) != testDefCount {
+		t.Fatalf("expected %v distinct lines, got: %q", testDefCount, output)
+	}
+	if reader.Err() != ErrBudgetExceeded {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", reader.Err())
+	}
+
+	traits, err = NewTraits([]string{"goblin", "smoke"})
+	tmust(t, err)
+	reader = NewReader(traits.Generator(), 0)
+	count := 0
+	for {
+		_, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			if err != ErrExhausted {
+				t.Fatalf("expected ErrExhausted, got %v", err)
+			}
+			break
+		}
+		tmust(t, err)
+		count++
+	}
+	if count != len(collectAll(traits)) {
+		t.Fatalf("expected every word before the end, got %v", count)
+	}
+}
+
+// Verifies that custom vowels are known sounds and are classified as vowels.
+func Test_KnownVowels(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	if _, err := NewTraits([]string{"kärpänen"}); err == nil {
+		t.Fatal("expected an error for unknown vowels by default")
+	}
+
+	vowels := DefaultVowels()
+	for vowel := range Letters("äöø") {
+		vowels.Add(vowel)
+	}
+	traits := &Traits{KnownVowels: vowels}
+	tmust(t, traits.Examine([]string{"kärpänen", "søster"}))
+
+	if n := traits.countVowels([]string{"k", "ä", "r", "p", "ä", "n", "e", "n"}); n != 3 {
+		t.Fatalf("expected 3 vowels, got %v", n)
+	}
+	if traits.MaxConseqCons != 2 || traits.MinNVowels != 2 || traits.MaxNVowels != 3 {
+		t.Fatalf("expected vowels to be classified per KnownVowels, got: %#v", traits)
+	}
+
+	copied := traits.Vowels()
+	if !reflect.DeepEqual(copied, vowels) {
+		t.Fatalf("expected vowels %v, got %v", vowels, copied)
+	}
+	copied.Add("zz")
+	defaults := DefaultVowels()
+	if traits.KnownVowels.Has("zz") || defaults.Has("ä") {
+		t.Fatal("expected copies of vowel sets")
+	}
+}
+
+// Verifies that sounds and word lengths are measured in letters rather than
+// bytes, so samples in other scripts work like Latin ones.
+func Test_Unicode(t *testing.T) {
+	// t.SkipNow()
+
+	Test_getSounds_Multigraphs(t)
+
+	known := Letters("абвгдежзийклмнопрстуфхцчшщъыьэюя")
+	known.Add("дж")
+	sounds, err := getSounds("джунгли", known)
+	tmust(t, err)
+	if expect := []string{"дж", "у", "н", "г", "л", "и"}; !reflect.DeepEqual(sounds, expect) {
+		t.Fatalf("expected %v, got %v", expect, sounds)
+	}
+
+	// 22 letters, but 44 bytes.
+	long := "превысокомногорассмотр"
+	if !validLength(long, defaultMaxWordLen) {
+		t.Fatalf("expected %v to fit the default limit", long)
+	}
+	if validLength(long, 21) {
+		t.Fatalf("expected %v not to fit 21 letters", long)
+	}
+
+	traits := &Traits{
+		KnownSounds: Letters("αβγδεζηθικλμνξοπρσςτυφχψω"),
+		KnownVowels: Letters("αεηιουω"),
+		Casing:      TitleCase,
+	}
+	tmust(t, traits.Examine([]string{"θαλασσα", "ουρανος", "ηλιος"}))
+	if traits.MinNSounds != 5 || traits.MaxNSounds != 7 {
+		t.Fatalf("expected words of 5 to 7 sounds, got: %#v", traits)
+	}
+	words := collectAll(traits)
+	if len(words) == 0 {
+		t.Fatal("expected derived words")
+	}
+	for word := range words {
+		if []rune(word)[0] != []rune(strings.ToUpper(word))[0] {
+			t.Fatalf("expected a title-cased word, got %v", word)
+		}
+	}
+}
+
+func Test_Cyrillic(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Unicode(t)
+
+	traits := &Traits{KnownSounds: CyrillicSounds(), KnownVowels: CyrillicVowels()}
+	tmust(t, traits.Examine([]string{"джерело", "тополя", "вишня", "ґанок"}))
+
+	if !traits.SoundSet.Has("дж") || traits.SoundSet.Has("д") {
+		t.Fatalf("expected the digraph дж, got %v", traits.SoundSet)
+	}
+	if len(collectAll(traits)) == 0 {
+		t.Fatal("expected derived words")
+	}
+
+	sounds := CyrillicSounds()
+	sounds.Del("а")
+	if !cyrillicSounds.Has("а") {
+		t.Fatal("expected a copy of the Cyrillic sounds")
+	}
+}
+
+func Test_ExaminePhonemes(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+	Test_Traits_Dump(t)
+
+	traits := &Traits{
+		KnownVowels: Set.New(nil, "ɪ", "iː", "æ", "ɒ"),
+		Spelling:    map[string]string{"ʃ": "sh", "tʃ": "ch", "iː": "ee", "æ": "a", "ɒ": "o", "ɪ": "i"},
+		Casing:      TitleCase,
+	}
+	tmust(t, traits.ExaminePhonemes([]string{"ʃ.ɪ.p", "tʃ iː p", "ʃ.æ.tʃ", "t.ɒ.p"}))
+
+	expect := Set.New(nil, "ʃ", "ɪ", "p", "tʃ", "iː", "æ", "t", "ɒ")
+	if !reflect.DeepEqual(traits.SoundSet, expect) {
+		t.Fatalf("expected sounds %v, got %v", expect, traits.SoundSet)
+	}
+	if traits.MinNSounds != 3 || traits.MaxNSounds != 3 {
+		t.Fatalf("expected words of 3 sounds, got: %#v", traits)
+	}
+
+	words := collectAll(traits)
+	if !words.Has("Ship") || !words.Has("Cheep") || !words.Has("Shach") {
+		t.Fatalf("expected spelled words, got %v", words)
+	}
+	for word := range words {
+		if strings.ContainsAny(word, "ʃɪæɒː") {
+			t.Fatalf("expected only spelled words, got %v", word)
+		}
+	}
+
+	test_Traits_Encodings(t, traits)
+}
+
+func Test_Traits_Matches(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Generator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	for word := range collectAll(traits) {
+		if !traits.Matches(word) {
+			t.Fatalf("expected %v to match the traits", word)
+		}
+	}
+
+	traits.Casing = TitleCase
+	gen := traits.Generator()
+	if word := gen(); !traits.Matches(word) {
+		t.Fatalf("expected %v to match regardless of casing", word)
+	}
+
+	for _, word := range []string{"", "q", "lisomox", "mountainmountain", "wa-ter"} {
+		if traits.Matches(word) {
+			t.Fatalf("expected %v not to match the traits", word)
+		}
+	}
+}
+
+func Test_Traits_Score(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_TopK(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	top := traits.TopK(testDefCount)
+	for index := 1; index < len(top); index++ {
+		if traits.Score(top[index]) > traits.Score(top[index-1]) {
+			t.Fatalf("expected scores to follow the order of TopK, got %v", top)
+		}
+	}
+
+	if score := traits.Score(strings.ToUpper(top[0])); score != traits.Score(top[0]) {
+		t.Fatalf("expected casing to be ignored, got %v", score)
+	}
+	for _, word := range []string{"", "wa-ter", "qqq"} {
+		if score := traits.Score(word); !math.IsInf(score, -1) {
+			t.Fatalf("expected negative infinity for %q, got %v", word, score)
+		}
+	}
+
+	// Scores follow the lengths of the sample words.
+	word := top[0]
+	sounds, err := getSounds(word, traits.knownSounds())
+	tmust(t, err)
+	before := traits.Score(word)
+	traits.LengthCount[len(sounds)] += 100
+	if after := traits.Score(word); !(after > before) {
+		t.Fatalf("expected a common length to raise the score from %v, got %v", before, after)
+	}
+	traits.LengthCount = map[int]int{len(sounds) + 1: 100}
+	if after := traits.Score(word); !(after < before) {
+		t.Fatalf("expected a rare length to lower the score from %v, got %v", before, after)
+	}
+	traits.LengthCount = nil
+	if after := traits.Score(word); !(after > before) {
+		t.Fatalf("expected no length term without LengthCount, got %v", after)
+	}
+}
+
+func Test_Traits_Merge(t *testing.T) {
+	// t.SkipNow()
+
+	Test_PairCount(t)
+
+	norse := []string{"sigurd", "ragnar", "ingrid"}
+	celtic := []string{"brigid", "aodhan", "niamh", "ciaran"}
+
+	one, err := NewTraits(norse)
+	tmust(t, err)
+	other, err := NewTraits(celtic)
+	tmust(t, err)
+	both, err := NewTraits(append(append([]string{}, norse...), celtic...))
+	tmust(t, err)
+
+	oneInput, err := json.Marshal(one)
+	tmust(t, err)
+
+	merged := one.Merge(other)
+	if !reflect.DeepEqual(merged, both) {
+		t.Fatalf("expected merged traits to equal traits of the combined sample\nexpected: %#v\ngot: %#v", both, merged)
+	}
+
+	oneOutput, err := json.Marshal(one)
+	tmust(t, err)
+	if string(oneInput) != string(oneOutput) {
+		t.Fatal("expected Merge not to modify its inputs")
+	}
+	merged.SoundSet.Add("zz")
+	if one.SoundSet.Has("zz") || other.SoundSet.Has("zz") {
+		t.Fatal("expected merged traits not to share sets with the inputs")
+	}
+
+	if merged := one.Merge(&Traits{}); !reflect.DeepEqual(merged.SoundSet, one.SoundSet) ||
+		merged.MinNSounds != one.MinNSounds || merged.MinNVowels != one.MinNVowels {
+		t.Fatalf("expected merging with empty traits to keep the traits, got: %#v", merged)
+	}
+}
+
+func Test_Traits_Intersect(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Matches(t)
+
+	one, err := NewTraits([]string{"marina", "solana", "lorena", "amelia"})
+	tmust(t, err)
+	other, err := NewTraits([]string{"marlene", "selina", "ramona", "melanie"})
+	tmust(t, err)
+
+	if same := one.Intersect(one); !reflect.DeepEqual(same, one) {
+		t.Fatalf("expected the intersection with itself to equal the traits\nexpected: %#v\ngot: %#v", one, same)
+	}
+
+	both := one.Intersect(other)
+	if len(both.PairSet) == 0 || len(both.PairSet) >= len(one.PairSet) {
+		t.Fatalf("expected some but not all pairs, got %v", both.PairSet)
+	}
+	words := collectAll(both)
+	if len(words) == 0 {
+		t.Fatal("expected derived words")
+	}
+	for word := range words {
+		if !one.Matches(word) || !other.Matches(word) {
+			t.Fatalf("expected %v to match both traits", word)
+		}
+	}
+}
+
+func Test_Traits_Exclude(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Edit(t)
+
+	traits, err := NewTraits([]string{"aurelio", "valeria", "lucrezia", "ottavio"})
+	tmust(t, err)
+	bad, err := NewTraits([]string{"gottfried", "wolfgang"})
+	tmust(t, err)
+
+	input, err := json.Marshal(traits)
+	tmust(t, err)
+
+	out := traits.Exclude(bad)
+	tmust(t, out.Validate())
+	for pair := range bad.PairSet {
+		if out.PairSet.Has(pair) {
+			t.Fatalf("expected pair %v to be excluded", pair)
+		}
+	}
+	if !out.PairSet.Has([2]string{"a", "u"}) || out.PairSet.Has([2]string{"t", "t"}) {
+		t.Fatalf("expected only the pairs of the other traits to be excluded, got %v", out.PairSet)
+	}
+	for word := range collectAll(out) {
+		sounds, err := getSounds(word, out.knownSounds())
+		tmust(t, err)
+		for pair := range getPairs(sounds) {
+			if bad.PairSet.Has(pair) {
+				t.Fatalf("expected %v not to have excluded pair %v", word, pair)
+			}
+		}
+	}
+
+	output, err := json.Marshal(traits)
+	tmust(t, err)
+	if string(input) != string(output) {
+		t.Fatal("expected Exclude not to modify the traits")
+	}
+}
+
+func Test_Traits_AddWord(t *testing.T) {
+	// t.SkipNow()
+
+	Test_PairCount(t)
+
+	expect, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	traits := &Traits{}
+	for _, word := range testDefWords {
+		tmust(t, traits.AddWord(word))
+	}
+	if !reflect.DeepEqual(traits, expect) {
+		t.Fatalf("expected incremental traits to equal traits of the whole sample\nexpected: %#v\ngot: %#v", expect, traits)
+	}
+
+	input, err := json.Marshal(traits)
+	tmust(t, err)
+	for _, word := range []string{"", "a", "wa-ter"} {
+		if traits.AddWord(word) == nil {
+			t.Fatalf("expected an error for %q", word)
+		}
+	}
+	output, err := json.Marshal(traits)
+	tmust(t, err)
+	if string(input) != string(output) {
+		t.Fatal("expected invalid words to leave the traits unchanged")
+	}
+}
+
+func Test_Traits_RemoveWord(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_AddWord(t)
+
+	expect, err := NewTraits([]string{"mountain", "grotto"})
+	tmust(t, err)
+
+	traits, err := NewTraits([]string{"mountain", "waterfall", "grotto"})
+	tmust(t, err)
+	tmust(t, traits.RemoveWord("waterfall"))
+	tmust(t, traits.Validate())
+
+	if !reflect.DeepEqual(traits.SoundSet, expect.SoundSet) ||
+		!reflect.DeepEqual(traits.PairSet, expect.PairSet) ||
+		!reflect.DeepEqual(traits.PairCount, expect.PairCount) {
+		t.Fatalf("expected the sounds and pairs of the remaining words\nexpected: %#v\ngot: %#v", expect, traits)
+	}
+
+	input, err := json.Marshal(traits)
+	tmust(t, err)
+	for _, word := range []string{"waterfall", "mountainmountain", "wa-ter", "m"} {
+		if traits.RemoveWord(word) == nil {
+			t.Fatalf("expected an error for %q", word)
+		}
+	}
+	output, err := json.Marshal(traits)
+	tmust(t, err)
+	if string(input) != string(output) {
+		t.Fatal("expected failed removals to leave the traits unchanged")
+	}
+}
+
+func Test_Traits_Clone(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Edit(t)
+
+	traits, err := NewTraitsN(testDefWords, 2)
+	tmust(t, err)
+	traits.KnownSounds = DefaultSounds()
+	traits.KnownVowels = DefaultVowels()
+	traits.Spelling = map[string]string{"th": "þ"}
+
+	input, err := json.Marshal(traits)
+	tmust(t, err)
+
+	clone := traits.Clone()
+	if !reflect.DeepEqual(clone, traits) {
+		t.Fatalf("expected the clone to equal the traits\nexpected: %#v\ngot: %#v", traits, clone)
+	}
+
+	for pair := range clone.PairSet {
+		clone.DelPair(pair)
+		break
+	}
+	clone.PairCount[[2]string{"z", "z"}] = 1
+	clone.KnownSounds.Add("zz")
+	clone.KnownVowels.Add("zz")
+	clone.Spelling["a"] = "æ"
+
+	output, err := json.Marshal(traits)
+	tmust(t, err)
+	if string(input) != string(output) {
+		t.Fatal("expected editing the clone not to modify the traits")
+	}
+}
+
+// Verifies that cluster limits tuned after analysis constrain derived words.
+func Test_ClusterLimits(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Generator(t)
+
+	traits, err := NewTraits([]string{"strength", "kamehameha", "aerial"})
+	tmust(t, err)
+	all := collectAll(traits)
+
+	if traits.SetClusterLimits(0, 1) == nil || traits.SetClusterLimits(1, -1) == nil {
+		t.Fatal("expected an error for limits below 1")
+	}
+	tmust(t, traits.SetClusterLimits(1, 1))
+	if vowels, consonants := traits.ClusterLimits(); vowels != 1 || consonants != 1 {
+		t.Fatalf("expected limits 1 and 1, got %v and %v", vowels, consonants)
+	}
+	limited := collectAll(traits)
+	if len(limited) == 0 || len(limited) >= len(all) {
+		t.Fatalf("expected fewer words with lower limits, got %v of %v", len(limited), len(all))
+	}
+	for word := range limited {
+		sounds, err := getSounds(word, traits.knownSounds())
+		tmust(t, err)
+		if traits.maxConsequtiveConsonants(sounds) > 1 || traits.maxConsequtiveVowels(sounds) > 1 {
+			t.Fatalf("expected no clusters in %v", word)
+		}
+	}
+}
+
+func Test_Traits_SetLengthRange(t *testing.T) {
+	// t.SkipNow()
+
+	Test_ClusterLimits(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	all := collectAll(traits)
+
+	tmust(t, traits.SetLengthRange(3, 4))
+	if min, max := traits.LengthRange(); min != 3 || max != 4 {
+		t.Fatalf("expected range 3..4, got %v..%v", min, max)
+	}
+	short := collectAll(traits)
+	if len(short) == 0 || len(short) >= len(all) {
+		t.Fatalf("expected fewer words, got %v of %v", len(short), len(all))
+	}
+	for word := range short {
+		sounds, err := getSounds(word, traits.knownSounds())
+		tmust(t, err)
+		if len(sounds) < 3 || len(sounds) > 4 {
+			t.Fatalf("expected 3 to 4 sounds in %v", word)
+		}
+	}
+
+	for _, bounds := range [][2]int{{0, 3}, {4, 3}, {-1, -1}} {
+		if traits.SetLengthRange(bounds[0], bounds[1]) == nil {
+			t.Fatalf("expected an error for %v", bounds)
+		}
+	}
+	if min, max := traits.LengthRange(); min != 3 || max != 4 {
+		t.Fatalf("expected invalid bounds to be ignored, got %v..%v", min, max)
+	}
+}
+
+func Test_Positional(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+	Test_Traits_Dump(t)
+	Test_Traits_Clone(t)
+
+	plain, err := NewTraits(testDefWords)
+	tmust(t, err)
+	traits := &Traits{Positional: true}
+	tmust(t, traits.Examine(testDefWords))
+	tmust(t, traits.Validate())
+
+	if !traits.StartSet.Has([2]string{"th", "o"}) || traits.StartSet.Has([2]string{"r", "o"}) {
+		t.Fatalf("expected start pairs of the sample, got %v", traits.StartSet)
+	}
+	if !traits.EndSet.Has([2]string{"o", "n"}) || !traits.MidSet.Has([2]string{"r", "o"}) {
+		t.Fatalf("expected end and middle pairs of the sample, got %v and %v", traits.EndSet, traits.MidSet)
+	}
+
+	all := collectAll(plain)
+	words := collectAll(traits)
+	if len(words) == 0 || len(words) >= len(all) {
+		t.Fatalf("expected fewer words than without positions, got %v of %v", len(words), len(all))
+	}
+	for word := range words {
+		if !all.Has(word) {
+			t.Fatalf("expected %v among the words derived without positions", word)
+		}
+		sounds, err := getSounds(word, traits.knownSounds())
+		tmust(t, err)
+		last := len(sounds) - 2
+		if !traits.StartSet.Has([2]string{sounds[0], sounds[1]}) ||
+			!traits.EndSet.Has([2]string{sounds[last], sounds[last+1]}) {
+			t.Fatalf("expected %v to begin and end with positioned pairs", word)
+		}
+		for i := 1; i < last; i++ {
+			if !traits.MidSet.Has([2]string{sounds[i], sounds[i+1]}) {
+				t.Fatalf("expected %v to continue with middle pairs", word)
+			}
+		}
+	}
+	for pair := range traits.Ends() {
+		if !traits.EndSet.Has(pair) {
+			t.Fatalf("expected ends among the EndSet, got %v", pair)
+		}
+	}
+
+	test_Traits_Encodings(t, traits)
+
+	clone := traits.Clone()
+	clone.DelSound("th")
+	tmust(t, clone.Validate())
+	if !traits.StartSet.Has([2]string{"th", "o"}) {
+		t.Fatal("expected editing the clone not to modify the traits")
+	}
+}
+
+func Test_Traits_Reverse(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_GeneratorTo(t)
+	Test_Positional(t)
+
+	plain, err := NewTraits(testDefWords)
+	tmust(t, err)
+	higher, err := NewTraitsN(testManyWords, 2)
+	tmust(t, err)
+	positional := &Traits{Positional: true}
+	tmust(t, positional.Examine(testDefWords))
+
+	for _, traits := range []*Traits{plain, higher, positional} {
+		input, err := json.Marshal(traits)
+		tmust(t, err)
+
+		reversed := traits.Reverse()
+		tmust(t, reversed.Validate())
+
+		expect := Set{}
+		for word := range collectAll(traits) {
+			sounds, err := getSounds(word, traits.knownSounds())
+			tmust(t, err)
+			expect.Add(join(reverseSounds(sounds), ""))
+		}
+		if words := collectAll(reversed); !reflect.DeepEqual(words, expect) {
+			t.Fatalf("expected the reversed words\nexpected: %v\ngot: %v", expect, words)
+		}
+
+		if !reflect.DeepEqual(reversed.Reverse(), traits) {
+			t.Fatalf("expected reversing twice to restore the traits, got: %#v", reversed.Reverse())
+		}
+
+		output, err := json.Marshal(traits)
+		tmust(t, err)
+		if string(input) != string(output) {
+			t.Fatal("expected Reverse not to modify the traits")
+		}
+	}
+}
+
+func Test_Harmony(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Positional(t)
+
+	words := []string{"talo", "kylä", "pöytä", "koulu", "metsä", "kala", "järvi", "tuoli", "hyvä", "sauna"}
+	vowels := DefaultVowels()
+	for vowel := range Letters("äö") {
+		vowels.Add(vowel)
+	}
+
+	mixes := func(word string) bool {
+		return strings.ContainsAny(word, "äöy") && strings.ContainsAny(word, "aou")
+	}
+
+	plain := &Traits{KnownVowels: vowels}
+	tmust(t, plain.Examine(words))
+	mixed := 0
+	for word := range collectAll(plain) {
+		if mixes(word) {
+			mixed++
+		}
+	}
+	if mixed == 0 {
+		t.Fatal("expected words that mix vowel classes without harmony")
+	}
+
+	traits := &Traits{KnownVowels: vowels, Harmony: true}
+	tmust(t, traits.Examine(words))
+	tmust(t, traits.Validate())
+	if !traits.HarmonySet.Has([2]string{"e", "ä"}) || !traits.HarmonySet.Has([2]string{"a", "u"}) ||
+		traits.HarmonySet.Has([2]string{"a", "ä"}) {
+		t.Fatalf("expected vowels that occur together, got %v", traits.HarmonySet)
+	}
+
+	derived := collectAll(traits)
+	if len(derived) == 0 {
+		t.Fatal("expected derived words")
+	}
+	for word := range derived {
+		if mixes(word) {
+			t.Fatalf("expected %v not to mix front and back vowels", word)
+		}
+	}
+
+	test_Traits_Encodings(t, traits)
+}
+
+// Verifies that combining marks stay with the letters they follow, both in
+// sample words and in known sounds.
+func Test_CombiningMarks(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Unicode(t)
+
+	// Decomposed: each vowel is followed by one or two combining marks.
+	const (
+		maAcute  = "ma\u0301"
+		aHook    = "a\u0309"
+		eCircDot = "e\u0302\u0323"
+	)
+
+	if n := countLetters(maAcute + aHook + eCircDot); n != 4 {
+		t.Fatalf("expected 4 letters, got %v", n)
+	}
+	if letters := Letters("a\u0301e"); !reflect.DeepEqual(letters, Set.New(nil, "a\u0301", "e")) {
+		t.Fatalf("expected marks to stay with their letters, got %v", letters)
+	}
+
+	known := Letters("mnhtca\u0301a\u0309e\u0302\u0323")
+	known.Add("ngh")
+	sounds, err := getSounds("ngh"+eCircDot+maAcute, known)
+	tmust(t, err)
+	if expect := []string{"ngh", eCircDot, "m", "a\u0301"}; !reflect.DeepEqual(sounds, expect) {
+		t.Fatalf("expected %q, got %q", expect, sounds)
+	}
+	if _, err := getSounds("ma", known); err == nil {
+		t.Fatal("expected an error for a letter without its mark")
+	}
+
+	traits := &Traits{
+		KnownSounds: known,
+		KnownVowels: Letters("a\u0301a\u0309e\u0302\u0323"),
+	}
+	tmust(t, traits.Examine([]string{maAcute + "n" + aHook, "t" + eCircDot + "c" + aHook, "h" + aHook + "m" + eCircDot}))
+	for word := range collectAll(traits) {
+		if _, err := getSounds(word, known); err != nil {
+			t.Fatalf("expected %q to consist of whole letters", word)
+		}
+	}
+}
+
+func Test_Connectives(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+	Test_Traits_Dump(t)
+
+	words := []string{"o'brien", "jean-luc", "d'arcy", "anne-marie", "brian", "lucie"}
+	if _, err := NewTraits(words); err == nil {
+		t.Fatal("expected an error for connectives by default")
+	}
+
+	traits := &Traits{Connectives: Set.New(nil, "'", "-")}
+	tmust(t, traits.Examine(words))
+	tmust(t, traits.Validate())
+
+	if n := traits.maxConsequtiveConsonants([]string{"n", "-", "l"}); n != 1 {
+		t.Fatalf("expected connectives to break up clusters, got %v", n)
+	}
+	if traits.MaxConseqCons != 2 {
+		t.Fatalf("expected clusters of at most 2 consonants, got %v", traits.MaxConseqCons)
+	}
+
+	words = collectGen(traits.Generator())
+	joined := 0
+	for _, word := range words {
+		if strings.HasPrefix(word, "-") || strings.HasPrefix(word, "'") ||
+			strings.HasSuffix(word, "-") || strings.HasSuffix(word, "'") {
+			t.Fatalf("expected %v not to begin or end with a connective", word)
+		}
+		if strings.ContainsAny(word, "'-") {
+			joined++
+		}
+	}
+	if joined == 0 {
+		t.Fatal("expected words with connectives")
+	}
+	for end := range traits.Ends() {
+		if traits.Connectives.Has(end[1]) {
+			t.Fatalf("expected ends without connectives, got %v", end)
+		}
+	}
+
+	test_Traits_Encodings(t, traits)
+}
+
+func Test_Traits_Stats(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Matrix(t)
+
+	traits, err := NewTraits([]string{"go", "ga"})
+	tmust(t, err)
+	stats := traits.Stats()
+	expect := Stats{NSounds: 3, NPairs: 2, Branching: 2, Entropy: 1, NWords: 2}
+	if stats != expect {
+		t.Fatalf("expected %#v, got %#v", expect, stats)
+	}
+
+	traits, err = NewTraits(testDefWords)
+	tmust(t, err)
+	stats = traits.Stats()
+	if stats.NSounds != len(traits.SoundSet) || stats.NPairs != len(traits.PairSet) ||
+		stats.Branching <= 1 || stats.Entropy <= 0 || stats.NWords <= 0 {
+		t.Fatalf("expected plausible stats, got %#v", stats)
+	}
+}
+
+func Test_Traits_Count(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Generator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	count := traits.Count()
+	if all := collectAll(traits); count != uint64(len(all)) {
+		t.Fatalf("expected the count to match the %v generated words, got %v", len(all), count)
+	}
+
+	estimate := traits.estimateCount(rand.New(rand.NewSource(1)), 4096)
+	if math.Abs(estimate-float64(count)) > float64(count)/4 {
+		t.Fatalf("expected an estimate near %v, got %v", count, estimate)
+	}
+	if traits.EstimateCount() <= 0 {
+		t.Fatal("expected a positive estimate")
+	}
+}
+
+func Test_Smoothing(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Count(t)
+
+	traits, err := NewTraits([]string{"nebula", "aurora", "quasar"})
+	tmust(t, err)
+	strict := collectAll(traits)
+
+	traits.MaxUnseen = 1
+	loose := collectAll(traits)
+	if uint64(len(loose)) != traits.Count() || len(loose) <= len(strict) {
+		t.Fatalf("expected unseen pairs to add words to the %v strict ones, got %v", len(strict), len(loose))
+	}
+	for word := range strict {
+		if !loose.Has(word) {
+			t.Fatalf("expected %q to remain derived", word)
+		}
+	}
+
+	// Known sounds may split some words differently from how they were made,
+	// for example "ae" as a single sound.
+	var unseen string
+	for word := range loose {
+		sounds, err := getSounds(word, traits.SoundSet)
+		tmust(t, err)
+		if n := traits.countUnseen(nil, sounds); n > 1 {
+			t.Fatalf("expected at most one unseen pair in %q, got %v", word, n)
+		}
+		known, err := getSounds(word, traits.knownSounds())
+		tmust(t, err)
+		if unseen == "" && reflect.DeepEqual(known, sounds) && traits.countUnseen(nil, sounds) == 1 {
+			unseen = word
+		}
+	}
+	if !traits.Matches(unseen) {
+		t.Fatalf("expected %q to match traits with unseen pairs", unseen)
+	}
+
+	// Unweighted generators try unseen pairs last.
+	values := []string{"x", "a", "u", "e"}
+	traits.PairSet.Add([2]string{"q", "u"})
+	traits.PairSet.Add([2]string{"q", "e"})
+	traits.sortUnseen(nil, "q", values)
+	if !reflect.DeepEqual(values, []string{"u", "e", "x", "a"}) {
+		t.Fatalf("expected sounds of seen pairs first, got %v", values)
+	}
+	values = []string{"x", "a", "u", "e"}
+	traits.sortUnseen(newSoundTable(traits.transitions()), "q", values)
+	if !reflect.DeepEqual(values, []string{"u", "e", "x", "a"}) {
+		t.Fatalf("expected sounds of seen pairs of the table first, got %v", values)
+	}
+
+	traits, err = NewTraits([]string{"nebula", "aurora", "quasar"})
+	tmust(t, err)
+	if traits.Matches(unseen) {
+		t.Fatalf("expected %q not to match strict traits", unseen)
+	}
+	if score := traits.Score(unseen); !math.IsInf(score, -1) {
+		t.Fatalf("expected negative infinity for %q without smoothing, got %v", unseen, score)
+	}
+
+	traits.Smoothing = 1
+	if score := traits.Score(unseen); math.IsInf(score, 0) {
+		t.Fatalf("expected a finite score for %q with smoothing, got %v", unseen, score)
+	}
+	if traits.Score(unseen) >= traits.Score(traits.TopK(1)[0]) {
+		t.Fatalf("expected %q to score below the most likely word", unseen)
+	}
+
+	// Smoothed probabilities of the successors of each sound add up to 1.
+	chances := traits.chances()
+	for sound := range traits.SoundSet {
+		var total float64
+		for other := range traits.SoundSet {
+			total += math.Exp(chances.transition(sound, other))
+		}
+		total += math.Exp(chances.transition(sound, EndToken))
+		if math.Abs(total-1) > 1e-9 {
+			t.Fatalf("expected the successors of %q to add up to 1, got %v", sound, total)
+		}
+	}
+
+	traits.MaxUnseen = 2
+	test_Traits_Encodings(t, traits)
+}
+
+// Verifies that every way of deriving words agrees on the words of traits with
+// unseen pairs, which may only begin and end with sounds of seen pairs.
+func Test_Smoothing_Agreement(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Smoothing(t)
+
+	// Single letters, so words split into sounds the same way they were made.
+	traits, err := NewTraitsWithSounds([]string{"nola", "lux"}, Letters("alnoux"))
+	tmust(t, err)
+	traits.MaxUnseen = 1
+	words := collectAll(traits)
+	if uint64(len(words)) != traits.Count() {
+		t.Fatalf("expected %v words to be counted, got %v", len(words), traits.Count())
+	}
+
+	for word := range words {
+		if !traits.Matches(word) {
+			t.Fatalf("expected %q to match the traits", word)
+		}
+		index, err := traits.IndexOf(word)
+		tmust(t, err)
+		if index >= traits.Count() {
+			t.Fatalf("expected the index of %q below the count, got %v", word, index)
+		}
+	}
+
+	// "x" only ends pairs, and "n" only begins them.
+	for _, word := range []string{"xola", "lon"} {
+		if traits.Matches(word) {
+			t.Fatalf("expected %q not to match the traits", word)
+		}
+		if _, err := traits.IndexOf(word); err == nil {
+			t.Fatalf("expected no index for %q", word)
+		}
+	}
+
+	for end := range traits.Ends() {
+		for _, word := range collectGen(traits.GeneratorTo(end)) {
+			if !words.Has(word) {
+				t.Fatalf("expected %q ending with %q to be derived forwards", word, end)
+			}
+		}
+	}
+}
+
+func Test_Backoff(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraitsN(t)
+
+	traits := &Traits{Order: 2, Backoff: true}
+	tmust(t, traits.Examine([]string{"lano", "mona"}))
+	if !traits.GramSet.Has("l>a>n") || traits.GramSet.Has("l>a") {
+		t.Fatalf("expected trigrams, got %v", traits.GramSet)
+	}
+	// "n>o" never continues in the sample, so "o>n" backs off to the pair; "o>n"
+	// continues with "a", so "o" can't follow it.
+	if !traits.validGrams([]string{"l", "a", "n", "o", "n", "a"}) {
+		t.Fatal("expected a context without continuations to back off")
+	}
+	if traits.validGrams([]string{"m", "o", "n", "o"}) {
+		t.Fatal("expected a context with continuations not to back off")
+	}
+
+	strict, err := NewTraitsN(testDefWords, 3)
+	tmust(t, err)
+	traits = &Traits{Order: 3, Backoff: true}
+	tmust(t, traits.Examine(testDefWords))
+	if !traits.GramSet.Has("n>e>b>u") || !traits.GramSet.Has("n>e>b") {
+		t.Fatalf("expected n-grams of every order, got %v", traits.GramSet)
+	}
+	tmust(t, traits.Validate())
+
+	all := collectAll(traits)
+	strictAll := collectAll(strict)
+	if len(all) <= len(strictAll) {
+		t.Fatalf("expected more words than the %v without backoff, got %v", len(strictAll), len(all))
+	}
+	for word := range strictAll {
+		if !all.Has(word) {
+			t.Fatalf("expected %q to remain derived", word)
+		}
+	}
+
+	input, err := traits.MarshalText()
+	tmust(t, err)
+	decoded := new(Traits)
+	tmust(t, decoded.UnmarshalText(input))
+	if !reflect.DeepEqual(traits, decoded) {
+		t.Fatalf("expected parsed traits to equal the original: %#v vs %#v", traits, decoded)
+	}
+
+	merged := traits.Merge(strict)
+	if merged.Backoff || merged.GramSet.Has("n>e>b") || !merged.GramSet.Has("n>e>b>u") {
+		t.Fatalf("expected merging with strict traits to drop the shorter n-grams, got %v", merged.GramSet)
+	}
+	tmust(t, merged.Validate())
+}
+
+func Test_DiscoverDigraphs(t *testing.T) {
+	// t.SkipNow()
+
+	Test_getSounds_Multigraphs(t)
+
+	digraphs := DiscoverDigraphs([]string{"Szasz", "kesz", "szel", "masz", "visz", "csak", "kocsi"}, 0.5)
+	if !reflect.DeepEqual(digraphs, Set.New(nil, "sz")) {
+		t.Fatalf("expected to discover \"sz\", got %v", digraphs)
+	}
+
+	digraphs = DiscoverDigraphs(testDefWords, 0.5)
+	if !digraphs.Has("th") || digraphs.Has("ne") {
+		t.Fatalf("expected to discover \"th\" only, got %v", digraphs)
+	}
+
+	// Pairs split by non-letters and pairs that occur once don't count.
+	if digraphs := DiscoverDigraphs([]string{"t-h", "xq"}, 0); len(digraphs) != 0 {
+		t.Fatalf("expected no digraphs, got %v", digraphs)
+	}
+
+	if digraphs := DiscoverDigraphs(testDefWords, 1.01); len(digraphs) != 0 {
+		t.Fatalf("expected no digraphs above the maximum coefficient, got %v", digraphs)
+	}
+}
+
+func Test_Rules(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Casing(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	traits.Rules = EnglishRules()
+
+	for word, expected := range map[string]string{
+		"qasar":  "quasar",
+		"quasar": "quasar",
+		"olav":   "olave",
+		"back":   "back",
+		"beack":  "beak",
+		"tolck":  "tolk",
+		"cka":    "ka",
+		"":       "",
+	} {
+		if rewritten := traits.rewrite(word); rewritten != expected {
+			t.Fatalf("expected %q to be rewritten to %q, got %q", word, expected, rewritten)
+		}
+	}
+
+	traits.Casing = TitleCase
+	for word := range collectAll(traits) {
+		lower := strings.ToLower(word)
+		if strings.Contains(strings.Replace(lower, "qu", "", -1), "q") || strings.HasSuffix(lower, "v") {
+			t.Fatalf("expected %q to follow the rules", word)
+		}
+		if word[:1] != strings.ToUpper(word[:1]) {
+			t.Fatalf("expected rules to apply before casing, got %q", word)
+		}
+	}
+
+	test_Traits_Encodings(t, traits)
+
+	decoded := new(Traits)
+	for _, invalid := range []string{`rule "(" "x"`, `rule "a"`, `rule a b`} {
+		if decoded.UnmarshalText([]byte(invalid)) == nil {
+			t.Fatalf("expected an error when parsing %q", invalid)
+		}
+	}
+}
+
+func Test_Traits_Hash(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	reversed := make([]string, 0, len(testDefWords))
+	for index := len(testDefWords) - 1; index >= 0; index-- {
+		reversed = append(reversed, testDefWords[index])
+	}
+	other, err := NewTraits(reversed)
+	tmust(t, err)
+	if traits.Hash() != other.Hash() {
+		t.Fatal("expected the hash not to depend on the order of the sample")
+	}
+	if traits.Hash() != traits.Clone().Hash() {
+		t.Fatal("expected clones to have equal hashes")
+	}
+
+	tmust(t, other.AddWord("nebulae"))
+	if traits.Hash() == other.Hash() {
+		t.Fatal("expected the hash to change with the sample")
+	}
+	other, err = NewTraits(testDefWords)
+	tmust(t, err)
+	other.Casing = TitleCase
+	if traits.Hash() == other.Hash() {
+		t.Fatal("expected the hash to change with the settings")
+	}
+}
+
+func Test_Traits_Explain(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Invalid_Input(t)
+
+	traits := &Traits{}
+	if err := traits.Explain("nebula"); err != nil {
+		t.Fatalf("expected a valid word to be explained with nil, got %v", err)
+	}
+
+	for word, expected := range map[string]WordError{
+		"a":         {Word: "a", Reason: "the word is too short or too long", Offset: -1},
+		"mi5t":      {Word: "mi5t", Reason: "encountered unknown symbol", Offset: 2, Symbol: "5"},
+		"ñandú":     {Word: "ñandú", Reason: "encountered unknown symbol", Offset: 0, Symbol: "ñ"},
+		"ae":        {Word: "ae", Reason: "less than two sounds found", Offset: -1},
+		"nebula-11": {Word: "nebula-11", Reason: "encountered unknown symbol", Offset: 6, Symbol: "-"},
+	} {
+		err, ok := traits.Explain(word).(*WordError)
+		if !ok || *err != expected {
+			t.Fatalf("expected %#v for %q, got %#v", expected, word, err)
+		}
+	}
+	if len(traits.SoundSet) != 0 {
+		t.Fatal("expected explaining not to modify the traits")
+	}
+
+	err := traits.Examine([]string{"nebula", "mi5t"})
+	if err, ok := err.(*WordError); !ok || err.Word != "mi5t" {
+		t.Fatalf("expected a *WordError for the invalid word, got %#v", err)
+	}
+	if err.Error() != `invalid word "mi5t": encountered unknown symbol "5" at offset 2` {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+}
+
+func Test_Traits_ExamineWeighted(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_AddWord(t)
+
+	words := []string{"nebula", "aurora", "quasar"}
+	expect, err := NewTraits(words)
+	tmust(t, err)
+
+	traits := &Traits{}
+	tmust(t, traits.ExamineWeighted(map[string]int{"nebula": 3, "aurora": 1, "quasar": 2}))
+	if count := traits.PairCount[[2]string{"n", "e"}]; count != 3 {
+		t.Fatalf("expected the pair count to be scaled by the weight, got %v", count)
+	}
+	if count := traits.PairCount[[2]string{"a", "r"}]; count != 2 {
+		t.Fatalf("expected weights of different words to add up, got %v", count)
+	}
+	if !reflect.DeepEqual(traits.LengthCount, map[int]int{6: 6}) {
+		t.Fatalf("expected weighted length counts, got %v", traits.LengthCount)
+	}
+
+	traits.PairCount, expect.PairCount = nil, nil
+	traits.LengthCount, expect.LengthCount = nil, nil
+	traits.BoundaryCount, expect.BoundaryCount = nil, nil
+	if !reflect.DeepEqual(traits, expect) {
+		t.Fatalf("expected weights to affect only the counts\nexpected: %#v\ngot: %#v", expect, traits)
+	}
+
+	err = (&Traits{}).ExamineWeighted(map[string]int{"nebula": 0})
+	if err, ok := err.(*WordError); !ok || err.Word != "nebula" {
+		t.Fatalf("expected a *WordError for a non-positive weight, got %#v", err)
+	}
+}
+
+func Test_Traits_ForbidPair(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Edit(t)
+
+	traits, err := NewTraits([]string{"nebula", "aurora", "quasar"})
+	tmust(t, err)
+	if !traits.Matches("nebula") {
+		t.Fatal("expected a sample word to match the traits")
+	}
+
+	traits.ForbidPair("u", "l")
+	if traits.PairSet.Has([2]string{"u", "l"}) || !traits.Forbidden.Has([2]string{"u", "l"}) {
+		t.Fatal("expected the pair to move from PairSet to Forbidden")
+	}
+	tmust(t, traits.Validate())
+
+	// The pair stays forbidden after examining it again, and with unseen pairs.
+	tmust(t, traits.AddWord("nebula"))
+	if traits.PairSet.Has([2]string{"u", "l"}) || traits.PairCount[[2]string{"u", "l"}] != 0 {
+		t.Fatal("expected examining a forbidden pair not to add it back")
+	}
+	tmust(t, traits.Validate())
+	traits.MaxUnseen = 1
+	words := collectAll(traits)
+	if uint64(len(words)) != traits.Count() {
+		t.Fatalf("expected the count to match the %v derived words, got %v", len(words), traits.Count())
+	}
+	for word := range words {
+		if strings.Contains(word, "ul") {
+			t.Fatalf("expected no words with a forbidden pair, got %q", word)
+		}
+	}
+	if traits.Matches("nebula") {
+		t.Fatal("expected a word with a forbidden pair not to match")
+	}
+
+	traits.ForbidPairs(PairSet.New(nil, [2]string{"q", "u"}, [2]string{"a", "s"}))
+	if len(traits.Forbidden) != 3 {
+		t.Fatalf("expected three forbidden pairs, got %v", traits.Forbidden)
+	}
+	if !traits.Reverse().Forbidden.Has([2]string{"s", "a"}) {
+		t.Fatal("expected reversed traits to forbid reversed pairs")
+	}
+
+	input, err := traits.MarshalText()
+	tmust(t, err)
+	decoded := new(Traits)
+	tmust(t, decoded.UnmarshalText(input))
+	if !reflect.DeepEqual(decoded.Forbidden, traits.Forbidden) {
+		t.Fatalf("expected forbidden pairs to survive a dump, got %v", decoded.Forbidden)
+	}
+}
+
+func Test_Traits_Prune(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Edit(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	if n := traits.Prune(1); n != 0 {
+		t.Fatalf("expected no pairs to be pruned below one occurrence, got %v", n)
+	}
+
+	total := len(traits.PairSet)
+	if n := traits.Prune(2); n != total-3 {
+		t.Fatalf("expected %v pairs to be pruned, got %v", total-3, n)
+	}
+	expected := PairSet.New(nil, [2]string{"o", "r"}, [2]string{"r", "a"}, [2]string{"r", "o"})
+	if !reflect.DeepEqual(traits.PairSet, expected) {
+		t.Fatalf("expected only frequent pairs to remain, got %v", traits.PairSet)
+	}
+	if !reflect.DeepEqual(traits.SoundSet, Set.New(nil, "a", "o", "r")) {
+		t.Fatalf("expected orphaned sounds to be deleted, got %v", traits.SoundSet)
+	}
+	tmust(t, traits.Validate())
+}
+
+func Test_Interpolate(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Merge(t)
+
+	japanese, err := NewTraits([]string{"sakura", "kimono", "tanuki"})
+	tmust(t, err)
+	italian, err := NewTraits([]string{"aurelio", "valeria", "lucrezia"})
+	tmust(t, err)
+
+	for alpha, expected := range map[float64]*Traits{1: japanese, 0: italian} {
+		mixed, err := Interpolate(japanese, italian, alpha)
+		tmust(t, err)
+		if !reflect.DeepEqual(mixed.PairSet, expected.PairSet) {
+			t.Fatalf("expected a share of %v to keep the pairs of one traits, got %v", alpha, mixed.PairSet)
+		}
+		tmust(t, mixed.Validate())
+	}
+
+	mixed, err := Interpolate(japanese, italian, 0.7)
+	tmust(t, err)
+	if !mixed.Weighted || !reflect.DeepEqual(mixed.PairSet, japanese.Merge(italian).PairSet) {
+		t.Fatal("expected weighted traits with the pairs of both")
+	}
+	// "k>i" makes up 2 of the 15 Japanese pairs and none of the Italian ones.
+	if count := mixed.PairCount[[2]string{"k", "i"}]; count != 933 {
+		t.Fatalf("expected the count of a pair to follow its share, got %v", count)
+	}
+	if mixed.LengthCount[6] != 7000 || mixed.LengthCount[7] != 2000 || mixed.LengthCount[8] != 1000 {
+		t.Fatalf("expected lengths to be mixed by share, got %v", mixed.LengthCount)
+	}
+	if japanese.Weighted || italian.Weighted {
+		t.Fatal("expected the inputs to remain unmodified")
+	}
+
+	for _, alpha := range []float64{-0.1, 1.5, math.NaN()} {
+		if _, err := Interpolate(japanese, italian, alpha); err == nil {
+			t.Fatalf("expected an error for a share of %v", alpha)
+		}
+	}
+}
+
+func Test_Boundaries(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_Matrix(t)
+
+	traits, err := NewTraits([]string{"nebula", "nova", "lumen"})
+	tmust(t, err)
+	expected := map[[2]string]int{
+		{StartToken, "n"}: 2, {StartToken, "l"}: 1,
+		{"a", EndToken}: 2, {"n", EndToken}: 1,
+	}
+	if !reflect.DeepEqual(traits.BoundaryCount, expected) {
+		t.Fatalf("expected the first and last sounds to be counted, got %v", traits.BoundaryCount)
+	}
+	tmust(t, traits.Validate())
+
+	chances := traits.chances()
+	if !(chances.start["n"] > chances.start["l"] && chances.start["l"] == chances.start["u"]) {
+		t.Fatalf("expected start chances to follow the sample, got %v", chances.start)
+	}
+	if !(chances.transition("a", EndToken) > chances.transition("u", EndToken)) {
+		t.Fatal("expected end chances to follow the sample")
+	}
+
+	reversed := traits.Reverse()
+	if reversed.BoundaryCount[[2]string{StartToken, "a"}] != 2 || reversed.BoundaryCount[[2]string{"n", EndToken}] != 2 {
+		t.Fatalf("expected reversed traits to swap starts and ends, got %v", reversed.BoundaryCount)
+	}
+
+	input, err := traits.MarshalText()
+	tmust(t, err)
+	decoded := new(Traits)
+	tmust(t, decoded.UnmarshalText(input))
+	if !reflect.DeepEqual(decoded.BoundaryCount, traits.BoundaryCount) {
+		t.Fatalf("expected boundaries to survive a dump, got %v", decoded.BoundaryCount)
+	}
+
+	tmust(t, traits.RemoveWord("lumen"))
+	delete(expected, [2]string{StartToken, "l"})
+	delete(expected, [2]string{"n", EndToken})
+	if !reflect.DeepEqual(traits.BoundaryCount, expected) {
+		t.Fatalf("expected removing a word to subtract its boundaries, got %v", traits.BoundaryCount)
+	}
+
+	invalid := Matrix{Transitions: map[string]map[string]int{StartToken: {EndToken: 1}}}
+	if traits.ImportMatrix(invalid) == nil {
+		t.Fatal("expected an error for a word without sounds")
+	}
+	traits.BoundaryCount[[2]string{"ж", EndToken}] = 1
+	if traits.Validate() == nil {
+		t.Fatal("expected an error for a boundary of an unknown sound")
+	}
+}
+
+func Test_Traits_transitions(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Boundaries(t)
+
+	traits := &Traits{Connectives: Set.New(nil, "-")}
+	tmust(t, traits.Examine([]string{"ab-ba"}))
+	transitions := traits.transitions()
+	for _, pair := range [][2]string{{StartToken, "a"}, {StartToken, "b"}, {"a", EndToken}, {"b", EndToken}} {
+		if !transitions.Has(pair) {
+			t.Fatalf("expected a transition %q, got %v", pair, transitions)
+		}
+	}
+	for _, pair := range [][2]string{{StartToken, "-"}, {"-", EndToken}} {
+		if transitions.Has(pair) {
+			t.Fatalf("expected no transition %q for a connective", pair)
+		}
+	}
+
+	// Generated words start from the StartToken and end with a transition to the
+	// EndToken.
+	gen := traits.Generator()
+	for word := gen(); word != ""; word = gen() {
+		sounds, err := getSounds(word, traits.knownSounds())
+		tmust(t, err)
+		if !transitions.Has([2]string{StartToken, sounds[0]}) ||
+			!transitions.Has([2]string{sounds[len(sounds)-1], EndToken}) {
+			t.Fatalf("expected %q to follow the token transitions", word)
+		}
+	}
+
+	for _, token := range []string{StartToken, EndToken} {
+		traits := &Traits{KnownSounds: Set.New(nil, "a", "b", token)}
+		if traits.Validate() == nil {
+			t.Fatalf("expected an error for a known sound %q", token)
+		}
+		traits = &Traits{Connectives: Set.New(nil, token)}
+		if traits.Validate() == nil {
+			t.Fatalf("expected an error for a connective %q", token)
+		}
+	}
+}
+
+func Test_soundTable(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_transitions(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	transitions := traits.transitions()
+	table := newSoundTable(transitions)
+	sounds := append(sortedSet(traits.SoundSet), StartToken, EndToken)
+
+	// Without the bitset, pairs are looked up in the lists of successors.
+	for _, adjacent := range [][]uint64{table.adjacent, nil} {
+		table.adjacent = adjacent
+		for _, sound := range sounds {
+			for _, other := range sounds {
+				pair := [2]string{sound, other}
+				if table.has(sound, other) != transitions.Has(pair) {
+					t.Fatalf("expected the table to have %q: %v", pair, transitions.Has(pair))
+				}
+			}
+		}
+	}
+	if table.has("ж", "a") {
+		t.Fatal("expected no pairs of unknown sounds")
+	}
+
+	if !reflect.DeepEqual(table.sprout(), traits.successors(nil)) {
+		t.Fatalf("expected the table to start from the StartToken, got %v", table.sprout())
+	}
+	if !reflect.DeepEqual(table.sprout("n"), sprout(traits.PairSet, "n")) {
+		t.Fatalf("expected the successors of the PairSet, got %v", table.sprout("n"))
+	}
+}
+
+func Test_NewTraitsFromReader(t *testing.T) {
+	// t.SkipNow()
+
+	Test_NewTraits(t)
+
+	expect, err := NewTraits(testDefWords)
+	tmust(t, err)
+
+	input := "\n" + strings.Join(testDefWords, "\r\n") + "\n\n"
+	traits, err := NewTraitsFromReader(strings.NewReader(input))
+	tmust(t, err)
+	if !reflect.DeepEqual(traits, expect) {
+		t.Fatalf("expected streamed traits to equal traits of the whole sample\nexpected: %#v\ngot: %#v", expect, traits)
+	}
+
+	_, err = NewTraitsFromReader(strings.NewReader("nebula\nmi5t\n"))
+	if err, ok := err.(*WordError); !ok || err.Word != "mi5t" {
+		t.Fatalf("expected a *WordError for the invalid word, got %#v", err)
+	}
+
+	failure := errors.New("read failure")
+	_, err = NewTraitsFromReader(io.MultiReader(strings.NewReader("nebula\n"), iotest.ErrReader(failure)))
+	if err != failure {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+}
+
+// Verifies that every field of the traits survives both encodings, so a model
+// with every setting in use can be stored and reloaded without retraining.
+func Test_Traits_Encodings_Complete(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Traits_JSON(t)
+	Test_Traits_Dump(t)
+
+	traits := &Traits{
+		KnownSounds: DefaultSounds(),
+		KnownVowels: DefaultVowels(),
+		Connectives: Set.New(nil, "-"),
+		Order:       2,
+		Backoff:     true,
+		Positional:  true,
+		Harmony:     true,
+		Weighted:    true,
+		MaxUnseen:   1,
+		Smoothing:   0.5,
+		Casing:      TitleCase,
+		Spelling:    map[string]string{"th": "þ"},
+		Rules:       EnglishRules(),
+		MaxWordLen:  20,
+	}
+	tmust(t, traits.Examine([]string{"nebula", "aurora-borealis", "theron"}))
+	traits.ForbidPair("n", "e")
+
+	value := reflect.ValueOf(*traits)
+	for index := 0; index < value.NumField(); index++ {
+		if value.Field(index).IsZero() {
+			t.Fatalf("expected field %v to be set", value.Type().Field(index).Name)
+		}
+	}
+
+	test_Traits_Encodings(t, traits)
+}
+
+// Verifies that word lists are split on any separator, skip comments and
+// repeats, and load from file systems.
+func Test_LoadWords(t *testing.T) {
+	// t.SkipNow()
+
+	input := "# Elven names\nnebula, aurora\r\n\n  theron\tnebula # repeated\n,,lyra"
+	words, err := LoadWords(strings.NewReader(input))
+	tmust(t, err)
+	expected := []string{"nebula", "aurora", "theron", "lyra"}
+	if !reflect.DeepEqual(words, expected) {
+		t.Fatalf("expected words %#v, got %#v", expected, words)
+	}
 
-	traits, _ := NewTraits(testLimitedWords)
-	words := collectAll(traits)
+	fsys := fstest.MapFS{"names.txt": &fstest.MapFile{Data: []byte(input)}}
+	words, err = LoadWordsFS(fsys, "names.txt")
+	tmust(t, err)
+	if !reflect.DeepEqual(words, expected) {
+		t.Fatalf("expected words %#v, got %#v", expected, words)
+	}
 
-	test_Words_Match_Traits(t, traits, words)
+	if _, err := LoadWordsFS(fsys, "missing.txt"); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+
+	// One line longer than the default buffer of bufio.Scanner.
+	long := make([]string, 20000)
+	for index := range long {
+		long[index] = "word" + strconv.Itoa(index)
+	}
+	words, err = LoadWords(strings.NewReader(strings.Join(long, ", ")))
+	tmust(t, err)
+	if !reflect.DeepEqual(words, long) {
+		t.Fatalf("expected %d words from one long line, got %d", len(long), len(words))
+	}
+
+	failure := errors.New("failure")
+	if _, err := LoadWords(iotest.ErrReader(failure)); err != failure {
+		t.Fatalf("expected the error of the reader, got: %v", err)
+	}
 }
 
-// Verifies that NewTraits() produces an error with invalid input.
-func Test_Invalid_Input(t *testing.T) {
+// Verifies that sets convert to slices of all their elements, sorted or not.
+func Test_Set_Sorted(t *testing.T) {
 	// t.SkipNow()
 
-	Test_NewTraits(t)
+	set := Set.New(nil, "th", "a", "b")
+	if sorted := set.Sorted(); !reflect.DeepEqual(sorted, []string{"a", "b", "th"}) {
+		t.Fatalf("expected sorted elements, got %#v", sorted)
+	}
 
-	invalids := []string{
-		"", "a", "CAPITALS", "Capitalised", "with space",
-		"numbers134125", "łàtîñôñè", "кириллица",
+	slice := set.Slice()
+	if len(slice) != len(set) || !reflect.DeepEqual(Set.New(nil, slice...), set) {
+		t.Fatalf("expected every element, got %#v", slice)
 	}
 
-	for _, invalid := range invalids {
-		traits, err := NewTraits([]string{invalid})
-		if traits != nil || err == nil {
-			t.Fatalf("expected nil traits and non-nil error, got %v and %v", traits, err)
+	if sorted := Set(nil).Sorted(); sorted == nil || len(sorted) != 0 {
+		t.Fatalf("expected an empty slice, got %#v", sorted)
+	}
+}
+
+// Verifies set algebra, including with nil sets, and that the inputs are left
+// unchanged.
+func Test_Set_Algebra(t *testing.T) {
+	// t.SkipNow()
+
+	one := Set.New(nil, "a", "b", "c")
+	other := Set.New(nil, "b", "c", "d")
+
+	for _, test := range []struct {
+		result   Set
+		expected Set
+	}{
+		{one.Union(other), Set.New(nil, "a", "b", "c", "d")},
+		{one.Intersect(other), Set.New(nil, "b", "c")},
+		{other.Intersect(Set.New(nil, "b")), Set.New(nil, "b")},
+		{one.Diff(other), Set.New(nil, "a")},
+		{other.Diff(one), Set.New(nil, "d")},
+		{Set(nil).Union(nil), Set{}},
+		{one.Intersect(nil), Set{}},
+		{one.Diff(nil), one},
+		{Set(nil).Diff(one), Set{}},
+	} {
+		if !reflect.DeepEqual(test.result, test.expected) {
+			t.Fatalf("expected %#v, got %#v", test.expected, test.result)
 		}
 	}
+
+	if !reflect.DeepEqual(one, Set.New(nil, "a", "b", "c")) || !reflect.DeepEqual(other, Set.New(nil, "b", "c", "d")) {
+		t.Fatalf("expected the sets to be unchanged, got %#v and %#v", one, other)
+	}
 }
 
-// Verifies that Traits.Examine() and NewTraits() are equivalent.
-func Test_Traits_Examine(t *testing.T) {
+// Verifies that sets filter and map their elements into new sets.
+func Test_Set_Filter(t *testing.T) {
 	// t.SkipNow()
 
-	Test_NewTraits(t)
+	set := Set.New(nil, "goblin", "orc", "Troll", "ogre")
 
-	traits := new(Traits)
-	tmust(t, traits.Examine(testDefWords))
+	short := set.Filter(func(word string) bool { return len(word) <= 4 })
+	if !reflect.DeepEqual(short, Set.New(nil, "orc", "ogre")) {
+		t.Fatalf("unexpected filtered set: %#v", short)
+	}
 
-	other, _ := NewTraits(testDefWords)
+	upper := set.Map(strings.ToUpper)
+	if !reflect.DeepEqual(upper, Set.New(nil, "GOBLIN", "ORC", "TROLL", "OGRE")) {
+		t.Fatalf("unexpected mapped set: %#v", upper)
+	}
 
-	if !reflect.DeepEqual(traits, other) {
-		t.Fatal("expected new(Traits) + Traits.Examine() to be equivalent to NewTraits()")
+	merged := set.Map(func(word string) string { return word[:1] })
+	if !reflect.DeepEqual(merged, Set.New(nil, "g", "o", "T")) {
+		t.Fatalf("expected equal results to merge, got %#v", merged)
 	}
 
-	if !reflect.DeepEqual(collectAll(traits), collectAll(other)) {
-		t.Fatal("expected resulting word sets to be equivalent")
+	if len(set) != 4 || !set.Has("Troll") {
+		t.Fatalf("expected the set to be unchanged, got %#v", set)
+	}
+	if out := Set(nil).Filter(func(string) bool { return true }); out == nil || len(out) != 0 {
+		t.Fatalf("expected an empty set, got %#v", out)
 	}
 }
 
-// Verifies that a Traits object uses internal known sounds, if available.
-func Test_Traits_KnownSounds(t *testing.T) {
+// Verifies that random picks come from the set, are distinct, and cover every
+// element.
+func Test_Set_Pick(t *testing.T) {
 	// t.SkipNow()
 
-	Test_Traits_Examine(t)
+	set := Set.New(nil, "a", "b", "c", "d")
 
-	traits := new(Traits)
+	seen := Set{}
+	for i := 0; i < 1000; i++ {
+		key, ok := set.Pick()
+		if !ok || !set.Has(key) {
+			t.Fatalf("expected an element of the set, got %q", key)
+		}
+		seen.Add(key)
+	}
+	if !reflect.DeepEqual(seen, set) {
+		t.Fatalf("expected every element to be picked eventually, got %#v", seen)
+	}
+	if key, ok := Set(nil).Pick(); ok || key != "" {
+		t.Fatalf("expected nothing to pick from an empty set, got %q", key)
+	}
 
-	traits.KnownSounds = Set.New(nil,
-		"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m",
-	)
+	seen = Set{}
+	for i := 0; i < 1000; i++ {
+		keys := set.PickN(2)
+		if len(keys) != 2 || keys[0] == keys[1] || !set.Has(keys[0]) || !set.Has(keys[1]) {
+			t.Fatalf("expected two distinct elements of the set, got %#v", keys)
+		}
+		seen.Add(keys[0])
+	}
+	if !reflect.DeepEqual(seen, set) {
+		t.Fatalf("expected every element to be picked first eventually, got %#v", seen)
+	}
+	if keys := set.PickN(10); !reflect.DeepEqual(Set.New(nil, keys...), set) || len(keys) != len(set) {
+		t.Fatalf("expected every element, got %#v", keys)
+	}
+	if keys := set.PickN(-1); len(keys) != 0 {
+		t.Fatalf("expected no elements, got %#v", keys)
+	}
+}
 
-	if reflect.DeepEqual(traits.knownSounds(), knownSounds) {
-		t.Fatal("expected Traits.knownSounds() to return the internal sound set")
+// Verifies that pair sets share the methods of sets, including sorting and the
+// text encoding, which makes them usable as JSON fields.
+func Test_SetOf_Pairs(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Set_Algebra(t)
+	Test_Set_Text(t)
+
+	one := PairSet.New(nil, [2]string{"b", "a"}, [2]string{"a", "th"}, [2]string{"a", "b"})
+	other := PairSet.New(nil, [2]string{"a", "b"}, [2]string{"c", "d"})
+
+	sorted := []([2]string){{"a", "b"}, {"a", "th"}, {"b", "a"}}
+	if !reflect.DeepEqual(one.Sorted(), sorted) {
+		t.Fatalf("expected %#v, got %#v", sorted, one.Sorted())
+	}
+	if !reflect.DeepEqual(one.Intersect(other), PairSet.New(nil, [2]string{"a", "b"})) {
+		t.Fatalf("unexpected intersection: %#v", one.Intersect(other))
+	}
+	if len(one.Union(other)) != 4 || len(one.Diff(other)) != 2 {
+		t.Fatalf("unexpected union or difference: %#v, %#v", one.Union(other), one.Diff(other))
 	}
 
-	if traits.Examine(testDefWords) == nil {
-		t.Fatal("expected Traits.Examine() to fail when used with a limited sound set")
+	input, err := json.Marshal(Fidelity{MissingPairs: one})
+	tmust(t, err)
+	var fidelity Fidelity
+	tmust(t, json.Unmarshal(input, &fidelity))
+	if !reflect.DeepEqual(fidelity.MissingPairs, one) {
+		t.Fatalf("expected pairs to round-trip through JSON, got %#v", fidelity.MissingPairs)
+	}
+	output, err := one.MarshalText()
+	tmust(t, err)
+	if string(output) != "a>b\na>th\nb>a" {
+		t.Fatalf("unexpected text: %q", output)
+	}
+
+	if _, err := PairSet.New(nil, [2]string{"a>", "b"}).MarshalText(); err == nil {
+		t.Fatalf("expected a sound with \">\" to fail to encode")
+	}
+	for _, input := range []string{"a", "a>b>c", ">b"} {
+		var set PairSet
+		if set.UnmarshalText([]byte(input)) == nil {
+			t.Fatalf("expected %q to fail to decode", input)
+		}
 	}
 }
 
-// Verifies that a Traits object uses internal known vowels, if available.
-func Test_Traits_KnownVowels(t *testing.T) {
+// Verifies that sets print their elements quoted and sorted.
+func Test_Set_String(t *testing.T) {
 	// t.SkipNow()
 
-	Test_NewTraits(t)
-	Test_Traits_Examine(t)
+	set := Set.New(nil, "th", "a", `"`, "b")
+	expected := `{"\"", "a", "b", "th"}`
+	for _, output := range []string{fmt.Sprintf("%#v", set), fmt.Sprint(set)} {
+		if output != expected {
+			t.Fatalf("expected %v, got %v", expected, output)
+		}
+	}
 
-	traits := new(Traits)
+	pairs := PairSet.New(nil, [2]string{"b", "a"}, [2]string{"a", "th"}, [2]string{"a", "b"})
+	expected = `{{"a", "b"}, {"a", "th"}, {"b", "a"}}`
+	for _, output := range []string{fmt.Sprintf("%#v", pairs), fmt.Sprint(pairs)} {
+		if output != expected {
+			t.Fatalf("expected %v, got %v", expected, output)
+		}
+	}
 
-	traits.KnownVowels = Set.New(nil,
-		"a", "e", "i",
-	)
+	if output := fmt.Sprint(Set(nil), PairSet{}); output != "{} {}" {
+		t.Fatalf("expected empty sets to print as {}, got %v", output)
+	}
+}
 
-	if reflect.DeepEqual(traits.knownVowels(), knownVowels) {
-		t.Fatal("expected Traits.knownVowels() to return the internal vowel set")
+// Verifies that sets round-trip through text, including as JSON fields, and
+// reject keys that can't be represented.
+func Test_Set_Text(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Set_String(t)
+
+	set := Set.New(nil, "th", "a", "b")
+	output, err := set.MarshalText()
+	tmust(t, err)
+	if string(output) != "a\nb\nth" {
+		t.Fatalf("expected sorted lines, got %q", output)
 	}
 
-	if traits.Examine(testDefWords) != nil {
-		t.Fatal("expected Traits.Examine() to complete successfully when used with a limited vowel set")
+	var decoded Set
+	tmust(t, decoded.UnmarshalText([]byte(" a\r\n\nb\nth\n")))
+	if !reflect.DeepEqual(decoded, set) {
+		t.Fatalf("expected %#v, got %#v", set, decoded)
 	}
 
-	other, _ := NewTraits(testDefWords)
-	if reflect.DeepEqual(traits, other) {
-		t.Fatalf("expected Traits.Examine() with custom vowels to produce different traits")
+	for _, key := range []string{"", " a", "a\nb"} {
+		if _, err := Set.New(nil, key).MarshalText(); err == nil {
+			t.Fatalf("expected key %q to fail to encode", key)
+		}
 	}
+}
 
-	if reflect.DeepEqual(collectAll(traits), collectAll(other)) {
-		t.Fatal("expected resulting word sets to be different")
+// Verifies that sets are encoded as sorted JSON arrays, and decoded from
+// arrays or from their text encoding.
+func Test_Set_JSON(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Set_Text(t)
+
+	set := Set.New(nil, "th", "a", "b")
+	input, err := json.Marshal(struct{ Sounds Set }{set})
+	tmust(t, err)
+	if string(input) != `{"Sounds":["a","b","th"]}` {
+		t.Fatalf("unexpected JSON: %s", input)
+	}
+
+	for _, input := range []string{`{"Sounds":["th","a","b","a"]}`, `{"Sounds":"a\nb\nth"}`} {
+		var config struct{ Sounds Set }
+		tmust(t, json.Unmarshal([]byte(input), &config))
+		if !reflect.DeepEqual(config.Sounds, set) {
+			t.Fatalf("expected %#v, got %#v", set, config.Sounds)
+		}
+	}
+
+	for _, empty := range []Set{nil, {}} {
+		output, err := json.Marshal(empty)
+		tmust(t, err)
+		if string(output) != "[]" {
+			t.Fatalf("expected an empty array, got %s", output)
+		}
+	}
+	kept := set
+	tmust(t, json.Unmarshal([]byte("null"), &kept))
+	if !reflect.DeepEqual(kept, set) {
+		t.Fatalf("expected null to leave the set unchanged, got %#v", kept)
+	}
+
+	pairs := PairSet.New(nil, [2]string{"b", "a"}, [2]string{"a", "th"})
+	output, err := json.Marshal(pairs)
+	tmust(t, err)
+	if string(output) != `[["a","th"],["b","a"]]` {
+		t.Fatalf("unexpected JSON of pairs: %s", output)
+	}
+	var decoded PairSet
+	tmust(t, json.Unmarshal(output, &decoded))
+	if !reflect.DeepEqual(decoded, pairs) {
+		t.Fatalf("expected %#v, got %#v", pairs, decoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"a": 1}`), &decoded); err == nil {
+		t.Fatalf("expected an object to fail to decode as a set")
 	}
 }
 
-// Verifies that words from a generator are randomly distributed. Rudimental and
-// naive, todo remember some math and use a real probability function.
-func Test_Generator_Random_Distribution(t *testing.T) {
+// Verifies that frequency lists are parsed with either separator, sum repeated
+// words, feed weighted training, and reject lines without counts.
+func Test_LoadFrequencies(t *testing.T) {
 	// t.SkipNow()
 
-	Test_Generator(t)
+	Test_LoadWords(t)
 
-	traits, _ := NewTraits(testDefWords)
+	input := "# word\tcount\nnebula\t3\r\n\naurora 2\nnebula\t1 # again\ntheron  1\n"
+	counts, err := LoadFrequencies(strings.NewReader(input))
+	tmust(t, err)
+	expected := map[string]int{"nebula": 4, "aurora": 2, "theron": 1}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, counts)
+	}
 
-	// Make a sorted list of words.
-	gen := traits.Generator()
-	unordered := Set{}
-	for word := gen(); word != ""; word = gen() {
-		unordered.Add(word)
+	traits := new(Traits)
+	tmust(t, traits.ExamineWeighted(counts))
+	if traits.LengthCount[6] != 6 {
+		t.Fatalf("expected the counts to weigh the training, got lengths %#v", traits.LengthCount)
 	}
-	words := make([]string, 0, len(unordered))
-	for word := range unordered {
-		words = append(words, word)
+
+	for _, input := range []string{"nebula", "nebula\tmany", "nebula 0", "\n\nnebula -1"} {
+		_, err := LoadFrequencies(strings.NewReader(input))
+		if err == nil {
+			t.Fatalf("expected %q to fail to load", input)
+		}
+		if !strings.HasPrefix(err.Error(), "line ") {
+			t.Fatalf("expected the error to state the line, got: %v", err)
+		}
 	}
-	sort.Strings(words)
 
-	// Limit of how many tight groups to permit.
-	maxTightGroups := len(words) / testDefCount / 10
-	if maxTightGroups == 0 {
-		maxTightGroups = 1
+	failure := errors.New("failure")
+	if _, err := LoadFrequencies(iotest.ErrReader(failure)); err != failure {
+		t.Fatalf("expected the error of the reader, got: %v", err)
 	}
+}
 
-	// Counter of tight group occurrences.
-	count := 0
+// Verifies that multisets count additions and rank by count, and that words
+// spelled the same through different sounds count every derivation path.
+func Test_CountSet(t *testing.T) {
+	// t.SkipNow()
 
-	// Prepare a generator that makes words in chunks.
-	wordsN := generatorN(traits)
+	Test_SortedGenerator(t)
 
-	// Loop over generator results and count how many times all indices from
-	// a sample fall within a tight range (let's say 1/5th the length).
-	for sample := wordsN(testDefCount); len(sample) > 0; sample = wordsN(testDefCount) {
-		indices := make([]int, 0, len(sample))
-		for word := range sample {
-			indices = append(indices, findIndex(words, word))
+	var counts CountSet
+	for _, key := range []string{"b", "a", "b", "c", "a", "b"} {
+		counts.Add(key)
+	}
+	if counts.Count("b") != 3 || counts.Count("a") != 2 || counts.Count("d") != 0 {
+		t.Fatalf("unexpected counts: %#v", counts)
+	}
+	if ranked := counts.Ranked(); !reflect.DeepEqual(ranked, []string{"b", "a", "c"}) {
+		t.Fatalf("expected elements ranked by count, got %#v", ranked)
+	}
+	if !reflect.DeepEqual(counts.Set(), Set.New(nil, "a", "b", "c")) {
+		t.Fatalf("expected the elements without counts, got %#v", counts.Set())
+	}
+
+	traits := &Traits{Spelling: map[string]string{"x": "ks"}}
+	tmust(t, traits.Examine([]string{"axa", "aksa", "saka", "kasa"}))
+	derivations := traits.Derivations()
+	if derivations.Count("aksa") != 2 {
+		t.Fatalf(`expected "aksa" to be derived as "x" and as "k", "s", got %d`, derivations.Count("aksa"))
+	}
+
+	total := 0
+	for _, count := range derivations {
+		total += count
+	}
+	if uint64(total) != traits.Count() {
+		t.Fatalf("expected the counts to sum to the number of paths %d, got %d", traits.Count(), total)
+	}
+	words, err := traits.WordsN(len(derivations))
+	tmust(t, err)
+	if !reflect.DeepEqual(words, derivations.Set()) {
+		t.Fatalf("expected the distinct words of the traits, got %#v", derivations.Set())
+	}
+}
+
+// Verifies that Bloom sets never miss added words, keep false positives near
+// the requested rate in a fraction of the memory of a Set, and deduplicate
+// generators.
+func Test_BloomSet(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Unique(t)
+
+	const n = 20000
+	set := NewBloomSet(n, 0.01)
+	for i := 0; i < n; i++ {
+		set.Add("word" + strconv.Itoa(i))
+	}
+	for i := 0; i < n; i++ {
+		if !set.Has("word" + strconv.Itoa(i)) {
+			t.Fatalf("expected the set to have word %d", i)
 		}
-		if maximum(indices)-minimum(indices) < len(words)/5 {
-			count++
+	}
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if set.Has("other" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / n; rate > 0.02 {
+		t.Fatalf("expected a false positive rate near 0.01, got %v", rate)
+	}
+	if set.Len() != n {
+		t.Fatalf("expected %d additions, got %d", n, set.Len())
+	}
+	if set.Size() > n*10/8+8 {
+		t.Fatalf("expected about 10 bits per word, got %d bytes", set.Size())
+	}
+
+	tiny := NewBloomSet(0, 2)
+	tiny.Add("nebula")
+	if !tiny.Has("nebula") {
+		t.Fatalf("expected invalid sizes to be replaced with usable ones")
+	}
+
+	words := []string{"Nebula", "nebula", "aurora", "Aurora", "theron"}
+	index := 0
+	gen := UniqueWith(func() string {
+		if index >= len(words) {
+			return ""
 		}
+		index++
+		return words[index-1]
+	}, CaseKey, NewBloomSet(len(words), 0.001))
+	if result := collectGen(gen); !reflect.DeepEqual(result, []string{"Nebula", "aurora", "theron"}) {
+		t.Fatalf("expected words deduplicated by the Bloom set, got %v", result)
 	}
+}
 
-	if count > maxTightGroups {
-		t.Fatalf("for %v sorted words, %v out of %v samples were tightly grouped", len(words), count, len(words)/testDefCount+1)
+// Verifies that ordered sets list words in insertion or sorted order, and that
+// set operations keep the order.
+func Test_OrderedSet(t *testing.T) {
+	// t.SkipNow()
+
+	Test_Set_Sorted(t)
+
+	words := []string{"nebula", "aurora", "nebula", "theron", "comet"}
+	inserted := NewOrderedSet(InsertionOrder, words...)
+	sorted := NewOrderedSet(SortedOrder, words...)
+	if result := inserted.Slice(); !reflect.DeepEqual(result, []string{"nebula", "aurora", "theron", "comet"}) {
+		t.Fatalf("expected words in insertion order, got %#v", result)
+	}
+	if result := sorted.Slice(); !reflect.DeepEqual(result, []string{"aurora", "comet", "nebula", "theron"}) {
+		t.Fatalf("expected sorted words, got %#v", result)
+	}
+	if inserted.Len() != 4 || !inserted.Has("comet") || inserted.Has("pulsar") {
+		t.Fatalf("unexpected membership: %#v", inserted.Slice())
+	}
+	if !reflect.DeepEqual(inserted.Set(), sorted.Set()) {
+		t.Fatalf("expected equal sets of words regardless of order")
+	}
+
+	inserted.Del("aurora")
+	inserted.Del("pulsar")
+	sorted.Del("comet")
+	if result := inserted.Slice(); !reflect.DeepEqual(result, []string{"nebula", "theron", "comet"}) {
+		t.Fatalf("expected the deletion to keep the order, got %#v", result)
+	}
+
+	other := NewOrderedSet(InsertionOrder, "pulsar", "comet", "aurora")
+	cases := []struct {
+		set    *OrderedSet
+		expect []string
+	}{
+		{inserted.Union(other), []string{"nebula", "theron", "comet", "pulsar", "aurora"}},
+		{sorted.Union(other), []string{"aurora", "comet", "nebula", "pulsar", "theron"}},
+		{other.Intersect(inserted), []string{"comet"}},
+		{inserted.Diff(other), []string{"nebula", "theron"}},
+		{sorted.Filter(func(word string) bool { return len(word) == 6 }), []string{"aurora", "nebula", "theron"}},
+	}
+	for _, testCase := range cases {
+		if result := testCase.set.Slice(); !reflect.DeepEqual(result, testCase.expect) {
+			t.Fatalf("expected %#v, got %#v", testCase.expect, result)
+		}
+	}
+	if sorted.Union(other).Order() != SortedOrder {
+		t.Fatalf("expected operations to keep the order of the receiver")
+	}
+
+	var zero OrderedSet
+	zero.Add("theron")
+	zero.Add("aurora")
+	if result := zero.Slice(); !reflect.DeepEqual(result, []string{"theron", "aurora"}) {
+		t.Fatalf("expected the zero value to use insertion order, got %#v", result)
+	}
+}
+
+// Verifies that the parallel enumeration yields the same words as exhausting
+// a generator, with any number of workers.
+func Test_Traits_Words(t *testing.T) {
+	// t.SkipNow()
+
+	Test_SortedGenerator(t)
+
+	traits, err := NewTraits(testDefWords)
+	tmust(t, err)
+	traits.Casing = TitleCase
+	expected := Set.New(nil, collectGen(traits.Generator())...)
+
+	if words := traits.Words(); !reflect.DeepEqual(words, expected) {
+		t.Fatalf("expected %d words, got %d", len(expected), len(words))
+	}
+	for _, workers := range []int{0, 1, 3, 64} {
+		if words := traits.words(workers); !reflect.DeepEqual(words, expected) {
+			t.Fatalf("expected %d words with %d workers, got %d", len(expected), workers, len(words))
+		}
+	}
+
+	if words := new(Traits).Words(); len(words) != 0 {
+		t.Fatalf("expected no words from empty traits, got %#v", words)
 	}
 }
 
@@ -360,6 +4243,33 @@ func test_Words_Match_Traits(t *testing.T, traits *Traits, words Set) {
 	}
 }
 
+// Verifies that the traits survive each of their encodings unchanged.
+func test_Traits_Encodings(t *testing.T, traits *Traits) {
+	encodings := []struct {
+		name      string
+		marshal   func() ([]byte, error)
+		unmarshal func(*Traits, []byte) error
+	}{
+		{
+			"JSON",
+			func() ([]byte, error) { return json.Marshal(traits) },
+			func(out *Traits, input []byte) error { return json.Unmarshal(input, out) },
+		},
+		{"the dump", traits.MarshalText, (*Traits).UnmarshalText},
+		{"the binary encoding", traits.MarshalBinary, (*Traits).UnmarshalBinary},
+		{"the protobuf encoding", traits.MarshalProto, (*Traits).UnmarshalProto},
+	}
+	for _, encoding := range encodings {
+		input, err := encoding.marshal()
+		tmust(t, err)
+		decoded := new(Traits)
+		tmust(t, encoding.unmarshal(decoded, input))
+		if !reflect.DeepEqual(decoded, traits) {
+			t.Fatalf("expected traits to survive %v\nexpected: %#v\ngot: %#v", encoding.name, traits, decoded)
+		}
+	}
+}
+
 /*********************************** Utils ***********************************/
 
 // Prints expanded values.
@@ -439,6 +4349,27 @@ func generatorN(traits *Traits) func(int) Set {
 	}
 }
 
+// Compares sequences of sounds lexicographically.
+func lessSounds(one, other []string) bool {
+	for index := range one {
+		if index >= len(other) {
+			return false
+		}
+		if one[index] != other[index] {
+			return one[index] < other[index]
+		}
+	}
+	return len(one) < len(other)
+}
+
+// Collects the words from the given generator, in order.
+func collectGen(gen func() string) (words []string) {
+	for word := gen(); word != ""; word = gen() {
+		words = append(words, word)
+	}
+	return
+}
+
 // Collects all words from the given traits.
 func collectAll(traits *Traits) Set {
 	words := Set{}
@@ -448,3 +4379,33 @@ func collectAll(traits *Traits) Set {
 	}
 	return words
 }
+
+var errWrite = errors.New("write failed")
+
+// Writer that fails every write after the first `limit` ones, counting them.
+type failingWriter struct {
+	limit int
+	count int
+}
+
+func (this *failingWriter) Write(buf []byte) (int, error) {
+	this.count++
+	if this.count > this.limit {
+		return 0, errWrite
+	}
+	return len(buf), nil
+}
+
+// Sink that fails every word after the first `limit` ones, counting them.
+type failingSink struct {
+	limit int
+	count int
+}
+
+func (this *failingSink) WriteWord(string) error {
+	this.count++
+	if this.count > this.limit {
+		return errWrite
+	}
+	return nil
+}